@@ -13,29 +13,41 @@ import (
 	"github.com/mattsp1290/october-talks-2025/example/client/internal/ui"
 )
 
-func runTea(p *tea.Program, userInputCh chan string) error {
+func runTea(p *tea.Program, userInputCh chan ui.Prompt) error {
 	defer close(userInputCh)
 	_, err := p.Run()
 	return err
 }
 
 func main() {
-	userInputCh := make(chan string)
-	p := tea.NewProgram(ui.InitialModel(userInputCh), tea.WithAltScreen())
+	userInputCh := make(chan ui.Prompt)
+	answerCh := make(chan ui.Answer)
+	p := tea.NewProgram(ui.InitialModel(userInputCh, answerCh), tea.WithAltScreen())
 
 	sendUserInput := func(msg *message.Message) {
 		p.Send(msg)
 	}
 	go func() {
 
-		for msg := range userInputCh {
-			err := agent.Chat(context.Background(), msg, agent.DefaultEndpoint(), sendUserInput)
+		for prompt := range userInputCh {
+			err := agent.Chat(context.Background(), prompt.Text, agent.DefaultEndpoint(), sendUserInput, prompt.Attachments...)
 			if err != nil {
 				log.Fatal(err)
 			}
 		}
 	}()
 
+	// Answers are sent while the run that asked is still streaming
+	go func() {
+		for answer := range answerCh {
+			go func(answer ui.Answer) {
+				if err := agent.Answer(context.Background(), agent.DefaultEndpoint(), answer.ToolCallID, answer.Content); err != nil {
+					log.Print(err)
+				}
+			}(answer)
+		}
+	}()
+
 	teaErr := runTea(p, userInputCh)
 	if teaErr != nil {
 		log.Fatal(teaErr)
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxAttachmentSize bounds attached files, base64 encoded they must fit the
+// server's request size limit
+const maxAttachmentSize = 20 << 20
+
+// Attachment is a local file sent with a message
+type Attachment struct {
+	Name     string
+	MimeType string
+	Data     []byte
+}
+
+// LoadAttachment reads the file at path, a leading ~ is the home directory
+func LoadAttachment(path string) (Attachment, error) {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return Attachment{}, err
+	}
+	if info.IsDir() {
+		return Attachment{}, fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > maxAttachmentSize {
+		return Attachment{}, fmt.Errorf("%s is larger than %d MB", path, maxAttachmentSize>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(path))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	return Attachment{Name: filepath.Base(path), MimeType: mimeType, Data: data}, nil
+}
+
+// content returns the content of a user message, a string or, with
+// attachments, a list of AG-UI text and binary parts
+func content(text string, attachments []Attachment) any {
+	if len(attachments) == 0 {
+		return text
+	}
+
+	var parts []map[string]any
+	if text != "" {
+		parts = append(parts, map[string]any{"type": "text", "text": text})
+	}
+	for _, a := range attachments {
+		parts = append(parts, map[string]any{
+			"type":     "binary",
+			"mimeType": a.MimeType,
+			"data":     base64.StdEncoding.EncodeToString(a.Data),
+			"filename": a.Name,
+		})
+	}
+	return parts
+}
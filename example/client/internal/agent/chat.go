@@ -16,7 +16,7 @@ func DefaultEndpoint() string {
 	return "http://localhost:8000/agentic"
 }
 
-func Chat(ctx context.Context, inputMsg string, endpoint string, send func(msg *message.Message)) error {
+func newClient(endpoint string) *sse.Client {
 	logger := logrus.New()
 	logger.SetLevel(logrus.FatalLevel)
 	sseConfig := sse.Config{
@@ -28,8 +28,12 @@ func Chat(ctx context.Context, inputMsg string, endpoint string, send func(msg *
 		AuthHeader:     "Authorization",
 		AuthScheme:     "Bearer",
 	}
+	return sse.NewClient(sseConfig)
+}
 
-	client := sse.NewClient(sseConfig)
+// Chat sends inputMsg with its attachments and passes the streamed messages to send
+func Chat(ctx context.Context, inputMsg string, endpoint string, send func(msg *message.Message), attachments ...Attachment) error {
+	client := newClient(endpoint)
 	defer func() {
 		client.Close()
 	}()
@@ -45,7 +49,7 @@ func Chat(ctx context.Context, inputMsg string, endpoint string, send func(msg *
 			{
 				"id":      "msg-1",
 				"role":    "user",
-				"content": inputMsg,
+				"content": content(inputMsg, attachments),
 			},
 		},
 		"tools":          []interface{}{},
@@ -65,6 +69,7 @@ func Chat(ctx context.Context, inputMsg string, endpoint string, send func(msg *
 	}
 
 	// Parse SSE events
+	stream := message.NewStream()
 	for {
 		select {
 		case frame, ok := <-frames:
@@ -76,7 +81,7 @@ func Chat(ctx context.Context, inputMsg string, endpoint string, send func(msg *
 			if err != nil {
 				return fmt.Errorf("failed to process SSE event %w", err)
 			}
-			currMsg := message.NewMessage(rawEvent)
+			currMsg := stream.Next(rawEvent)
 			if currMsg == nil {
 				return fmt.Errorf("failed to parse message %w", err)
 			}
@@ -95,3 +100,50 @@ func Chat(ctx context.Context, inputMsg string, endpoint string, send func(msg *
 		}
 	}
 }
+
+// Answer replies to an elicitation or approval request. The reply is sent as the result of the
+// tool call it is about, the run that asked keeps streaming through Chat.
+func Answer(ctx context.Context, endpoint string, toolCallID string, content string) error {
+	client := newClient(endpoint)
+	defer client.Close()
+
+	payload := map[string]interface{}{
+		"threadId": "test-session-1755371887",
+		"messages": []map[string]interface{}{
+			{
+				"id":         "answer-" + toolCallID,
+				"role":       "tool",
+				"toolCallId": toolCallID,
+				"content":    content,
+			},
+		},
+	}
+
+	frames, errorCh, err := client.Stream(sse.StreamOptions{
+		Context: ctx,
+		Payload: payload,
+	})
+	if err != nil {
+		return fmt.Errorf("send answer: %w", err)
+	}
+
+	// The server acknowledges with an empty run
+	for {
+		select {
+		case _, ok := <-frames:
+			if !ok {
+				return nil
+			}
+		case err, ok := <-errorCh:
+			if !ok {
+				errorCh = nil
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("send answer: %w", err)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
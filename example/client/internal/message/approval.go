@@ -0,0 +1,59 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ApprovalRequestEvent is the CUSTOM event the server sends before running a
+// tool that needs the user's approval
+const ApprovalRequestEvent = "approval_request"
+
+// Approval is a tool call the server is waiting for the user to approve
+type Approval struct {
+	ToolCallID string
+	ToolName   string
+	// Arguments are the call's arguments, indented when they are JSON
+	Arguments string
+}
+
+// approvalRequest is the value of an ApprovalRequestEvent
+type approvalRequest struct {
+	ToolCallID string          `json:"toolCallId"`
+	ToolName   string          `json:"toolName"`
+	Arguments  json.RawMessage `json:"arguments"`
+}
+
+// parseApproval reads the value of an ApprovalRequestEvent
+func parseApproval(value any) (*Approval, bool) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	var request approvalRequest
+	if err := json.Unmarshal(data, &request); err != nil || request.ToolCallID == "" {
+		return nil, false
+	}
+
+	// Arguments that are not JSON arrive as a JSON string
+	arguments := string(request.Arguments)
+	var text string
+	var indented bytes.Buffer
+	if err := json.Unmarshal(request.Arguments, &text); err == nil {
+		arguments = text
+	} else if err := json.Indent(&indented, request.Arguments, "", "  "); err == nil {
+		arguments = indented.String()
+	}
+
+	return &Approval{
+		ToolCallID: request.ToolCallID,
+		ToolName:   request.ToolName,
+		Arguments:  arguments,
+	}, true
+}
+
+// Answer is the reply that lets the call run, or skips it with reason
+func (a *Approval) Answer(approved bool, reason string) string {
+	data, _ := json.Marshal(map[string]any{"approved": approved, "reason": reason})
+	return string(data)
+}
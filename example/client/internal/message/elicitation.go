@@ -0,0 +1,187 @@
+package message
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ag-ui-protocol/ag-ui/sdks/community/go/pkg/core/events"
+)
+
+// ElicitToolName is the tool the server calls to ask the user for input on
+// behalf of an MCP tool
+const ElicitToolName = "elicit_user_input"
+
+// Elicitation is a question the server is waiting for the user to answer
+type Elicitation struct {
+	ToolCallID string
+	Message    string
+	Fields     []Field
+}
+
+// Field is one value the elicitation asks for
+type Field struct {
+	Name        string
+	Title       string
+	Description string
+	// Type is the JSON Schema type: string, integer, number or boolean
+	Type     string
+	Enum     []string
+	Required bool
+}
+
+// elicitationArgs are the arguments of an ElicitToolName call
+type elicitationArgs struct {
+	Message         string `json:"message"`
+	RequestedSchema struct {
+		Properties map[string]struct {
+			Type        string   `json:"type"`
+			Title       string   `json:"title"`
+			Description string   `json:"description"`
+			Enum        []string `json:"enum"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	} `json:"requestedSchema"`
+}
+
+func parseElicitation(toolCallID, args string) (*Elicitation, error) {
+	var parsed elicitationArgs
+	if err := json.Unmarshal([]byte(args), &parsed); err != nil {
+		return nil, fmt.Errorf("parse elicitation: %w", err)
+	}
+
+	required := make(map[string]bool, len(parsed.RequestedSchema.Required))
+	for _, name := range parsed.RequestedSchema.Required {
+		required[name] = true
+	}
+
+	e := &Elicitation{ToolCallID: toolCallID, Message: parsed.Message}
+	for name, property := range parsed.RequestedSchema.Properties {
+		e.Fields = append(e.Fields, Field{
+			Name:        name,
+			Title:       property.Title,
+			Description: property.Description,
+			Type:        property.Type,
+			Enum:        property.Enum,
+			Required:    required[name],
+		})
+	}
+	// Required fields first, then by name, so the order is stable
+	sort.Slice(e.Fields, func(i, j int) bool {
+		if e.Fields[i].Required != e.Fields[j].Required {
+			return e.Fields[i].Required
+		}
+		return e.Fields[i].Name < e.Fields[j].Name
+	})
+	return e, nil
+}
+
+// Label is the field's title, or its name when it has none
+func (f Field) Label() string {
+	if f.Title != "" {
+		return f.Title
+	}
+	return f.Name
+}
+
+// Parse converts the user's input to the field's type. Empty input is allowed
+// for optional fields and reported as nil.
+func (f Field) Parse(input string) (any, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		if f.Required {
+			return nil, fmt.Errorf("%s is required", f.Label())
+		}
+		return nil, nil
+	}
+
+	if len(f.Enum) > 0 {
+		for _, option := range f.Enum {
+			if strings.EqualFold(option, input) {
+				return option, nil
+			}
+		}
+		return nil, fmt.Errorf("expected one of %s", strings.Join(f.Enum, ", "))
+	}
+
+	switch f.Type {
+	case "integer":
+		n, err := strconv.ParseInt(input, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a whole number")
+		}
+		return n, nil
+	case "number":
+		n, err := strconv.ParseFloat(input, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number")
+		}
+		return n, nil
+	case "boolean":
+		switch strings.ToLower(input) {
+		case "y", "yes", "true":
+			return true, nil
+		case "n", "no", "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("expected yes or no")
+	default:
+		return input, nil
+	}
+}
+
+// Accept is the answer providing values
+func (e *Elicitation) Accept(values map[string]any) string {
+	data, _ := json.Marshal(map[string]any{"action": "accept", "content": values})
+	return string(data)
+}
+
+// Decline is the answer refusing to provide the values
+func (e *Elicitation) Decline() string {
+	return `{"action":"decline"}`
+}
+
+// Stream turns the events of one run into messages. Unlike NewMessage it
+// follows tool calls across events, so elicitations can be recognized.
+type Stream struct {
+	toolCalls map[string]*toolCall
+}
+
+type toolCall struct {
+	name string
+	args strings.Builder
+}
+
+func NewStream() *Stream {
+	return &Stream{toolCalls: make(map[string]*toolCall)}
+}
+
+// Next returns the message for event, nil if it has none
+func (s *Stream) Next(event events.Event) *Message {
+	msg := getMessageFromEvent(event)
+
+	switch e := event.(type) {
+	case *events.ToolCallStartEvent:
+		s.toolCalls[e.ToolCallID] = &toolCall{name: e.ToolCallName}
+	case *events.ToolCallArgsEvent:
+		if call, ok := s.toolCalls[e.ToolCallID]; ok {
+			call.args.WriteString(e.Delta)
+		}
+	case *events.ToolCallEndEvent:
+		call, ok := s.toolCalls[e.ToolCallID]
+		delete(s.toolCalls, e.ToolCallID)
+		if !ok || call.name != ElicitToolName || msg == nil {
+			break
+		}
+		elicitation, err := parseElicitation(e.ToolCallID, call.args.String())
+		if err != nil {
+			msg.contents = append(msg.contents, err.Error())
+			break
+		}
+		msg.contents = []string{"The server has a question: " + elicitation.Message}
+		msg.elicitation = elicitation
+	}
+	return msg
+}
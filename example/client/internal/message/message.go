@@ -11,13 +11,31 @@ import (
 var serverStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("21"))
 
 type Message struct {
-	contents []string
+	contents    []string
+	choices     []string
+	elicitation *Elicitation
+	approval    *Approval
 }
 
 func (m *Message) Strings() []string {
 	return m.contents
 }
 
+// Choices returns the options offered by a structured tool result, if any
+func (m *Message) Choices() []string {
+	return m.choices
+}
+
+// Elicitation returns the question the server asked the user, if any
+func (m *Message) Elicitation() *Elicitation {
+	return m.elicitation
+}
+
+// Approval returns the tool call the server wants the user to approve, if any
+func (m *Message) Approval() *Approval {
+	return m.approval
+}
+
 func NewMessage(event events.Event) *Message {
 	return getMessageFromEvent(event)
 }
@@ -114,9 +132,16 @@ func getMessageFromEvent(event events.Event) *Message {
 		if !ok {
 			return nil
 		}
-		curMsg := result.Content
+		// MCP results arrive as JSON, anything else is shown as is
+		toolResult, ok := parseToolResult(result.Content)
+		if !ok {
+			return &Message{
+				contents: []string{result.Content},
+			}
+		}
 		return &Message{
-			contents: []string{curMsg},
+			contents: toolResult.Lines(),
+			choices:  toolResult.Choices(),
 		}
 	case events.EventTypeStateSnapshot:
 		snapshot, ok := event.(*events.StateSnapshotEvent)
@@ -243,6 +268,35 @@ func getMessageFromEvent(event events.Event) *Message {
 		if !ok {
 			return nil
 		}
+		if evt.Name == ApprovalRequestEvent {
+			if approval, ok := parseApproval(evt.Value); ok {
+				return &Message{
+					contents: []string{fmt.Sprintf("Approval requested for %s", approval.ToolName)},
+					approval: approval,
+				}
+			}
+		}
+		if evt.Name == SubAgentEvent {
+			if text, ok := formatSubAgent(evt.Value); ok {
+				return &Message{
+					contents: []string{serverStyle.Render("Agent: ") + text},
+				}
+			}
+		}
+		if evt.Name == StructuredOutputEvent {
+			if text, ok := formatStructured(evt.Value); ok {
+				return &Message{
+					contents: []string{serverStyle.Render("Structured: ") + text},
+				}
+			}
+		}
+		if evt.Name == ToolProgressEvent {
+			if text, ok := formatProgress(evt.Value); ok {
+				return &Message{
+					contents: []string{serverStyle.Render("Progress: ") + text},
+				}
+			}
+		}
 		jsonData, err := json.Marshal(evt.Value)
 		if err != nil {
 			fmt.Println("Error marshaling JSON:", err)
@@ -0,0 +1,40 @@
+package message
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ToolProgressEvent is the CUSTOM event the server sends when a running tool
+// reports its progress
+const ToolProgressEvent = "tool_progress"
+
+// toolProgress is the value of a ToolProgressEvent
+type toolProgress struct {
+	ToolCallID string  `json:"toolCallId"`
+	ToolName   string  `json:"toolName"`
+	Progress   float64 `json:"progress"`
+	Total      float64 `json:"total"`
+	Message    string  `json:"message"`
+}
+
+// formatProgress renders the value of a ToolProgressEvent as one line
+func formatProgress(value any) (string, bool) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+	var progress toolProgress
+	if err := json.Unmarshal(data, &progress); err != nil || progress.ToolCallID == "" {
+		return "", false
+	}
+
+	text := fmt.Sprintf("%s: %g", progress.ToolName, progress.Progress)
+	if progress.Total > 0 {
+		text = fmt.Sprintf("%s: %g/%g", progress.ToolName, progress.Progress, progress.Total)
+	}
+	if progress.Message != "" {
+		text += " " + progress.Message
+	}
+	return text, true
+}
@@ -0,0 +1,16 @@
+package message
+
+import "encoding/json"
+
+// StructuredOutputEvent is the CUSTOM event the server sends with the answer
+// of an agent with an output schema, as an object matching the schema
+const StructuredOutputEvent = "structured_output"
+
+// formatStructured renders the value of a StructuredOutputEvent as indented JSON
+func formatStructured(value any) (string, bool) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
@@ -0,0 +1,42 @@
+package message
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SubAgentEvent is the CUSTOM event the server sends before each step,
+// message and tool call of a sub-agent working on a delegated task
+const SubAgentEvent = "sub_agent"
+
+// subAgent is the value of a SubAgentEvent, it names one of a step, a
+// message or a tool call
+type subAgent struct {
+	Agent            string `json:"agent"`
+	ParentToolCallID string `json:"parentToolCallId"`
+	StepName         string `json:"stepName"`
+	MessageID        string `json:"messageId"`
+	ToolCallID       string `json:"toolCallId"`
+	ToolName         string `json:"toolName"`
+}
+
+// formatSubAgent renders the value of a SubAgentEvent as one line naming the
+// agent, the IDs only correlate events
+func formatSubAgent(value any) (string, bool) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+	var agent subAgent
+	if err := json.Unmarshal(data, &agent); err != nil || agent.Agent == "" {
+		return "", false
+	}
+	switch {
+	case agent.ToolCallID != "":
+		return fmt.Sprintf("%s calls %s", agent.Agent, agent.ToolName), true
+	case agent.MessageID != "":
+		return fmt.Sprintf("%s writes", agent.Agent), true
+	default:
+		return fmt.Sprintf("%s works on its task", agent.Agent), true
+	}
+}
@@ -0,0 +1,164 @@
+package message
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChoicesMeta is the _meta key the server sets on results whose structured
+// content holds options for the user to pick from
+const ChoicesMeta = "ag-ui/choices"
+
+// ToolResult is the MCP tool result the server sends as TOOL_CALL_RESULT content
+type ToolResult struct {
+	Content           []ContentBlock `json:"content"`
+	StructuredContent any            `json:"structuredContent,omitempty"`
+	IsError           bool           `json:"isError,omitempty"`
+	Meta              map[string]any `json:"_meta,omitempty"`
+}
+
+// ContentBlock is one item of a tool result: text, image, audio, resource_link or resource
+type ContentBlock struct {
+	Type     string            `json:"type"`
+	Text     string            `json:"text,omitempty"`
+	Data     string            `json:"data,omitempty"`
+	MIMEType string            `json:"mimeType,omitempty"`
+	URI      string            `json:"uri,omitempty"`
+	Resource *EmbeddedResource `json:"resource,omitempty"`
+}
+
+// EmbeddedResource is the resource of a "resource" content block
+type EmbeddedResource struct {
+	URI      string `json:"uri"`
+	MIMEType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// parseToolResult decodes TOOL_CALL_RESULT content, reporting false for plain text results
+func parseToolResult(content string) (*ToolResult, bool) {
+	var result ToolResult
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return nil, false
+	}
+	if result.Content == nil && result.StructuredContent == nil {
+		return nil, false
+	}
+	return &result, true
+}
+
+// Lines renders the result for display. Structured content that offers a set of
+// choices is rendered as a numbered list, see Choices, other structured content
+// as JSON.
+func (r *ToolResult) Lines() []string {
+	var lines []string
+	if choices := r.Choices(); len(choices) > 0 {
+		list := make([]string, 0, len(choices)+1)
+		list = append(list, "Tool result options:")
+		for i, choice := range choices {
+			list = append(list, fmt.Sprintf("  %d. %s", i+1, choice))
+		}
+		lines = append(lines, strings.Join(list, "\n"))
+	}
+
+	for _, block := range r.Content {
+		switch block.Type {
+		case "text":
+			// Text is the fallback rendering of structured content, skip the duplicate
+			if r.StructuredContent == nil {
+				lines = append(lines, block.Text)
+			}
+		case "image", "audio":
+			lines = append(lines, fmt.Sprintf("[%s: %s, %s]", block.Type, block.MIMEType, formatSize(decodedSize(block.Data))))
+		case "resource_link":
+			lines = append(lines, fmt.Sprintf("[resource: %s]", block.URI))
+		case "resource":
+			if block.Resource == nil {
+				continue
+			}
+			if block.Resource.Text != "" {
+				lines = append(lines, fmt.Sprintf("[resource: %s]\n%s", block.Resource.URI, block.Resource.Text))
+			} else {
+				lines = append(lines, fmt.Sprintf("[resource: %s, %s]", block.Resource.URI, block.Resource.MIMEType))
+			}
+		}
+	}
+
+	if len(lines) == 0 && r.StructuredContent != nil {
+		if data, err := json.MarshalIndent(r.StructuredContent, "", "  "); err == nil {
+			lines = append(lines, string(data))
+		}
+	}
+
+	if r.IsError {
+		for i, line := range lines {
+			lines[i] = "Tool error: " + line
+		}
+	}
+	return lines
+}
+
+// Choices returns the options of a result the tool marked with ChoicesMeta:
+// a list of strings, an object of string values such as {"option1": "Go", ...}
+// in key order, or an object holding a single list of strings
+func (r *ToolResult) Choices() []string {
+	if r.IsError || r.Meta[ChoicesMeta] != true {
+		return nil
+	}
+
+	switch v := r.StructuredContent.(type) {
+	case []any:
+		return stringList(v)
+	case map[string]any:
+		if len(v) == 1 {
+			for _, value := range v {
+				if list, ok := value.([]any); ok {
+					return stringList(list)
+				}
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		choices := make([]string, 0, len(keys))
+		for _, key := range keys {
+			s, ok := v[key].(string)
+			if !ok {
+				return nil
+			}
+			choices = append(choices, s)
+		}
+		return choices
+	}
+	return nil
+}
+
+func stringList(values []any) []string {
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil
+		}
+		list = append(list, s)
+	}
+	return list
+}
+
+// decodedSize is the size of base64 encoded data
+func decodedSize(data string) int {
+	return len(strings.TrimRight(data, "=")) * 3 / 4
+}
+
+func formatSize(n int) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	return fmt.Sprintf("%.1f KB", float64(n)/1024)
+}
@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattsp1290/october-talks-2025/example/client/internal/message"
+)
+
+// approvalPrompt asks the user to approve or reject a tool call
+type approvalPrompt struct {
+	approval *message.Approval
+}
+
+func (p *approvalPrompt) active() bool {
+	return p.approval != nil
+}
+
+func (p *approvalPrompt) set(approval *message.Approval) {
+	p.approval = approval
+}
+
+// update returns the answer and whether the call was approved once the user
+// approves or rejects it. Only y approves, so a stray Enter meant for the
+// input does not run the tool.
+func (p *approvalPrompt) update(msg tea.KeyMsg) (*Answer, bool) {
+	var approved bool
+	switch msg.String() {
+	case "y":
+		approved = true
+	case "n", "esc":
+		approved = false
+	default:
+		return nil, false
+	}
+
+	answer := &Answer{ToolCallID: p.approval.ToolCallID, Content: p.approval.Answer(approved, "")}
+	p.approval = nil
+	return answer, approved
+}
+
+func (p *approvalPrompt) View() string {
+	var b strings.Builder
+	b.WriteString(ChoiceTitleStyle.Render("Run " + p.approval.ToolName + "?"))
+	if p.approval.Arguments != "" {
+		b.WriteString("\n" + ChoiceStyle.Render(p.approval.Arguments))
+	}
+	return InputContainerStyle.Render(b.String())
+}
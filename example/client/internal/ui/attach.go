@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattsp1290/october-talks-2025/example/client/internal/agent"
+)
+
+// Prompt is a message the user sends, with the files attached to it
+type Prompt struct {
+	Text        string
+	Attachments []agent.Attachment
+}
+
+// attachments holds the files attached to the next message
+type attachments struct {
+	pending []agent.Attachment
+	// notice reports the last /attach problem
+	notice string
+}
+
+// command runs /attach <path> and /detach, and reports whether text was one
+func (a *attachments) command(text string) bool {
+	command, arg, _ := strings.Cut(strings.TrimSpace(text), " ")
+	switch command {
+	case "/attach":
+		arg = strings.TrimSpace(arg)
+		if arg == "" {
+			a.notice = "usage: /attach <path>"
+			return true
+		}
+		attachment, err := agent.LoadAttachment(arg)
+		if err != nil {
+			a.notice = err.Error()
+			return true
+		}
+		a.pending = append(a.pending, attachment)
+		a.notice = ""
+		return true
+	case "/detach":
+		a.pending, a.notice = nil, ""
+		return true
+	}
+	return false
+}
+
+// take returns the pending attachments and clears them
+func (a *attachments) take() []agent.Attachment {
+	taken := a.pending
+	a.pending, a.notice = nil, ""
+	return taken
+}
+
+func (a *attachments) View() string {
+	var lines []string
+	if len(a.pending) > 0 {
+		names := make([]string, 0, len(a.pending))
+		for _, attachment := range a.pending {
+			names = append(names, fmt.Sprintf("%s (%s)", attachment.Name, attachment.MimeType))
+		}
+		lines = append(lines, FieldHintStyle.Render("📎 "+strings.Join(names, ", ")+" · /detach to remove"))
+	}
+	if a.notice != "" {
+		lines = append(lines, FieldErrorStyle.Render(a.notice))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// attachmentNames returns the names shown with a sent message
+func attachmentNames(attached []agent.Attachment) []string {
+	names := make([]string, 0, len(attached))
+	for _, attachment := range attached {
+		names = append(names, attachment.Name)
+	}
+	return names
+}
@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// choiceList lets the user pick one of the options a tool result offered
+type choiceList struct {
+	options []string
+	cursor  int
+}
+
+func (c *choiceList) active() bool {
+	return len(c.options) > 0
+}
+
+func (c *choiceList) set(options []string) {
+	c.options = options
+	c.cursor = 0
+}
+
+func (c *choiceList) clear() {
+	c.options = nil
+	c.cursor = 0
+}
+
+// update moves the cursor and returns the chosen option once one is picked
+func (c *choiceList) update(msg tea.KeyMsg) (chosen string, done bool) {
+	switch msg.String() {
+	case "up", "k", "shift+tab":
+		if c.cursor > 0 {
+			c.cursor--
+		}
+	case "down", "j", "tab":
+		if c.cursor < len(c.options)-1 {
+			c.cursor++
+		}
+	case "enter":
+		chosen = c.options[c.cursor]
+		c.clear()
+		return chosen, true
+	case "esc":
+		c.clear()
+		return "", true
+	default:
+		// Number keys pick directly
+		if key := msg.String(); len(key) == 1 && key[0] >= '1' && key[0] <= '9' {
+			if i := int(key[0] - '1'); i < len(c.options) {
+				chosen = c.options[i]
+				c.clear()
+				return chosen, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (c *choiceList) View() string {
+	var b strings.Builder
+	b.WriteString(ChoiceTitleStyle.Render("Select an option"))
+	for i, option := range c.options {
+		b.WriteString("\n")
+		if i == c.cursor {
+			b.WriteString(SelectedChoiceStyle.Render("▸ " + option))
+		} else {
+			b.WriteString(ChoiceStyle.Render("  " + option))
+		}
+	}
+	return InputContainerStyle.Render(b.String())
+}
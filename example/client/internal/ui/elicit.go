@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattsp1290/october-talks-2025/example/client/internal/message"
+)
+
+// Answer is the user's reply to an elicitation or approval request, sent while its
+// run is still streaming
+type Answer struct {
+	ToolCallID string
+	Content    string
+}
+
+// elicitForm asks the user for the fields of an elicitation one at a time
+type elicitForm struct {
+	elicitation *message.Elicitation
+	field       int
+	values      map[string]any
+	input       textinput.Model
+	err         string
+}
+
+func (f *elicitForm) active() bool {
+	return f.elicitation != nil
+}
+
+func (f *elicitForm) set(elicitation *message.Elicitation) tea.Cmd {
+	f.elicitation = elicitation
+	f.field = 0
+	f.values = make(map[string]any)
+	f.err = ""
+	f.input = textinput.New()
+	f.input.Prompt = "│ "
+	f.input.PromptStyle = InputPromptStyle
+	f.input.TextStyle = InputTextStyle
+	return f.input.Focus()
+}
+
+func (f *elicitForm) clear() {
+	f.elicitation = nil
+	f.values = nil
+}
+
+// update edits the current field and returns the answer once the last field
+// is entered or the form is declined
+func (f *elicitForm) update(msg tea.KeyMsg) (*Answer, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		answer := &Answer{ToolCallID: f.elicitation.ToolCallID, Content: f.elicitation.Decline()}
+		f.clear()
+		return answer, nil
+	case tea.KeyEnter:
+		if f.field < len(f.elicitation.Fields) {
+			value, err := f.elicitation.Fields[f.field].Parse(f.input.Value())
+			if err != nil {
+				f.err = err.Error()
+				return nil, nil
+			}
+			if value != nil {
+				f.values[f.elicitation.Fields[f.field].Name] = value
+			}
+			f.field++
+			f.err = ""
+			f.input.Reset()
+		}
+		if f.field >= len(f.elicitation.Fields) {
+			answer := &Answer{ToolCallID: f.elicitation.ToolCallID, Content: f.elicitation.Accept(f.values)}
+			f.clear()
+			return answer, nil
+		}
+		return nil, nil
+	}
+
+	var cmd tea.Cmd
+	f.input, cmd = f.input.Update(msg)
+	return nil, cmd
+}
+
+func (f *elicitForm) View() string {
+	var b strings.Builder
+	b.WriteString(ChoiceTitleStyle.Render(f.elicitation.Message))
+
+	if f.field < len(f.elicitation.Fields) {
+		field := f.elicitation.Fields[f.field]
+		hint := field.Type
+		if len(field.Enum) > 0 {
+			hint = strings.Join(field.Enum, " / ")
+		}
+		if !field.Required {
+			hint += ", optional"
+		}
+		b.WriteString(fmt.Sprintf("\n%s %s", ChoiceStyle.Render(field.Label()), FieldHintStyle.Render("("+hint+")")))
+		if field.Description != "" {
+			b.WriteString("\n" + FieldHintStyle.Render(field.Description))
+		}
+		b.WriteString("\n" + f.input.View())
+	} else {
+		b.WriteString("\n" + FieldHintStyle.Render("Press Enter to confirm"))
+	}
+
+	if f.err != "" {
+		b.WriteString("\n" + FieldErrorStyle.Render(f.err))
+	}
+	return InputContainerStyle.Render(b.String())
+}
@@ -18,6 +18,8 @@ type UIMessage struct {
 	Role      string
 	Content   string
 	Timestamp time.Time
+	// Attachments are the names of the files sent with the message
+	Attachments []string
 }
 
 func NewUIMessage(role, content string) UIMessage {
@@ -43,6 +45,9 @@ func (m UIMessage) String() string {
 	timestamp := m.Timestamp.Format("15:04")
 	header := fmt.Sprintf("%s %s", roleStyle.Render(rolePrefix), TimestampStyle.Render(timestamp))
 	content := MessageContentStyle.Render(m.Content)
+	for _, name := range m.Attachments {
+		content += "\n" + MessageContentStyle.Render(FieldHintStyle.Render("📎 "+name))
+	}
 	
 	return fmt.Sprintf("%s\n%s", header, content)
 }
@@ -51,10 +56,16 @@ type Model struct {
 	messages       []UIMessage
 	viewport       viewport.Model
 	textarea       textarea.Model
-	userInput      chan string
+	userInput      chan Prompt
+	answers        chan<- Answer
+	attachments    attachments
 	ready          bool
 	waitingForResp bool
 	typingDots     int
+	choices        choiceList
+	elicit         elicitForm
+	approval       approvalPrompt
+	requests       []request
 }
 
 func (m *Model) updateViewportContent() {
@@ -105,7 +116,9 @@ func getTextarea() textarea.Model {
 	return ta
 }
 
-func InitialModel(userInput chan string) *Model {
+// InitialModel creates the chat model. Messages the user sends go to userInput,
+// answers to the server's questions go to answers.
+func InitialModel(userInput chan Prompt, answers chan<- Answer) *Model {
 	vp := viewport.New(80, 20)
 	vp.KeyMap = viewport.KeyMap{
 		Up:       key.NewBinding(key.WithKeys("up", "k")),
@@ -118,6 +131,7 @@ func InitialModel(userInput chan string) *Model {
 		viewport:  vp,
 		textarea:  getTextarea(),
 		userInput: userInput,
+		answers:   answers,
 		messages:  []UIMessage{},
 	}
 }
@@ -126,12 +140,83 @@ func (m *Model) Init() tea.Cmd {
 	return tea.Batch(textarea.Blink, tea.EnterAltScreen, tickCmd())
 }
 
+// send submits text as the user's next message, with the files attached to it.
+// While a response is still streaming it is left in the input for the user to
+// send once it finishes.
+func (m *Model) send(text string) {
+	if m.waitingForResp {
+		m.textarea.SetValue(text)
+		m.textarea.Focus()
+		return
+	}
+
+	attached := m.attachments.take()
+	m.userInput <- Prompt{Text: text, Attachments: attached}
+
+	// Add to messages
+	uiMsg := NewUIMessage("user", text)
+	uiMsg.Attachments = attachmentNames(attached)
+	m.messages = append(m.messages, uiMsg)
+	m.updateViewportContent()
+	m.textarea.Reset()
+	m.waitingForResp = true
+}
+
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var (
 		tiCmd tea.Cmd
 		vpCmd tea.Cmd
 	)
 
+	// A tool call waiting for approval takes the keyboard until it is approved or rejected
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && m.approval.active() && keyMsg.Type != tea.KeyCtrlC {
+		tool := m.approval.approval.ToolName
+		answer, approved := m.approval.update(keyMsg)
+		if answer == nil {
+			return m, nil
+		}
+		m.answers <- *answer
+		verdict := "Rejected " + tool
+		if approved {
+			verdict = "Approved " + tool
+		}
+		m.messages = append(m.messages, NewUIMessage("user", verdict))
+		m.updateViewportContent()
+		if cmd, ok := m.nextRequest(); ok {
+			return m, cmd
+		}
+		m.textarea.Focus()
+		return m, textarea.Blink
+	}
+
+	// A question from the server takes the keyboard until it is answered or declined
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && m.elicit.active() && keyMsg.Type != tea.KeyCtrlC {
+		answer, cmd := m.elicit.update(keyMsg)
+		if answer == nil {
+			return m, cmd
+		}
+		m.answers <- *answer
+		m.messages = append(m.messages, NewUIMessage("user", answer.Content))
+		m.updateViewportContent()
+		if cmd, ok := m.nextRequest(); ok {
+			return m, cmd
+		}
+		m.textarea.Focus()
+		return m, textarea.Blink
+	}
+
+	// An open choice list takes the keyboard until an option is picked or dismissed
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && m.choices.active() && keyMsg.Type != tea.KeyCtrlC {
+		if chosen, done := m.choices.update(keyMsg); done {
+			if chosen != "" {
+				m.send(chosen)
+			}
+			m.textarea.Focus()
+			return m, textarea.Blink
+		}
+		return m, nil
+	}
+
 	m.textarea, tiCmd = m.textarea.Update(msg)
 	m.viewport, vpCmd = m.viewport.Update(msg)
 
@@ -173,16 +258,12 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.viewport.YOffset = 0
 			}
 		case tea.KeyEnter:
-			if m.textarea.Focused() && m.textarea.Value() != "" && !m.waitingForResp {
-				// Send the message
-				m.userInput <- m.textarea.Value()
-
-				// Add to messages
-				uiMsg := NewUIMessage("user", m.textarea.Value())
-				m.messages = append(m.messages, uiMsg)
-				m.updateViewportContent()
+			if m.textarea.Focused() && m.attachments.command(m.textarea.Value()) {
+				// /attach and /detach change the files sent with the next message
 				m.textarea.Reset()
-				m.waitingForResp = true
+			} else if m.textarea.Focused() && (m.textarea.Value() != "" || len(m.attachments.pending) > 0) && !m.waitingForResp {
+				// Send the message
+				m.send(m.textarea.Value())
 			}
 		default:
 			if !m.textarea.Focused() {
@@ -201,7 +282,16 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			uiMsg := NewUIMessage("assistant", currMsg)
 			m.messages = append(m.messages, uiMsg)
 		}
+		if choices := msg.Choices(); len(choices) > 0 {
+			m.choices.set(choices)
+			m.textarea.Blur()
+		}
 		m.updateViewportContent()
+		// Requests arriving while another is shown wait for their turn
+		m.queue(msg)
+		if cmd, _ := m.nextRequest(); cmd != nil {
+			return m, tea.Batch(tiCmd, vpCmd, cmd)
+		}
 	
 	case tickMsg:
 		m.typingDots++
@@ -235,15 +325,62 @@ func (m *Model) View() string {
 	}
 	header := HeaderStyle.Render("💬 Chat" + msgCount)
 
+	inputView := m.textareaView()
+
+	// Build help text with styled keys
+	helpItems := []string{
+		HelpKeyStyle.Render("i/a") + " " + HelpDescStyle.Render("input mode"),
+		HelpKeyStyle.Render("Esc") + " " + HelpDescStyle.Render("normal mode"),
+		HelpKeyStyle.Render("Enter") + " " + HelpDescStyle.Render("send"),
+		HelpKeyStyle.Render("/attach <path>") + " " + HelpDescStyle.Render("attach file"),
+		HelpKeyStyle.Render("Ctrl+C") + " " + HelpDescStyle.Render("quit"),
+	}
+	if attached := m.attachments.View(); attached != "" {
+		inputView = attached + "\n" + inputView
+	}
+
+	if m.approval.active() {
+		inputView = m.approval.View()
+		helpItems = []string{
+			HelpKeyStyle.Render("y") + " " + HelpDescStyle.Render("approve"),
+			HelpKeyStyle.Render("n/Esc") + " " + HelpDescStyle.Render("reject"),
+		}
+	} else if m.elicit.active() {
+		inputView = m.elicit.View()
+		helpItems = []string{
+			HelpKeyStyle.Render("Enter") + " " + HelpDescStyle.Render("next"),
+			HelpKeyStyle.Render("Esc") + " " + HelpDescStyle.Render("decline"),
+		}
+	} else if m.choices.active() {
+		inputView = m.choices.View()
+		helpItems = []string{
+			HelpKeyStyle.Render("↑/↓") + " " + HelpDescStyle.Render("choose"),
+			HelpKeyStyle.Render("1-9") + " " + HelpDescStyle.Render("pick"),
+			HelpKeyStyle.Render("Enter") + " " + HelpDescStyle.Render("select"),
+			HelpKeyStyle.Render("Esc") + " " + HelpDescStyle.Render("dismiss"),
+		}
+	}
+	if len(m.requests) > 0 {
+		helpItems = append(helpItems, HelpDescStyle.Render(fmt.Sprintf("%d more waiting", len(m.requests))))
+	}
+
+	// Shrink the viewport so a form or choice list taller than the input still fits
+	vp := m.viewport
+	if extra := lipgloss.Height(inputView) - lipgloss.Height(m.textareaView()); extra > 0 && vp.Height > extra {
+		vp.Height -= extra
+		vp.GotoBottom()
+	}
+	help := HelpStyle.Render(strings.Join(helpItems, " • "))
+
 	// Viewport with scroll indicator
 	viewportView := ViewportStyle.
-		Width(m.viewport.Width + 2).
-		Height(m.viewport.Height + 2).
-		Render(m.viewport.View())
+		Width(vp.Width + 2).
+		Height(vp.Height + 2).
+		Render(vp.View())
 
 	// Add scroll percentage if there are messages
-	if len(m.messages) > 0 && m.viewport.TotalLineCount() > m.viewport.Height {
-		percent := float64(m.viewport.YOffset) / float64(m.viewport.TotalLineCount()-m.viewport.Height) * 100
+	if len(m.messages) > 0 && vp.TotalLineCount() > vp.Height {
+		percent := float64(vp.YOffset) / float64(vp.TotalLineCount()-vp.Height) * 100
 		if percent < 0 {
 			percent = 0
 		}
@@ -254,17 +391,6 @@ func (m *Model) View() string {
 		viewportView = lipgloss.JoinHorizontal(lipgloss.Top, viewportView, scrollInfo)
 	}
 
-	inputView := m.textareaView()
-
-	// Build help text with styled keys
-	helpItems := []string{
-		HelpKeyStyle.Render("i/a") + " " + HelpDescStyle.Render("input mode"),
-		HelpKeyStyle.Render("Esc") + " " + HelpDescStyle.Render("normal mode"),
-		HelpKeyStyle.Render("Enter") + " " + HelpDescStyle.Render("send"),
-		HelpKeyStyle.Render("Ctrl+C") + " " + HelpDescStyle.Render("quit"),
-	}
-	help := HelpStyle.Render(strings.Join(helpItems, " • "))
-
 	// Add input mode indicator
 	if m.textarea.Focused() {
 		inputMode := lipgloss.NewStyle().
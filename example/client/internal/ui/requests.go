@@ -0,0 +1,43 @@
+package ui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattsp1290/october-talks-2025/example/client/internal/message"
+)
+
+// request is an approval or elicitation waiting for the user, only one of
+// the fields is set
+type request struct {
+	approval    *message.Approval
+	elicitation *message.Elicitation
+}
+
+// queue holds the requests a run sent while another one was shown
+func (m *Model) queue(msg *message.Message) {
+	if approval := msg.Approval(); approval != nil {
+		m.requests = append(m.requests, request{approval: approval})
+	}
+	if elicitation := msg.Elicitation(); elicitation != nil {
+		m.requests = append(m.requests, request{elicitation: elicitation})
+	}
+}
+
+// nextRequest shows the oldest queued request unless one is shown already,
+// so requests are answered in the order they arrived. It reports whether a
+// request holds the keyboard, with the command focusing its input.
+func (m *Model) nextRequest() (tea.Cmd, bool) {
+	if m.approval.active() || m.elicit.active() {
+		return nil, true
+	}
+	if len(m.requests) == 0 {
+		return nil, false
+	}
+	next := m.requests[0]
+	m.requests = m.requests[1:]
+	m.textarea.Blur()
+	if next.approval != nil {
+		m.approval.set(next.approval)
+		return nil, true
+	}
+	return m.elicit.set(next.elicitation), true
+}
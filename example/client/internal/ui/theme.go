@@ -76,6 +76,25 @@ var (
 	InputPlaceholderStyle = lipgloss.NewStyle().
 		Foreground(mutedTextColor)
 
+	// Choice list styles
+	ChoiceTitleStyle = lipgloss.NewStyle().
+		Foreground(secondaryColor).
+		Bold(true)
+
+	ChoiceStyle = lipgloss.NewStyle().
+		Foreground(textColor)
+
+	SelectedChoiceStyle = lipgloss.NewStyle().
+		Foreground(accentColor).
+		Bold(true)
+
+	// Elicitation form styles
+	FieldHintStyle = lipgloss.NewStyle().
+		Foreground(mutedTextColor)
+
+	FieldErrorStyle = lipgloss.NewStyle().
+		Foreground(errorColor)
+
 	// Help styles
 	HelpStyle = lipgloss.NewStyle().
 		Foreground(mutedTextColor).
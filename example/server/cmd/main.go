@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -12,12 +14,21 @@ import (
 	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/fiber/v3/middleware/cors"
 	"github.com/gofiber/fiber/v3/middleware/requestid"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/agentic"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/cassette"
 	"github.com/mattsp1290/october-talks-2025/example/server/internal/config"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/health"
 	"github.com/mattsp1290/october-talks-2025/example/server/internal/mcp"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/prompt"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/rag"
 	"github.com/mattsp1290/october-talks-2025/example/server/internal/routes"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/runs"
 	"github.com/sirupsen/logrus"
 )
 
+// shutdownTimeout bounds stopping the listeners once runs have drained
+const shutdownTimeout = 5 * time.Second
+
 func newErrorHandler() fiber.ErrorHandler {
 	return func(c fiber.Ctx, err error) error {
 		code := fiber.StatusInternalServerError
@@ -39,55 +50,114 @@ func newErrorHandler() fiber.ErrorHandler {
 	}
 }
 
-func registerRoutes(app *fiber.App, cfg *config.Config) {
+func registerRoutes(app *fiber.App, cfgs config.Provider, checker *health.Checker, deps routes.Deps) {
+	cfg := cfgs.Current()
 
 	// Basic info route
 	app.Get("/", func(c fiber.Ctx) error {
 		return c.JSON(fiber.Map{
 			"message": "AG-UI Go Example Server is running!",
-			"path":    c.Path(),
-			"method":  c.Method(),
-			"headers": c.GetReqHeaders(),
 		})
 	})
 
+	// Health, readiness and build info
+	app.Get("/healthz", routes.HealthzHandler())
+	app.Get("/readyz", routes.ReadyzHandler(checker))
+	app.Get("/version", routes.VersionHandler())
+
 	if !cfg.EnableSSE {
 		return
 	}
 
 	// Feature routes
-	app.Post("/agentic", routes.AgenticHandler(cfg))
+	agentHandler := routes.AgenticHandler(cfgs, deps)
+	app.Post("/agentic", agentHandler)
+	app.Post("/agents/:name", agentHandler)
 }
 
-func logConfig(logger *logrus.Logger, cfg *config.Config) {
-	logger.WithFields(logrus.Fields{
-		"host":                  cfg.Host,
-		"port":                  cfg.Port,
-		"log_level":             cfg.LogLevel,
-		"enable_sse":            cfg.EnableSSE,
-		"read_timeout":          cfg.ReadTimeout,
-		"write_timeout":         cfg.WriteTimeout,
-		"sse_keepalive":         cfg.SSEKeepAlive,
-		"cors_enabled":          cfg.CORSEnabled,
-		"streaming_chunk_delay": cfg.StreamingChunkDelay,
-	}).Info("Server configuration loaded")
+// packOptions configures the embedded MCP server's built-in tools
+func packOptions(cfg *config.Config) mcp.PackOptions {
+	opts := mcp.PackOptions{
+		FilesRoot:    cfg.FilesRoot,
+		AllowedHosts: cfg.FetchAllowedHosts,
+		Mirrors:      cfg.FetchMirrors,
+		Limits:       map[string]mcp.ToolLimits{},
+		Code: mcp.CodeOptions{
+			Enabled:     cfg.CodeExecution,
+			MemoryLimit: cfg.CodeMemoryLimit << 20,
+			Concurrency: cfg.CodeConcurrency,
+			Runtimes:    map[string]mcp.CodeRuntime{},
+		},
+	}
+	for language, runtime := range cfg.CodeRuntimes {
+		opts.Code.Runtimes[language] = mcp.CodeRuntime{Module: runtime.Module, Args: runtime.Args}
+	}
+	for name, tool := range cfg.BuiltinTools {
+		if !cfg.BuiltinToolEnabled(name) {
+			opts.Disabled = append(opts.Disabled, name)
+		}
+		opts.Limits[name] = mcp.ToolLimits{Timeout: tool.TimeoutDuration(), MaxOutput: tool.MaxOutput}
+	}
+	return opts
+}
+
+// applyLogLevel sets both the logrus logger and the default slog handler to the configured level
+func applyLogLevel(logger *logrus.Logger, slogLevel *slog.LevelVar, cfg *config.Config) {
+	if level, err := logrus.ParseLevel(cfg.LogLevel); err == nil {
+		logger.SetLevel(level)
+	}
+	slogLevel.Set(cfg.GetLogLevel())
 }
 
-func createApp(cfg *config.Config, logger *logrus.Logger) *fiber.App {
+// newChecker registers the readiness checks: the embedded MCP server (if any) is
+// listening, every MCP endpoint lists its tools on its shared connection and
+// the LLM provider is reachable, unless runs are replayed from a cassette
+func newChecker(cfgs config.Provider, mcpServer *mcp.Server, adapters *mcp.Registry) *health.Checker {
+	checker := health.NewChecker()
+	if mcpServer != nil {
+		checker.Register("mcp_server", mcpServer.CheckListening)
+	}
+	checker.Register("mcp_tools", func(ctx context.Context) error {
+		var errs []error
+		for _, endpoint := range cfgs.Current().MCPEndpoints() {
+			if err := adapters.Check(ctx, endpoint); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	})
+	checker.Register("llm_provider", func(ctx context.Context) error {
+		// Replayed runs never call the provider
+		if cassette.Mode(cfgs.Current().CassetteMode) == cassette.ModeReplay {
+			return nil
+		}
+		return agentic.CheckProvider(ctx)
+	})
+	return checker
+}
+
+// maxBodySize leaves room for base64 encoded attachments in run input
+const maxBodySize = 32 << 20
+
+func createApp(cfgs config.Provider, checker *health.Checker, deps routes.Deps, logger *logrus.Logger) *fiber.App {
+	cfg := cfgs.Current()
 	app := fiber.New(fiber.Config{
 		AppName:      "AG-UI Example Server",
 		ReadTimeout:  cfg.ReadTimeout,
 		WriteTimeout: cfg.WriteTimeout,
+		BodyLimit:    maxBodySize,
 		ErrorHandler: newErrorHandler(),
 	})
 
 	// Middleware
 	app.Use(requestid.New())
 
-	// CORS
+	// CORS, allowed origins are evaluated per request so they follow config reloads
 	if cfg.CORSEnabled {
 		app.Use(cors.New(cors.Config{
-			AllowOrigins:     cfg.CORSAllowedOrigins,
+			AllowOriginsFunc: func(origin string) bool {
+				return cfgs.Current().OriginAllowed(origin)
+			},
 			AllowMethods:     []string{"GET", "POST", "HEAD", "PUT", "DELETE", "PATCH", "OPTIONS"},
 			AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID"},
 			AllowCredentials: false,
@@ -102,26 +172,101 @@ func createApp(cfg *config.Config, logger *logrus.Logger) *fiber.App {
 	//}))
 
 	// Routes
-	registerRoutes(app, cfg)
+	registerRoutes(app, cfgs, checker, deps)
 
 	return app
 }
 
 func main() {
-	// Load configuration with proper precedence: flags > env > defaults
+	// Load configuration with proper precedence: flags > env > file > defaults
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Set up structured logging with logrus
+	// Set up structured logging with logrus, and route slog through a reloadable level
 	logger := logrus.New()
+	slogLevel := new(slog.LevelVar)
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slogLevel})))
+	applyLogLevel(logger, slogLevel, cfg)
 
 	// Log the effective configuration
-	logConfig(logger, cfg)
+	logger.WithFields(cfg.LogFields()).Info("Server configuration loaded")
 
-	app := createApp(cfg, logger)
+	// Reload non-listener settings on SIGHUP or config file changes
+	watcher := config.NewWatcher(cfg, logger)
+	watcher.OnReload(func(next *config.Config) {
+		applyLogLevel(logger, slogLevel, next)
+	})
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go func() {
+		if err := watcher.Run(watchCtx); err != nil {
+			logger.WithError(err).Error("Config watcher stopped")
+		}
+	}()
+
+	// Create the embedded MCP server up front so readiness can check it.
+	// With it disabled the agent only uses the external servers from the config.
+	var mcpServer *mcp.Server
+	if cfg.EmbeddedMCP {
+		pack, err := mcp.ToolPack(packOptions(cfg))
+		if err != nil {
+			logger.WithError(err).Error("Failed to create built-in tools")
+			os.Exit(1)
+		}
+		mcpServer, err = mcp.NewServer(cfg.MCPPort,
+			mcp.WithTools(pack...),
+			mcp.WithToolsDir(cfg.MCPToolsDir),
+			mcp.WithPromptsDir(cfg.MCPPromptsDir),
+			mcp.WithResourcesDir(cfg.MCPResourcesDir),
+		)
+		if err != nil {
+			logger.WithError(err).Error("Failed to create MCP server")
+			os.Exit(1)
+		}
+	}
+
+	// Prompt templates are reloaded when their files change
+	templates, err := prompt.Load(cfg.PromptTemplatesDir)
+	if err != nil {
+		logger.WithError(err).Error("Failed to load prompt templates")
+		os.Exit(1)
+	}
+	go func() {
+		if err := templates.Watch(watchCtx, logger); err != nil {
+			logger.WithError(err).Error("Prompt templates watcher stopped")
+		}
+	}()
+
+	// The document search indexes the docs directory once, at startup
+	var documents *rag.Store
+	if cfg.DocsDir != "" {
+		embedder, err := rag.NewEmbedder(cfg.EmbeddingProvider, cfg.EmbeddingModel)
+		if err != nil {
+			logger.WithError(err).Error("Failed to create embedder")
+			os.Exit(1)
+		}
+		if documents, err = rag.Index(context.Background(), cfg.DocsDir, embedder); err != nil {
+			logger.WithError(err).Error("Failed to index documents")
+			os.Exit(1)
+		}
+		logger.WithFields(logrus.Fields{"docs_dir": cfg.DocsDir, "chunks": documents.Len()}).Info("Documents indexed")
+	}
+
+	adapters := mcp.NewRegistry()
+	checker := newChecker(watcher, mcpServer, adapters)
+	tracker := runs.NewTracker()
+	app := createApp(watcher, checker, routes.Deps{
+		Tracker:      tracker,
+		Adapters:     adapters,
+		Interactions: agentic.NewInteractions(),
+		Templates:    templates,
+		History:      agentic.NewHistoryCache(),
+		Files:        agentic.NewFiles(),
+		Documents:    documents,
+	}, logger)
 
 	// Start server in a goroutine
 	serverAddr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
@@ -137,18 +282,23 @@ func main() {
 	logger.WithField("address", serverAddr).Info("Server started successfully")
 
 	// Start mcp in a goroutine
-	mcpServer, err := mcp.NewServer(mcp.DefaultPort)
-	if err != nil {
-		logger.WithError(err).Error("Failed to create MCP server")
-		os.Exit(1)
+	if mcpServer != nil {
+		go func() {
+			logger.WithField("endpoint", mcpServer.Endpoint()).Info("Starting embedded MCP server")
+			err := mcpServer.Start()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.WithError(err).Error("MCP server failed to start")
+				os.Exit(1)
+			}
+		}()
+
+		// Tool manifest changes are announced to connected clients
+		go func() {
+			if err := mcpServer.WatchTools(watchCtx, logger); err != nil {
+				logger.WithError(err).Error("Tools watcher stopped")
+			}
+		}()
 	}
-	go func() {
-		err := mcpServer.Start()
-		if err != nil {
-			logger.WithError(err).Error("MCP server failed to start")
-			os.Exit(1)
-		}
-	}()
 
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
@@ -157,13 +307,41 @@ func main() {
 
 	logger.Info("Shutting down server...")
 
+	// Fail readiness and refuse new runs so load balancers move traffic elsewhere
+	checker.SetShuttingDown()
+	tracker.StopAccepting()
+	current := watcher.Current()
+	if delay := current.ShutdownDrainDelay; delay > 0 {
+		logger.WithField("delay", delay).Info("Readiness failing, waiting for load balancers to drain")
+		time.Sleep(delay)
+	}
+
+	// Let in-flight runs finish, aborting stragglers with a server_shutdown RUN_ERROR.
+	// The MCP server must outlive them, so it only stops afterwards.
+	logger.WithFields(logrus.Fields{
+		"active_runs":  tracker.Active(),
+		"grace_period": current.ShutdownGracePeriod,
+	}).Info("Draining in-flight runs")
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), current.ShutdownGracePeriod+shutdownTimeout)
+	drained, aborted := tracker.Drain(drainCtx, current.ShutdownGracePeriod)
+	cancelDrain()
+	logger.WithFields(logrus.Fields{
+		"drained": drained,
+		"aborted": aborted,
+	}).Info("In-flight runs drained")
+
 	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
-	err = mcpServer.Shutdown(ctx)
-	if err != nil {
-		logger.WithError(err).Error("MCP server shutdown error")
+	// Close the agent's MCP connections first, their notification streams would
+	// otherwise hold the MCP server's shutdown open
+	adapters.Close()
+
+	if mcpServer != nil {
+		if err := mcpServer.Shutdown(ctx); err != nil {
+			logger.WithError(err).Error("MCP server shutdown error")
+		}
 	}
 
 	if err = app.ShutdownWithContext(ctx); err != nil {
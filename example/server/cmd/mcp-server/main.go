@@ -0,0 +1,132 @@
+package main
+
+// Standalone MCP server exposing the same tool set as the agent server's
+// embedded one, for use by any MCP host.
+//
+//	mcp-server                           # stdio, for hosts that spawn the server
+//	mcp-server -transport http -port 3217
+//	mcp-server -transport sse -port 3217
+//	mcp-server -tools-dir ./tools          # add tools declared in manifests, reloaded on change
+//	mcp-server -resources-dir ./docs       # expose docs as docs://<path> resources
+//	mcp-server -files-root ./docs          # serve read_file and list_files confined to ./docs
+//	mcp-server -fetch-allowed-hosts docs.ag-ui.com,*.github.com
+//	mcp-server -code-execution             # serve run_code, programs run in a WebAssembly sandbox
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/mcp"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	var (
+		transportName = flag.String("transport", string(mcp.TransportStdio), "Transport to serve (stdio, sse, http)")
+		port          = flag.Int("port", mcp.DefaultPort, "Port for the sse and http transports (1-65535)")
+		toolsDir      = flag.String("tools-dir", "", "Directory of YAML/JSON tool manifests to serve")
+		promptsDir    = flag.String("prompts-dir", "", "Directory of markdown prompt templates to serve")
+		resourcesDir  = flag.String("resources-dir", "", "Directory of docs to expose as resources")
+		filesRoot     = flag.String("files-root", "", "Directory the read_file and list_files tools are confined to")
+		fetchHosts    = flag.String("fetch-allowed-hosts", "", "Comma separated hosts the fetch_url tool may contact")
+		codeExecution = flag.Bool("code-execution", false, "Serve the run_code tool, which runs programs in a WebAssembly sandbox")
+		codeMemory    = flag.Int("code-memory-limit", mcp.DefaultCodeMemoryLimit>>20, "Memory in MiB a program run by run_code may use")
+		codeTimeout   = flag.Duration("code-timeout", mcp.DefaultPackTimeout, "How long a program run by run_code may run")
+		logLevel      = flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+	)
+	flag.Parse()
+
+	transport, err := mcp.ParseTransport(*transportName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid transport: %v\n", err)
+		os.Exit(1)
+	}
+	if *port < 1 || *port > 65535 {
+		fmt.Fprintf(os.Stderr, "Port must be between 1 and 65535, got %d\n", *port)
+		os.Exit(1)
+	}
+
+	// Logs always go to stderr, stdout carries the protocol for stdio
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	if level, err := logrus.ParseLevel(*logLevel); err == nil {
+		logger.SetLevel(level)
+	}
+
+	var allowedHosts []string
+	for _, host := range strings.Split(*fetchHosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			allowedHosts = append(allowedHosts, host)
+		}
+	}
+	pack, err := mcp.ToolPack(mcp.PackOptions{
+		FilesRoot:    *filesRoot,
+		AllowedHosts: allowedHosts,
+		Limits:       map[string]mcp.ToolLimits{mcp.ToolRunCode: {Timeout: *codeTimeout}},
+		Code:         mcp.CodeOptions{Enabled: *codeExecution, MemoryLimit: *codeMemory << 20},
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to create built-in tools")
+		os.Exit(1)
+	}
+
+	server, err := mcp.NewServer(*port,
+		mcp.WithTransport(transport),
+		mcp.WithTools(pack...),
+		mcp.WithToolsDir(*toolsDir),
+		mcp.WithPromptsDir(*promptsDir),
+		mcp.WithResourcesDir(*resourcesDir),
+	)
+	if err != nil {
+		logger.WithError(err).Error("Failed to create MCP server")
+		os.Exit(1)
+	}
+
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go func() {
+		if err := server.WatchTools(watchCtx, logger); err != nil {
+			logger.WithError(err).Error("Tools watcher stopped")
+		}
+	}()
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.WithFields(logrus.Fields{
+			"transport": transport,
+			"endpoint":  server.Endpoint(),
+		}).Info("Starting MCP server")
+		errCh <- server.Start()
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-errCh:
+		// stdio returns once the host closes stdin
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.WithError(err).Error("MCP server stopped")
+			os.Exit(1)
+		}
+		return
+	case <-quit:
+	}
+
+	logger.Info("Shutting down MCP server...")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logger.WithError(err).Error("MCP server shutdown error")
+		os.Exit(1)
+	}
+	logger.Info("MCP server shutdown complete")
+}
@@ -4,15 +4,24 @@ import (
 	"bufio"
 	"context"
 	_ "embed"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
-	"github.com/ag-ui-protocol/ag-ui/sdks/community/go/pkg/core/events"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/cassette"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/config"
 	"github.com/mattsp1290/october-talks-2025/example/server/internal/mcp"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/rag"
 	"github.com/tmc/langchaingo/agents"
 	"github.com/tmc/langchaingo/chains"
+	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/anthropic"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/ag-ui-protocol/ag-ui/sdks/community/go/pkg/core/events"
 	"github.com/ag-ui-protocol/ag-ui/sdks/community/go/pkg/encoding/sse"
 	langchaingoTools "github.com/tmc/langchaingo/tools"
 )
@@ -22,50 +31,262 @@ import (
 //go:embed data/reminder.md
 var reminder string
 
-func CallLLM(ctx context.Context, input string, tools []langchaingoTools.Tool, returnChan chan<- string) error {
+// Options configures a single agent run
+type Options struct {
+	// Model is the Anthropic model name
+	Model string
+	// MCPServers are the MCP endpoints tools are loaded from
+	MCPServers []string
+	// Adapters holds connections shared between runs, when nil the run
+	// connects to MCPServers itself and disconnects when it ends
+	Adapters *mcp.Registry
+	// Prompt names an MCP prompt to run, rendered with PromptArgs by the first
+	// server that provides it. The run's input is appended to the prompt.
+	Prompt     string
+	PromptArgs map[string]string
+	// Resources are MCP resource URIs attached to the input as context
+	Resources []string
+	// Interactions receives the client's answers to MCP elicitations, when nil
+	// they are declined. Sampling requests always go to Model.
+	Interactions *Interactions
+	// ToolPolicy returns the config.Policy* value of a tool, when nil every
+	// tool runs without asking. Approvals are answered through Interactions.
+	ToolPolicy func(tool string) string
+	// Cassette records the run's model and MCP exchanges, or replays them
+	// without contacting the model or any MCP server
+	Cassette *cassette.Cassette
+	// SystemPrompt is put in front of the agent's instructions
+	SystemPrompt string
+	// Tools limits the run to the named tools, empty allows every tool
+	Tools []string
+	// MaxIterations bounds the agent's reasoning steps, 0 uses config.DefaultMaxIterations
+	MaxIterations int
+	// ToolParallelism bounds the tool calls of one step that run at the same
+	// time, 0 uses config.DefaultToolParallelism
+	ToolParallelism int
+	// ToolTimeout returns how long a tool may run, 0 keeps the MCP client's
+	// timeout. When nil every tool keeps it.
+	ToolTimeout func(tool string) time.Duration
+	// Temperature overrides the model's sampling temperature
+	Temperature *float64
+	// Attachments are the files of the thread, see CheckAttachment and Files
+	Attachments []Attachment
+	// History holds the thread's earlier messages, kept as HistoryPolicy says.
+	// Summaries are cached in HistoryCache under ThreadID, when nil every run
+	// summarizes again.
+	History       []HistoryMessage
+	HistoryPolicy HistoryPolicy
+	HistoryCache  *HistoryCache
+	ThreadID      string
+	// Documents are searched by the RetrievalToolName tool, which is only
+	// offered when the store has documents
+	Documents *rag.Store
+	// SubAgents are the agents the run's agent can delegate tasks to
+	SubAgents []SubAgent
+	// OutputSchema is the JSON Schema of an object the final answer is turned
+	// into, sent as a StructuredOutputEvent. Answers that do not match are
+	// asked for again up to OutputRetries times, 0 uses
+	// config.DefaultOutputRetries.
+	OutputSchema  map[string]any
+	OutputRetries int
+}
 
-	adapter, err := mcp.NewAdapter(fmt.Sprintf("http://127.0.0.1:%d/mcp", mcp.DefaultPort))
+func CallLLM(ctx context.Context, input string, opts Options, tools []langchaingoTools.Tool, returnChan chan<- string) error {
 
-	if err != nil {
-		return fmt.Errorf("new mcp adapter: %w", err)
+	var adapters []*mcp.Adapter
+	if !opts.Cassette.Replaying() {
+		registry := opts.Adapters
+		if registry == nil {
+			registry = mcp.NewRegistry()
+			defer registry.Close()
+		}
+
+		for _, endpoint := range opts.MCPServers {
+			adapter, err := registry.Adapter(ctx, endpoint)
+			if err != nil {
+				return fmt.Errorf("new mcp adapter: %w", err)
+			}
+			adapters = append(adapters, adapter)
+
+			mcpTools, err := adapter.Tools()
+			if err != nil {
+				return fmt.Errorf("append tools: %w", err)
+			}
+			tools = append(tools, mcpTools...)
+		}
 	}
+	// Sub-agents pick their tools from every tool, appending to the run's
+	// tools must not change them
+	tools = opts.Cassette.WrapTools(tools)
+	allTools := slices.Clip(tools)
+	tools = allowTools(allTools, opts.Tools)
 
-	_, err = adapter.Tools()
+	input, err := buildInput(ctx, input, opts, adapters)
 	if err != nil {
-		return fmt.Errorf("append tools: %w", err)
+		return err
 	}
+	input, parts := inlineAttachments(input, opts.Attachments)
 
-	llm, err := anthropic.New(anthropic.WithModel("claude-3-haiku-20240307"))
-	if err != nil {
-		return fmt.Errorf("failed to create LLM client: %w", err)
+	var base, llm llms.Model
+	if !opts.Cassette.Replaying() {
+		if base, err = anthropic.New(anthropic.WithModel(opts.Model)); err != nil {
+			return fmt.Errorf("failed to create LLM client: %w", err)
+		}
+		llm = withAttachments(base, opts.Model, parts)
+	}
+	llm = opts.Cassette.Model(llm)
+	if opts.Temperature != nil {
+		llm = withTemperature(llm, *opts.Temperature)
 	}
 
-	agent := agents.NewOneShotAgent(llm,
-		tools,
-		agents.WithMaxIterations(50))
+	// The history is summarized without the attachments, which belong to the input
+	reserved := CountTokens(opts.Model, opts.SystemPrompt+reminder)
+	if input, err = withHistory(ctx, opts.Cassette.Model(base), input, reserved, opts); err != nil {
+		return err
+	}
 
-	executor := agents.NewExecutor(agent, agents.WithCallbacksHandler(NewHandler(returnChan)))
+	parallelism := opts.ToolParallelism
+	if parallelism == 0 {
+		parallelism = config.DefaultToolParallelism
+	}
+	// The model calls of sub-agents running side by side would be recorded
+	// in an order replays cannot follow
+	if opts.Cassette != nil && len(opts.SubAgents) > 0 {
+		parallelism = 1
+	}
+
+	handler := NewHandler(returnChan)
+	tools = withRetrieval(tools, opts.Documents, opts.Tools, handler)
+	for _, agent := range opts.SubAgents {
+		tool, err := newSubAgentTool(agent, allTools, opts, parallelism, handler)
+		if err != nil {
+			return err
+		}
+		tools = append(tools, tool)
+	}
+
+	executor := newAgentExecutor(llm, gateTools(tools, opts, handler), opts.SystemPrompt, opts.MaxIterations, parallelism, handler)
+	ctx = mcp.WithToolResultHandler(ctx, handler.HandleToolResult)
+	ctx = mcp.WithProgressHandler(ctx, handler.HandleToolProgress)
+	if opts.ToolTimeout != nil {
+		ctx = mcp.WithToolTimeouts(ctx, opts.ToolTimeout)
+	}
+	ctx, closeCalls := mcp.WithCallHandlers(ctx, callHandlers(llm, opts, returnChan))
+	defer closeCalls()
 
 	inputMap := make(map[string]any)
 	inputMap["input"] = input + "\n" + reminder
+	if len(opts.OutputSchema) > 0 {
+		outputSchema, err := json.MarshalIndent(opts.OutputSchema, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode output schema: %w", err)
+		}
+		inputMap["input"] = input + "\n\n" + outputHint + string(outputSchema) + "\n" + reminder
+	}
 
-	result, err := chains.Call(ctx, executor, inputMap)
+	// The final answer is sent by the handler's HandleAgentFinish
+	outputs, err := chains.Call(ctx, executor, inputMap)
 	if err != nil {
 		return fmt.Errorf("run chain: %w", err)
 	}
-	output := result["output"].(string)
-
-	// Create a proper event for the final output
-	messageID := events.GenerateMessageID()
-	finalMessage := events.NewTextMessageContentEvent(messageID, output)
-	if jsonData, err := finalMessage.ToJSON(); err == nil {
-		returnChan <- string(jsonData)
+	if len(opts.OutputSchema) == 0 {
+		return nil
 	}
 
+	// The provider only forces tool calls through the Messages API
+	var structurer llms.Model
+	if !opts.Cassette.Replaying() {
+		structurer = &messagesModel{model: opts.Model}
+	}
+	structurer = opts.Cassette.Model(structurer)
+	if opts.Temperature != nil {
+		structurer = withTemperature(structurer, *opts.Temperature)
+	}
+	retries := opts.OutputRetries
+	if retries == 0 {
+		retries = config.DefaultOutputRetries
+	}
+	answer, _ := outputs["output"].(string)
+	value, err := structureAnswer(ctx, structurer, strings.TrimSpace(answer), opts.OutputSchema, retries)
+	if err != nil {
+		return err
+	}
+	emit(returnChan, events.NewCustomEvent(StructuredOutputEvent, events.WithValue(value)))
 	return nil
 }
 
-func ProcessInput(ctx context.Context, w *bufio.Writer, sseWriter *sse.SSEWriter, input string) error {
+// newAgentExecutor returns the executor of an agent using llm and tools,
+// maxIterations 0 uses config.DefaultMaxIterations
+func newAgentExecutor(llm llms.Model, tools []langchaingoTools.Tool, systemPrompt string, maxIterations, parallelism int, handler *Handler) *executor {
+	if maxIterations == 0 {
+		maxIterations = config.DefaultMaxIterations
+	}
+	agentOpts := []agents.Option{agents.WithPromptFormatInstructions(formatInstructions)}
+	if systemPrompt != "" {
+		agentOpts = append(agentOpts, agents.WithPromptPrefix(promptPrefix(systemPrompt)))
+	}
+	agent := toolCallsAgent{agents.NewOneShotAgent(llm, tools, agentOpts...)}
+	return newExecutor(agent, handler, maxIterations, parallelism)
+}
+
+// withRetrieval adds the RetrievalToolName tool reporting to handler when the
+// store has documents and allowed permits it
+func withRetrieval(tools []langchaingoTools.Tool, store *rag.Store, allowed []string, handler *Handler) []langchaingoTools.Tool {
+	if store.Len() == 0 {
+		return tools
+	}
+	return append(tools, allowTools([]langchaingoTools.Tool{&retrievalTool{store: store, handler: handler}}, allowed)...)
+}
+
+// buildInput expands the requested prompt and attaches the requested resources
+func buildInput(ctx context.Context, input string, opts Options, adapters []*mcp.Adapter) (string, error) {
+	var sections []string
+
+	if opts.Prompt != "" {
+		args, _ := json.Marshal(opts.PromptArgs)
+		prompt, err := opts.Cassette.Exchange(cassette.KindPrompt, opts.Prompt, string(args), func() (string, error) {
+			return firstOf(adapters, func(a *mcp.Adapter) (string, error) {
+				return a.Prompt(ctx, opts.Prompt, opts.PromptArgs)
+			})
+		})
+		if err != nil {
+			return "", fmt.Errorf("prompt %s: %w", opts.Prompt, err)
+		}
+		sections = append(sections, prompt)
+	}
+
+	if input != "" {
+		sections = append(sections, input)
+	}
+
+	for _, uri := range opts.Resources {
+		text, err := opts.Cassette.Exchange(cassette.KindResource, uri, "", func() (string, error) {
+			return firstOf(adapters, func(a *mcp.Adapter) (string, error) {
+				return a.Resource(ctx, uri)
+			})
+		})
+		if err != nil {
+			return "", fmt.Errorf("attach resource %s: %w", uri, err)
+		}
+		sections = append(sections, fmt.Sprintf("## Attached resource: %s\n\n%s", uri, text))
+	}
+
+	return strings.Join(sections, "\n\n"), nil
+}
+
+// firstOf returns the first successful result of fn across adapters
+func firstOf(adapters []*mcp.Adapter, fn func(*mcp.Adapter) (string, error)) (string, error) {
+	err := errors.New("no MCP servers configured")
+	for _, adapter := range adapters {
+		var result string
+		if result, err = fn(adapter); err == nil {
+			return result, nil
+		}
+	}
+	return "", err
+}
+
+func ProcessInput(ctx context.Context, w *bufio.Writer, sseWriter *sse.SSEWriter, input string, opts Options) error {
 	resultChan := make(chan string)
 	g, groupCtx := errgroup.WithContext(ctx)
 
@@ -80,16 +301,21 @@ func ProcessInput(ctx context.Context, w *bufio.Writer, sseWriter *sse.SSEWriter
 				// All messages from the handler should now be proper JSON events
 				// WriteBytes will format them as SSE frames with "data: " prefix
 				if err := sseWriter.WriteBytes(ctx, w, []byte(result)); err != nil {
+					for range resultChan {
+					}
 					return fmt.Errorf("failed to write event: %w", err)
 				}
 			case <-ctx.Done():
+				// Keep draining so the callback handler is never left blocked on a send
+				for range resultChan {
+				}
 				return ctx.Err()
 			}
 		}
 	})
 
 	g.Go(func() error {
-		callLLMErr := CallLLM(groupCtx, input, nil, resultChan)
+		callLLMErr := CallLLM(groupCtx, input, opts, nil, resultChan)
 		close(resultChan)
 		return callLLMErr
 	})
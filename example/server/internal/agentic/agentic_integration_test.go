@@ -2,21 +2,15 @@ package agentic
 
 import (
 	"context"
-	_ "embed"
 	"os"
 	"testing"
 
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/config"
 	"github.com/mattsp1290/october-talks-2025/example/server/internal/mcp"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
 )
 
-//go:embed data/client_prompt.md
-var client_prompt string
-
-//go:embed data/languages_prompt.md
-var languages_prompt string
-
 func TestToolCalls(t *testing.T) {
 	if os.Getenv("RUN_INTEGRATION_TESTS") != "true" {
 		t.Skip("Skipping integration test")
@@ -52,7 +46,11 @@ func TestToolCalls(t *testing.T) {
 	})
 
 	g.Go(func() error {
-		callErr := CallLLM(groupCtx, languages_prompt, nil, resultChan)
+		callErr := CallLLM(groupCtx, "", Options{
+			Model:      config.DefaultModel,
+			MCPServers: []string{mcpServer.Endpoint()},
+			Prompt:     "languages_prompt",
+		}, nil, resultChan)
 		close(resultChan)
 		return callErr
 	})
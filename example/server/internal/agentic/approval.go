@@ -0,0 +1,135 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ag-ui-protocol/ag-ui/sdks/community/go/pkg/core/events"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/config"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/mcp"
+	langchaingoTools "github.com/tmc/langchaingo/tools"
+)
+
+// ApprovalRequestEvent names the CUSTOM event sent after TOOL_CALL_ARGS when a
+// tool's policy is confirm. Its value is
+//
+//	{"toolCallId": "...", "toolName": "...", "arguments": {...}}
+//
+// and the run waits until a follow-up request answers it, either with a tool
+// message for the call whose content is {"approved": true, "reason": "..."},
+// or with forwardedProps:
+//
+//	{"approval": {"toolCallId": "...", "approved": true, "reason": "..."}}
+const ApprovalRequestEvent = "approval_request"
+
+// approvalTimeout bounds how long a run waits for an approval, unanswered
+// calls are rejected
+const approvalTimeout = 5 * time.Minute
+
+// Approval is the user's answer to an ApprovalRequestEvent
+type Approval struct {
+	ToolCallID string `json:"toolCallId"`
+	Approved   bool   `json:"approved"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// Approve delivers approval to the run waiting for it and reports whether one was
+func (i *Interactions) Approve(approval Approval) bool {
+	answer, err := json.Marshal(approval)
+	if err != nil {
+		return false
+	}
+	return i.Resolve(approval.ToolCallID, string(answer))
+}
+
+// gateTools wraps the tools whose policy is confirm so their calls are
+// approved by the user, and leaves out the tools whose policy is deny so the
+// agent never sees them
+func gateTools(tools []langchaingoTools.Tool, opts Options, handler *Handler) []langchaingoTools.Tool {
+	if opts.ToolPolicy == nil {
+		return tools
+	}
+
+	gated := make([]langchaingoTools.Tool, 0, len(tools))
+	for _, tool := range tools {
+		switch opts.ToolPolicy(tool.Name()) {
+		case config.PolicyConfirm:
+			gated = append(gated, &gatedTool{Tool: tool, handler: handler, interactions: opts.Interactions})
+		case config.PolicyDeny:
+		default:
+			gated = append(gated, tool)
+		}
+	}
+	return gated
+}
+
+// gatedTool asks the user to approve each call before calling the tool
+type gatedTool struct {
+	langchaingoTools.Tool
+	handler      *Handler
+	interactions *Interactions
+}
+
+func (t *gatedTool) Call(ctx context.Context, input string) (string, error) {
+	if t.interactions == nil {
+		return t.handler.skipTool(ctx, t.Name(), "this tool needs the user's approval, which cannot be asked for in this run"), nil
+	}
+
+	approval, err := t.handler.requestApproval(ctx, t.interactions, t.Name(), input)
+	if err != nil {
+		return "", err
+	}
+	if !approval.Approved {
+		message := "the user rejected the call"
+		if approval.Reason != "" {
+			message += ": " + approval.Reason
+		}
+		return t.handler.skipTool(ctx, t.Name(), message), nil
+	}
+	return t.Tool.Call(ctx, input)
+}
+
+// requestApproval asks the client to approve the tool call announced by
+// HandleAgentAction and waits for the answer. Calls that are not answered
+// within approvalTimeout are rejected, an error means the run was canceled.
+func (h *Handler) requestApproval(ctx context.Context, interactions *Interactions, tool, input string) (Approval, error) {
+	toolCallID := h.callID(ctx)
+	answers := interactions.register(toolCallID)
+	defer interactions.remove(toolCallID)
+
+	var arguments any = input
+	if json.Valid([]byte(input)) {
+		arguments = json.RawMessage(input)
+	}
+	emit(h.returnChan, events.NewCustomEvent(ApprovalRequestEvent, events.WithValue(map[string]any{
+		"toolCallId": toolCallID,
+		"toolName":   tool,
+		"arguments":  arguments,
+	})))
+
+	timer := time.NewTimer(approvalTimeout)
+	defer timer.Stop()
+
+	select {
+	case answer := <-answers:
+		var approval Approval
+		if err := json.Unmarshal([]byte(answer), &approval); err != nil {
+			return Approval{Reason: "unreadable answer"}, nil
+		}
+		return approval, nil
+	case <-timer.C:
+		return Approval{Reason: fmt.Sprintf("no answer within %s", approvalTimeout)}, nil
+	case <-ctx.Done():
+		return Approval{}, fmt.Errorf("wait for approval of %s: %w", toolCallID, ctx.Err())
+	}
+}
+
+// skipTool reports a tool call that was not run as a denied ToolError and
+// returns the observation for the agent
+func (h *Handler) skipTool(ctx context.Context, tool, message string) string {
+	result := (&mcp.ToolError{Code: mcp.ToolErrorDenied, Message: message}).Result()
+	h.HandleToolResult(ctx, tool, result)
+	return mcp.ResultText(result)
+}
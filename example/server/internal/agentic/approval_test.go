@@ -0,0 +1,78 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+	langchaingoTools "github.com/tmc/langchaingo/tools"
+)
+
+// echoTool returns its input
+type echoTool struct{}
+
+func (echoTool) Name() string        { return "echo" }
+func (echoTool) Description() string { return "Echo the input" }
+func (echoTool) Call(ctx context.Context, input string) (string, error) {
+	return input, nil
+}
+
+func TestGatedTool(t *testing.T) {
+	interactions := NewInteractions()
+	returnChan := make(chan string, 10)
+	handler := NewHandler(returnChan)
+
+	gate := func(policy string) *gatedTool {
+		tools := gateTools([]langchaingoTools.Tool{echoTool{}}, Options{
+			Interactions: interactions,
+			ToolPolicy:   func(string) string { return policy },
+		}, handler)
+		require.IsType(t, &gatedTool{}, tools[0])
+		return tools[0].(*gatedTool)
+	}
+
+	// call announces the tool call and answers its approval request with approval
+	call := func(tool *gatedTool, approval *Approval) string {
+		handler.HandleAgentAction(context.Background(), schema.AgentAction{Tool: "echo", ToolInput: `{"text":"hi"}`})
+		<-returnChan
+		<-returnChan
+		if approval != nil {
+			go func() {
+				var request struct {
+					Name  string `json:"name"`
+					Value struct {
+						ToolCallID string          `json:"toolCallId"`
+						ToolName   string          `json:"toolName"`
+						Arguments  json.RawMessage `json:"arguments"`
+					} `json:"value"`
+				}
+				assert.NoError(t, json.Unmarshal([]byte(<-returnChan), &request))
+				assert.Equal(t, ApprovalRequestEvent, request.Name)
+				assert.Equal(t, "echo", request.Value.ToolName)
+				assert.JSONEq(t, `{"text":"hi"}`, string(request.Value.Arguments))
+				approval.ToolCallID = request.Value.ToolCallID
+				assert.True(t, interactions.Approve(*approval))
+			}()
+		}
+		output, err := tool.Call(context.Background(), `{"text":"hi"}`)
+		require.NoError(t, err)
+		return output
+	}
+
+	assert.Equal(t, `{"text":"hi"}`, call(gate(config.PolicyConfirm), &Approval{Approved: true}))
+
+	output := call(gate(config.PolicyConfirm), &Approval{Reason: "not now"})
+	assert.Contains(t, output, "denied: the user rejected the call: not now")
+	assert.Contains(t, <-returnChan, "TOOL_CALL_END")
+	assert.Contains(t, <-returnChan, `\"code\":\"denied\"`)
+
+	tools := gateTools([]langchaingoTools.Tool{echoTool{}}, Options{ToolPolicy: func(string) string { return config.PolicyAuto }}, handler)
+	assert.Equal(t, []langchaingoTools.Tool{echoTool{}}, tools)
+
+	tools = gateTools([]langchaingoTools.Tool{echoTool{}}, Options{ToolPolicy: func(string) string { return config.PolicyDeny }}, handler)
+	assert.Empty(t, tools)
+}
@@ -0,0 +1,303 @@
+package agentic
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// defaultMaxTokens bounds completions of prompts with attachments when the
+// call does not set a limit
+const defaultMaxTokens = 4096
+
+// imageTypes are the image formats the model reads
+var imageTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// Attachment is a file sent with the user's message, either its content or a URL
+type Attachment struct {
+	// ID names the file within its thread, see Files
+	ID       string
+	Name     string
+	MimeType string
+	Data     []byte
+	URL      string
+}
+
+// CheckAttachment returns why a file of mimeType cannot be given to the model,
+// nil when it can. Images and PDFs are sent to the model as they are, text
+// files are added to the input. Only images can be referenced by URL.
+func CheckAttachment(mimeType string, byURL bool) error {
+	switch {
+	case slices.Contains(imageTypes, mimeType):
+		return nil
+	case byURL && (isDocument(mimeType) || isText(mimeType)):
+		return fmt.Errorf("%s files must be sent as data, only images can be sent by url", mimeType)
+	case isDocument(mimeType), isText(mimeType):
+		return nil
+	default:
+		return fmt.Errorf("unsupported attachment type %s, send images, PDFs or text", mimeType)
+	}
+}
+
+// isDocument reports whether files of mimeType are sent to the model as content blocks
+func isDocument(mimeType string) bool {
+	return slices.Contains(imageTypes, mimeType) || mimeType == "application/pdf"
+}
+
+func isText(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "text/") || mimeType == "application/json"
+}
+
+// inlineAttachments appends the text attachments to input and returns the
+// others as content parts for the model
+func inlineAttachments(input string, attachments []Attachment) (string, []llms.ContentPart) {
+	var parts []llms.ContentPart
+	sections := []string{input}
+	for _, a := range attachments {
+		switch {
+		case isText(a.MimeType):
+			sections = append(sections, fmt.Sprintf("## Attached file: %s\n\n%s", a.Name, a.Data))
+		case a.URL != "":
+			parts = append(parts, llms.ImageURLContent{URL: a.URL})
+		default:
+			parts = append(parts, llms.BinaryContent{MIMEType: a.MimeType, Data: a.Data})
+		}
+	}
+	return strings.Join(sections, "\n\n"), parts
+}
+
+// withAttachments returns llm sending parts with every prompt. The anthropic
+// provider only sends text, so prompts with attachments go to the Messages API
+// directly, and their completion is streamed in one chunk.
+func withAttachments(llm llms.Model, model string, parts []llms.ContentPart) llms.Model {
+	if len(parts) == 0 {
+		return llm
+	}
+	return &attachmentModel{Model: llm, model: model, parts: parts}
+}
+
+type attachmentModel struct {
+	llms.Model
+	model string
+	parts []llms.ContentPart
+}
+
+func (m *attachmentModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	var opts llms.CallOptions
+	for _, option := range options {
+		option(&opts)
+	}
+
+	// The attachments go with the first user message, the agent sends its whole
+	// scratchpad as one prompt
+	messages = append([]llms.MessageContent{}, messages...)
+	for i, message := range messages {
+		if message.Role == llms.ChatMessageTypeHuman {
+			messages[i].Parts = append(append([]llms.ContentPart{}, m.parts...), message.Parts...)
+			break
+		}
+	}
+
+	resp, err := createMessage(ctx, m.model, messages, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.StreamingFunc != nil {
+		if err := opts.StreamingFunc(ctx, []byte(resp.Choices[0].Content)); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (m *attachmentModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// anthropicMessage is a message of a Messages API request
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []map[string]any `json:"content"`
+}
+
+// createMessage sends messages to the Anthropic Messages API and returns the
+// reply as one choice holding its text and tool calls
+func createMessage(ctx context.Context, model string, messages []llms.MessageContent, opts llms.CallOptions) (*llms.ContentResponse, error) {
+	baseURL, apiKey, err := anthropicAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]any{"model": model, "max_tokens": defaultMaxTokens}
+	if opts.MaxTokens > 0 {
+		body["max_tokens"] = opts.MaxTokens
+	}
+	if opts.Temperature > 0 {
+		body["temperature"] = opts.Temperature
+	}
+	if len(opts.StopWords) > 0 {
+		body["stop_sequences"] = opts.StopWords
+	}
+	if len(opts.Tools) > 0 {
+		tools := make([]map[string]any, 0, len(opts.Tools))
+		for _, tool := range opts.Tools {
+			if tool.Function == nil {
+				continue
+			}
+			tools = append(tools, map[string]any{
+				"name":         tool.Function.Name,
+				"description":  tool.Function.Description,
+				"input_schema": tool.Function.Parameters,
+			})
+		}
+		body["tools"] = tools
+	}
+	if choice := toolChoice(opts.ToolChoice); choice != nil {
+		body["tool_choice"] = choice
+	}
+
+	var system []string
+	var chat []anthropicMessage
+	for _, message := range messages {
+		blocks, err := contentBlocks(message.Parts)
+		if err != nil {
+			return nil, err
+		}
+		switch message.Role {
+		case llms.ChatMessageTypeSystem:
+			for _, block := range blocks {
+				if text, ok := block["text"].(string); ok {
+					system = append(system, text)
+				}
+			}
+		case llms.ChatMessageTypeHuman:
+			chat = append(chat, anthropicMessage{Role: "user", Content: blocks})
+		case llms.ChatMessageTypeAI:
+			chat = append(chat, anthropicMessage{Role: "assistant", Content: blocks})
+		default:
+			return nil, fmt.Errorf("unsupported message role %s with attachments", message.Role)
+		}
+	}
+	if len(system) > 0 {
+		body["system"] = strings.Join(system, "\n\n")
+	}
+	body["messages"] = chat
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/messages", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("reach anthropic: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+		Error *struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("anthropic returned %s: %w", resp.Status, err)
+	}
+	if reply.Error != nil {
+		return nil, fmt.Errorf("anthropic returned %s: %s: %s", resp.Status, reply.Error.Type, reply.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("anthropic returned %s", resp.Status)
+	}
+
+	var text strings.Builder
+	choice := &llms.ContentChoice{}
+	for _, block := range reply.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			choice.ToolCalls = append(choice.ToolCalls, llms.ToolCall{
+				ID:           block.ID,
+				Type:         "function",
+				FunctionCall: &llms.FunctionCall{Name: block.Name, Arguments: string(block.Input)},
+			})
+		}
+	}
+	choice.Content = text.String()
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{choice}}, nil
+}
+
+// toolChoice converts the tool choice of a call to its Messages API form,
+// nil leaves the choice to the model
+func toolChoice(choice any) map[string]any {
+	switch c := choice.(type) {
+	case llms.ToolChoice:
+		if c.Function != nil {
+			return map[string]any{"type": "tool", "name": c.Function.Name}
+		}
+		return toolChoice(c.Type)
+	case string:
+		switch c {
+		case "auto", "any", "none":
+			return map[string]any{"type": c}
+		case "required":
+			return map[string]any{"type": "any"}
+		}
+	}
+	return nil
+}
+
+// contentBlocks converts parts to Messages API content blocks
+func contentBlocks(parts []llms.ContentPart) ([]map[string]any, error) {
+	blocks := make([]map[string]any, 0, len(parts))
+	for _, part := range parts {
+		switch p := part.(type) {
+		case llms.TextContent:
+			blocks = append(blocks, map[string]any{"type": "text", "text": p.Text})
+		case llms.BinaryContent:
+			blocks = append(blocks, map[string]any{"type": blockType(p.MIMEType), "source": map[string]any{
+				"type":       "base64",
+				"media_type": p.MIMEType,
+				"data":       base64.StdEncoding.EncodeToString(p.Data),
+			}})
+		case llms.ImageURLContent:
+			blocks = append(blocks, map[string]any{"type": "image", "source": map[string]any{
+				"type": "url",
+				"url":  p.URL,
+			}})
+		default:
+			return nil, fmt.Errorf("unsupported content part %T", part)
+		}
+	}
+	return blocks, nil
+}
+
+// blockType is the content block type of a file
+func blockType(mimeType string) string {
+	if mimeType == "application/pdf" {
+		return "document"
+	}
+	return "image"
+}
@@ -0,0 +1,57 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestAttachmentModel(t *testing.T) {
+	var request map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/messages", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content": [{"type": "text", "text": "Final Answer: a cat"}]}`))
+	}))
+	defer server.Close()
+	t.Setenv(anthropicAPIKeyEnv, "test-key")
+	t.Setenv(anthropicBaseURLEnv, server.URL)
+
+	input, parts := inlineAttachments("What is this?", []Attachment{
+		{Name: "cat.png", MimeType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}},
+		{Name: "notes.txt", MimeType: "text/plain", Data: []byte("a note")},
+		{MimeType: "image/jpeg", URL: "https://example.com/dog.jpg"},
+	})
+	assert.Equal(t, "What is this?\n\n## Attached file: notes.txt\n\na note", input)
+
+	var streamed string
+	llm := withAttachments(nil, "claude-test", parts)
+	text, err := llms.GenerateFromSinglePrompt(context.Background(), llm, input,
+		llms.WithStopWords([]string{"\nObservation:"}),
+		llms.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+			streamed += string(chunk)
+			return nil
+		}))
+	require.NoError(t, err)
+	assert.Equal(t, "Final Answer: a cat", text)
+	assert.Equal(t, text, streamed)
+
+	assert.Equal(t, "claude-test", request["model"])
+	assert.Equal(t, []any{"\nObservation:"}, request["stop_sequences"])
+	messages := request["messages"].([]any)
+	require.Len(t, messages, 1)
+	content := messages[0].(map[string]any)["content"]
+	assert.Equal(t, []any{
+		map[string]any{"type": "image", "source": map[string]any{"type": "base64", "media_type": "image/png", "data": "iVBORw=="}},
+		map[string]any{"type": "image", "source": map[string]any{"type": "url", "url": "https://example.com/dog.jpg"}},
+		map[string]any{"type": "text", "text": input},
+	}, content)
+}
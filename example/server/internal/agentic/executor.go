@@ -0,0 +1,255 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ag-ui-protocol/ag-ui/sdks/community/go/pkg/core/events"
+	"github.com/tmc/langchaingo/agents"
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/chains"
+	"github.com/tmc/langchaingo/memory"
+	"github.com/tmc/langchaingo/schema"
+	langchaingoTools "github.com/tmc/langchaingo/tools"
+	"golang.org/x/sync/errgroup"
+)
+
+// formatInstructions is the one-shot agent's format, extended with calling
+// several tools in one turn
+const formatInstructions = `Use the following format:
+
+Question: the input question you must answer
+Thought: you should always think about what to do
+Action: the action to take, should be one of [ {{.tool_names}} ]
+Action Input: the input to the action
+Observation: the result of the action
+... (this Thought/Action/Action Input/Observation can repeat N times)
+Thought: I now know the final answer
+Final Answer: the final answer to the original input question
+
+When several actions do not depend on each other's results, write their Action and Action Input pairs one after the other before the Observation. They run at the same time.`
+
+const finalAnswerAction = "Final Answer:"
+
+// actionPattern matches an action's tool name, its input follows the match
+var actionPattern = regexp.MustCompile(`Action:[ \t]*(.+)\s*Action Input:[ \t]*`)
+
+// toolCallsAgent is the one-shot agent reading every Action and Action Input
+// pair of a reply, so the model can call independent tools in one turn
+type toolCallsAgent struct {
+	*agents.OneShotZeroAgent
+}
+
+// Plan is OneShotZeroAgent.Plan with parseActions and scratchpad
+func (a toolCallsAgent) Plan(ctx context.Context, steps []schema.AgentStep, inputs map[string]string) ([]schema.AgentAction, *schema.AgentFinish, error) {
+	fullInputs := make(map[string]any, len(inputs)+2)
+	for key, value := range inputs {
+		fullInputs[key] = value
+	}
+	fullInputs["agent_scratchpad"] = scratchpad(steps)
+	fullInputs["today"] = time.Now().Format("January 02, 2006")
+
+	var stream func(ctx context.Context, chunk []byte) error
+	if a.CallbacksHandler != nil {
+		stream = func(ctx context.Context, chunk []byte) error {
+			a.CallbacksHandler.HandleStreamingFunc(ctx, chunk)
+			return nil
+		}
+	}
+
+	output, err := chains.Predict(ctx, a.Chain, fullInputs,
+		chains.WithStopWords([]string{"\nObservation:", "\n\tObservation:"}),
+		chains.WithStreamingFunc(stream),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseActions(output, a.OutputKey)
+}
+
+// parseActions reads the final answer or the actions of a reply
+func parseActions(output, outputKey string) ([]schema.AgentAction, *schema.AgentFinish, error) {
+	if strings.Contains(output, finalAnswerAction) {
+		splits := strings.Split(output, finalAnswerAction)
+		return nil, &schema.AgentFinish{
+			ReturnValues: map[string]any{outputKey: splits[len(splits)-1]},
+			Log:          output,
+		}, nil
+	}
+
+	matches := actionPattern.FindAllStringSubmatchIndex(output, -1)
+	if len(matches) == 0 {
+		return nil, nil, fmt.Errorf("%w: %s", agents.ErrUnableToParseOutput, output)
+	}
+	actions := make([]schema.AgentAction, 0, len(matches))
+	for i, match := range matches {
+		end := len(output)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		actions = append(actions, schema.AgentAction{
+			Tool:      strings.TrimSpace(output[match[2]:match[3]]),
+			ToolInput: actionInput(output[match[1]:end]),
+			Log:       output,
+		})
+	}
+	return actions, nil, nil
+}
+
+// actionInput trims what the model wrote after an action's input, which is
+// the JSON value it starts with or otherwise runs up to the next thought
+func actionInput(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[") {
+		decoder := json.NewDecoder(strings.NewReader(text))
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err == nil {
+			return text[:decoder.InputOffset()]
+		}
+	}
+	text, _, _ = strings.Cut(text, "\nThought:")
+	return strings.TrimSpace(text)
+}
+
+// scratchpad renders the steps taken so far. The steps of one reply share its
+// log, which is written once and followed by an observation per tool.
+func scratchpad(steps []schema.AgentStep) string {
+	var pad strings.Builder
+	for i := 0; i < len(steps); {
+		n := 1
+		for i+n < len(steps) && steps[i+n].Action.Log == steps[i].Action.Log {
+			n++
+		}
+		pad.WriteString("\n" + steps[i].Action.Log)
+		for _, step := range steps[i : i+n] {
+			if n == 1 {
+				pad.WriteString("\nObservation: " + step.Observation + "\n")
+			} else {
+				pad.WriteString("\nObservation: [" + step.Action.Tool + "] " + step.Observation + "\n")
+			}
+		}
+		i += n
+	}
+	return pad.String()
+}
+
+// executor runs an agent like agents.Executor, except that the tool calls of
+// one turn run concurrently, at most parallelism at a time. Each call's
+// context carries its tool call ID, see withToolCallID.
+type executor struct {
+	agent         agents.Agent
+	handler       *Handler
+	memory        schema.Memory
+	maxIterations int
+	parallelism   int
+}
+
+var (
+	_ chains.Chain           = (*executor)(nil)
+	_ callbacks.HandlerHaver = (*executor)(nil)
+)
+
+func newExecutor(agent agents.Agent, handler *Handler, maxIterations, parallelism int) *executor {
+	return &executor{
+		agent:         agent,
+		handler:       handler,
+		memory:        memory.NewSimple(),
+		maxIterations: maxIterations,
+		parallelism:   max(parallelism, 1),
+	}
+}
+
+func (e *executor) Call(ctx context.Context, inputValues map[string]any, _ ...chains.ChainCallOption) (map[string]any, error) {
+	inputs := make(map[string]string, len(inputValues))
+	for key, value := range inputValues {
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", agents.ErrExecutorInputNotString, key)
+		}
+		inputs[key] = text
+	}
+	tools := make(map[string]langchaingoTools.Tool)
+	for _, tool := range e.agent.GetTools() {
+		tools[strings.ToUpper(tool.Name())] = tool
+	}
+
+	var steps []schema.AgentStep
+	for range e.maxIterations {
+		actions, finish, err := e.agent.Plan(ctx, steps, inputs)
+		if err != nil {
+			return nil, err
+		}
+		if finish != nil {
+			e.handler.HandleAgentFinish(ctx, *finish)
+			return finish.ReturnValues, nil
+		}
+		if len(actions) == 0 {
+			return nil, agents.ErrAgentNoReturn
+		}
+
+		taken, err := e.runActions(ctx, actions, tools)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, taken...)
+	}
+
+	e.handler.HandleAgentFinish(ctx, schema.AgentFinish{
+		ReturnValues: map[string]any{"output": agents.ErrNotFinished.Error()},
+	})
+	return map[string]any{}, agents.ErrNotFinished
+}
+
+// runActions announces every action, then runs them and returns their steps
+// in the order the model asked for them
+func (e *executor) runActions(ctx context.Context, actions []schema.AgentAction, tools map[string]langchaingoTools.Tool) ([]schema.AgentStep, error) {
+	g, groupCtx := errgroup.WithContext(ctx)
+	g.SetLimit(e.parallelism)
+
+	callCtxs := make([]context.Context, len(actions))
+	for i, action := range actions {
+		callCtxs[i] = withToolCallID(groupCtx, events.GenerateToolCallID())
+		e.handler.HandleAgentAction(callCtxs[i], action)
+	}
+
+	steps := make([]schema.AgentStep, len(actions))
+	for i, action := range actions {
+		g.Go(func() error {
+			steps[i] = schema.AgentStep{Action: action}
+			tool, ok := tools[strings.ToUpper(action.Tool)]
+			if !ok {
+				steps[i].Observation = fmt.Sprintf("%s is not a valid tool, try another one", action.Tool)
+				e.handler.finishToolCall(callCtxs[i], steps[i].Observation)
+				return nil
+			}
+
+			observation, err := tool.Call(callCtxs[i], action.ToolInput)
+			if err != nil {
+				return err
+			}
+			steps[i].Observation = observation
+			return nil
+		})
+	}
+	return steps, g.Wait()
+}
+
+func (e *executor) GetInputKeys() []string {
+	return e.agent.GetInputKeys()
+}
+
+func (e *executor) GetOutputKeys() []string {
+	return e.agent.GetOutputKeys()
+}
+
+func (e *executor) GetMemory() schema.Memory { //nolint:ireturn
+	return e.memory
+}
+
+func (e *executor) GetCallbackHandler() callbacks.Handler { //nolint:ireturn
+	return e.handler
+}
@@ -0,0 +1,147 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/agents"
+	"github.com/tmc/langchaingo/chains"
+	"github.com/tmc/langchaingo/llms"
+	langchaingoTools "github.com/tmc/langchaingo/tools"
+)
+
+func TestParseActions(t *testing.T) {
+	actions, finish, err := parseActions(`Thought: I need both
+Action: weather
+Action Input: {"city": "Paris"}
+Action: weather
+Action Input: {"city": "Oslo"} and then compare
+Action: echo
+Action Input: hello
+Thought: wait for them`, "output")
+	require.NoError(t, err)
+	assert.Nil(t, finish)
+	require.Len(t, actions, 3)
+	assert.Equal(t, "weather", actions[0].Tool)
+	assert.Equal(t, `{"city": "Paris"}`, actions[0].ToolInput)
+	assert.Equal(t, `{"city": "Oslo"}`, actions[1].ToolInput)
+	assert.Equal(t, "echo", actions[2].Tool)
+	assert.Equal(t, "hello", actions[2].ToolInput)
+
+	_, finish, err = parseActions("Thought: done\nFinal Answer: 42", "output")
+	require.NoError(t, err)
+	assert.Equal(t, " 42", finish.ReturnValues["output"])
+
+	_, _, err = parseActions("I am not sure", "output")
+	assert.ErrorIs(t, err, agents.ErrUnableToParseOutput)
+}
+
+// scriptModel answers with its replies in order and keeps the prompts
+type scriptModel struct {
+	replies []string
+	prompts []string
+}
+
+func (m *scriptModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.prompts = append(m.prompts, messages[0].Parts[0].(llms.TextContent).Text)
+	reply := m.replies[0]
+	m.replies = m.replies[1:]
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: reply}}}, nil
+}
+
+func (m *scriptModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// barrierTool returns once every call of the step started, reporting its progress
+type barrierTool struct {
+	handler *Handler
+	started *sync.WaitGroup
+}
+
+func (barrierTool) Name() string        { return "wait" }
+func (barrierTool) Description() string { return "Wait for the other calls" }
+func (b barrierTool) Call(ctx context.Context, input string) (string, error) {
+	b.started.Done()
+	b.started.Wait()
+	b.handler.HandleToolProgress(ctx, "wait", mcp.Progress{Progress: 1, Total: 2})
+	b.handler.HandleToolResult(ctx, "wait", mcpgo.NewToolResultText("waited "+input))
+	return "waited " + input, nil
+}
+
+func TestExecutor(t *testing.T) {
+	returnChan := make(chan string, 100)
+	handler := NewHandler(returnChan)
+	started := &sync.WaitGroup{}
+	started.Add(2)
+	llm := &scriptModel{replies: []string{
+		"Thought: both at once\nAction: wait\nAction Input: a\nAction: wait\nAction Input: b\nAction: sleep\nAction Input: c",
+		"Thought: I now know the final answer\nFinal Answer: done",
+	}}
+	tools := []langchaingoTools.Tool{barrierTool{handler: handler, started: started}}
+	agent := toolCallsAgent{agents.NewOneShotAgent(llm, tools, agents.WithPromptFormatInstructions(formatInstructions))}
+
+	// The calls would deadlock if they ran one after the other
+	done := make(chan error)
+	go func() {
+		_, err := chains.Call(context.Background(), newExecutor(agent, handler, 3, 3), map[string]any{"input": "go"})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("tool calls did not run concurrently")
+	}
+	close(returnChan)
+
+	assert.Contains(t, llm.prompts[1], "Action Input: c\nObservation: [wait] waited a\n\nObservation: [wait] waited b\n\nObservation: [sleep] sleep is not a valid tool, try another one\n")
+
+	var (
+		calls    = map[string]string{}
+		ended    = map[string]bool{}
+		progress int
+	)
+	for data := range returnChan {
+		var event struct {
+			Type         string `json:"type"`
+			Name         string `json:"name"`
+			ToolCallID   string `json:"toolCallId"`
+			ToolCallName string `json:"toolCallName"`
+			Value        struct {
+				ToolCallID string `json:"toolCallId"`
+			} `json:"value"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(data), &event))
+		switch event.Type {
+		case "TOOL_CALL_START":
+			calls[event.ToolCallID] = event.ToolCallName
+		case "TOOL_CALL_END":
+			ended[event.ToolCallID] = true
+		case "CUSTOM":
+			assert.Equal(t, ToolProgressEvent, event.Name)
+			assert.Equal(t, "wait", calls[event.Value.ToolCallID])
+			progress++
+		}
+	}
+	assert.Len(t, calls, 3)
+	assert.Len(t, ended, 3)
+	assert.Equal(t, 2, progress)
+}
+
+func TestExecutorNotFinished(t *testing.T) {
+	llm := &scriptModel{replies: []string{"Action: sleep\nAction Input: 1", "Action: sleep\nAction Input: 2"}}
+	agent := toolCallsAgent{agents.NewOneShotAgent(llm, nil)}
+	returnChan := make(chan string, 100)
+
+	_, err := chains.Call(context.Background(), newExecutor(agent, NewHandler(returnChan), 2, 1), map[string]any{"input": "go"})
+	assert.ErrorIs(t, err, agents.ErrNotFinished)
+	assert.Empty(t, llm.replies)
+}
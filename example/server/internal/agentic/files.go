@@ -0,0 +1,109 @@
+package agentic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const (
+	// maxFileThreads bounds the threads Files keeps attachments for, the least
+	// recently used thread is dropped first
+	maxFileThreads = 100
+	// maxThreadFiles bounds the attachments kept per thread, the oldest is dropped first
+	maxThreadFiles = 20
+)
+
+// Files keeps the attachments sent in each thread, so later runs still see
+// them and messages can refer to them by id. Clients may send only the new
+// message of a thread, so like HistoryCache it is shared between runs.
+type Files struct {
+	mu      sync.Mutex
+	threads map[string]*threadFiles
+}
+
+type threadFiles struct {
+	files    []Attachment
+	lastUsed time.Time
+}
+
+func NewFiles() *Files {
+	return &Files{threads: make(map[string]*threadFiles)}
+}
+
+// Get returns the attachment of threadID with id
+func (f *Files) Get(threadID, id string) (Attachment, bool) {
+	if f == nil || threadID == "" {
+		return Attachment{}, false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	thread, ok := f.threads[threadID]
+	if !ok {
+		return Attachment{}, false
+	}
+	thread.lastUsed = time.Now()
+	for _, file := range thread.files {
+		if file.ID == id {
+			return file, true
+		}
+	}
+	return Attachment{}, false
+}
+
+// Keep adds attachments to threadID and returns every attachment of the
+// thread, oldest first. Attachments without an ID are given one derived from
+// their content, so files sent again are only kept once.
+func (f *Files) Keep(threadID string, attachments []Attachment) []Attachment {
+	var files []Attachment
+	if f != nil && threadID != "" {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if thread, ok := f.threads[threadID]; ok {
+			files = thread.files
+		}
+	}
+
+	for _, a := range attachments {
+		if a.ID == "" {
+			a.ID = fileID(a)
+		}
+		files = append(removeFile(files, a.ID), a)
+	}
+	if len(files) > maxThreadFiles {
+		files = files[len(files)-maxThreadFiles:]
+	}
+
+	if f != nil && threadID != "" {
+		if _, ok := f.threads[threadID]; !ok && len(f.threads) >= maxFileThreads {
+			var oldest string
+			for id, t := range f.threads {
+				if oldest == "" || t.lastUsed.Before(f.threads[oldest].lastUsed) {
+					oldest = id
+				}
+			}
+			delete(f.threads, oldest)
+		}
+		f.threads[threadID] = &threadFiles{files: files, lastUsed: time.Now()}
+	}
+	return append([]Attachment{}, files...)
+}
+
+// fileID identifies an attachment sent without an id by its content
+func fileID(a Attachment) string {
+	h := sha256.New()
+	h.Write([]byte(a.MimeType + "\x00" + a.URL + "\x00"))
+	h.Write(a.Data)
+	return "file-" + hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+func removeFile(files []Attachment, id string) []Attachment {
+	kept := make([]Attachment, 0, len(files))
+	for _, file := range files {
+		if file.ID != id {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
@@ -2,21 +2,77 @@ package agentic
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/ag-ui-protocol/ag-ui/sdks/community/go/pkg/core/events"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/mcp"
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/schema"
 )
 
+// ToolProgressEvent names the CUSTOM event sent when a running tool reports
+// progress. Its value is
+//
+//	{"toolCallId": "...", "toolName": "...", "progress": 2, "total": 5, "message": "..."}
+//
+// where total and message are left out when the tool did not report them.
+const ToolProgressEvent = "tool_progress"
+
+// SubAgentEvent names the CUSTOM event sent before each step, message and tool
+// call of a sub-agent working on a task delegated by a tool call. Its value is
+// one of
+//
+//	{"agent": "...", "parentToolCallId": "...", "stepName": "..."}
+//	{"agent": "...", "parentToolCallId": "...", "messageId": "..."}
+//	{"agent": "...", "parentToolCallId": "...", "toolCallId": "...", "toolName": "..."}
+//
+// The sub-agent's answer is the delegating call's result.
+const SubAgentEvent = "sub_agent"
+
 type Handler struct {
 	returnChan chan<- string
 	// ID tracking for event correlation
-	threadID   string
-	runID      string
-	messageID  string
+	threadID  string
+	runID     string
+	messageID string
+	stepID    string
+
+	// toolCallID is the call opened by HandleAgentAction for a context
+	// without one, see withToolCallID
+	mu         sync.Mutex
+	toolCallID string
+
+	// parent is the tool call a sub-agent's handler works for, nil for the
+	// run's own agent
+	parent *delegation
+}
+
+// delegation is a task given to a sub-agent through a tool call
+type delegation struct {
+	agent      string
 	toolCallID string
-	stepID     string
+}
+
+type toolCallIDKey struct{}
+
+// withToolCallID returns the context a tool call runs with. The handler
+// reports the call's progress and result under id, which lets calls run
+// concurrently.
+func withToolCallID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, toolCallIDKey{}, id)
+}
+
+// callID returns the tool call ctx belongs to, empty when none is open
+func (h *Handler) callID(ctx context.Context) string {
+	if id, ok := ctx.Value(toolCallIDKey{}).(string); ok {
+		return id
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.toolCallID
 }
 
 func NewHandler(returnChan chan<- string) *Handler {
@@ -27,6 +83,28 @@ func NewHandler(returnChan chan<- string) *Handler {
 	}
 }
 
+// child returns the handler of agent working on the task of the tool call toolCallID
+func (h *Handler) child(agent, toolCallID string) *Handler {
+	return &Handler{
+		returnChan: h.returnChan,
+		threadID:   h.threadID,
+		runID:      h.runID,
+		parent:     &delegation{agent: agent, toolCallID: toolCallID},
+	}
+}
+
+// correlate sends a SubAgentEvent telling clients that the step, message or
+// tool call in value belongs to the delegated task, nothing is sent for the
+// run's own agent
+func (h *Handler) correlate(value map[string]any) {
+	if h.parent == nil {
+		return
+	}
+	value["agent"] = h.parent.agent
+	value["parentToolCallId"] = h.parent.toolCallID
+	emit(h.returnChan, events.NewCustomEvent(SubAgentEvent, events.WithValue(value)))
+}
+
 func (h *Handler) HandleText(ctx context.Context, text string) {
 	message := events.NewTextMessageContentEvent("test", text)
 	if jsonData, err := message.ToJSON(); err == nil {
@@ -35,14 +113,10 @@ func (h *Handler) HandleText(ctx context.Context, text string) {
 }
 
 func (h *Handler) HandleLLMStart(ctx context.Context, prompts []string) {
-	// Generate new message ID for this LLM interaction
+	// Generate new message ID for this LLM interaction, the run itself is
+	// started and finished by the route
 	h.messageID = events.GenerateMessageID()
-
-	// Send run started event
-	runStartedEvent := events.NewRunStartedEvent(h.threadID, h.runID)
-	if jsonData, err := runStartedEvent.ToJSON(); err == nil {
-		h.returnChan <- string(jsonData)
-	}
+	h.correlate(map[string]any{"messageId": h.messageID})
 
 	// Send text message start event
 	textStartEvent := events.NewTextMessageStartEvent(h.messageID, events.WithRole("assistant"))
@@ -55,6 +129,7 @@ func (h *Handler) HandleLLMGenerateContentStart(ctx context.Context, ms []llms.M
 	// Generate new message ID if not already set
 	if h.messageID == "" {
 		h.messageID = events.GenerateMessageID()
+		h.correlate(map[string]any{"messageId": h.messageID})
 	}
 
 	// Determine role from message content
@@ -111,6 +186,7 @@ func (h *Handler) HandleLLMError(ctx context.Context, err error) {
 func (h *Handler) HandleChainStart(ctx context.Context, inputs map[string]any) {
 	// Generate step ID for this chain execution
 	h.stepID = events.GenerateStepID()
+	h.correlate(map[string]any{"stepName": h.stepID})
 
 	// Send step started event with step name
 	stepStartedEvent := events.NewStepStartedEvent(h.stepID)
@@ -136,12 +212,23 @@ func (h *Handler) HandleChainError(ctx context.Context, err error) {
 		// Create error message
 		if h.messageID == "" {
 			h.messageID = events.GenerateMessageID()
+			h.correlate(map[string]any{"messageId": h.messageID})
+			textStartEvent := events.NewTextMessageStartEvent(h.messageID, events.WithRole("assistant"))
+			if jsonData, err := textStartEvent.ToJSON(); err == nil {
+				h.returnChan <- string(jsonData)
+			}
 		}
 		errorMessage := events.NewTextMessageContentEvent(h.messageID, "Chain error: "+err.Error())
 		if jsonData, err := errorMessage.ToJSON(); err == nil {
 			h.returnChan <- string(jsonData)
 		}
 
+		textEndEvent := events.NewTextMessageEndEvent(h.messageID)
+		if jsonData, err := textEndEvent.ToJSON(); err == nil {
+			h.returnChan <- string(jsonData)
+		}
+		h.messageID = ""
+
 		// Mark step as finished even with error
 		stepFinishedEvent := events.NewStepFinishedEvent(h.stepID)
 		if jsonData, err := stepFinishedEvent.ToJSON(); err == nil {
@@ -153,83 +240,138 @@ func (h *Handler) HandleChainError(ctx context.Context, err error) {
 
 func (h *Handler) HandleToolStart(ctx context.Context, input string) {
 	// Generate tool call ID
-	h.toolCallID = events.GenerateToolCallID()
+	toolCallID := events.GenerateToolCallID()
+	h.mu.Lock()
+	h.toolCallID = toolCallID
+	h.mu.Unlock()
 
 	// Extract tool name from input if possible
 	toolName := "tool"
+	h.correlate(map[string]any{"toolCallId": toolCallID, "toolName": toolName})
 
 	// Send tool call start event
-	toolStartEvent := events.NewToolCallStartEvent(h.toolCallID, toolName)
+	toolStartEvent := events.NewToolCallStartEvent(toolCallID, toolName)
 	if h.messageID != "" {
-		toolStartEvent = events.NewToolCallStartEvent(h.toolCallID, toolName, events.WithParentMessageID(h.messageID))
+		toolStartEvent = events.NewToolCallStartEvent(toolCallID, toolName, events.WithParentMessageID(h.messageID))
 	}
 	if jsonData, err := toolStartEvent.ToJSON(); err == nil {
 		h.returnChan <- string(jsonData)
 	}
 
 	// Send tool arguments
-	toolArgsEvent := events.NewToolCallArgsEvent(h.toolCallID, input)
+	toolArgsEvent := events.NewToolCallArgsEvent(toolCallID, input)
 	if jsonData, err := toolArgsEvent.ToJSON(); err == nil {
 		h.returnChan <- string(jsonData)
 	}
 }
 
 func (h *Handler) HandleToolEnd(ctx context.Context, output string) {
-	if h.toolCallID != "" {
-		// Send tool call end event
-		toolEndEvent := events.NewToolCallEndEvent(h.toolCallID)
-		if jsonData, err := toolEndEvent.ToJSON(); err == nil {
-			h.returnChan <- string(jsonData)
-		}
+	if toolCallID := h.closeToolCall(); toolCallID != "" {
+		h.endToolCall(toolCallID, output)
+	}
+}
 
-		// Send tool call result event
-		resultMessageID := events.GenerateMessageID()
-		toolResultEvent := events.NewToolCallResultEvent(resultMessageID, h.toolCallID, output)
-		if jsonData, err := toolResultEvent.ToJSON(); err == nil {
-			h.returnChan <- string(jsonData)
-		}
+func (h *Handler) HandleToolError(ctx context.Context, err error) {
+	if toolCallID := h.closeToolCall(); toolCallID != "" {
+		h.endToolCall(toolCallID, "Error: "+err.Error())
+	}
+}
 
-		h.toolCallID = ""
+// closeToolCall returns the call opened by HandleToolStart or
+// HandleAgentAction and forgets it, empty when none is open
+func (h *Handler) closeToolCall() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	toolCallID := h.toolCallID
+	h.toolCallID = ""
+	return toolCallID
+}
+
+// HandleToolResult reports the result of an MCP tool call started by
+// HandleAgentAction. The result is sent as its MCP JSON encoding so clients
+// get structured content, images and resources rather than flattened text.
+func (h *Handler) HandleToolResult(ctx context.Context, tool string, result *mcpgo.CallToolResult) {
+	if h.callID(ctx) == "" {
+		return
 	}
+
+	content, err := json.Marshal(result)
+	if err != nil {
+		content = []byte(mcp.ResultText(result))
+	}
+	h.finishToolCall(ctx, string(content))
 }
 
-func (h *Handler) HandleToolError(ctx context.Context, err error) {
-	if h.toolCallID != "" {
-		// Send error as tool result
-		resultMessageID := events.GenerateMessageID()
-		toolResultEvent := events.NewToolCallResultEvent(resultMessageID, h.toolCallID, "Error: "+err.Error())
-		if jsonData, err := toolResultEvent.ToJSON(); err == nil {
-			h.returnChan <- string(jsonData)
-		}
+// HandleToolProgress sends the progress a running MCP tool reported as a
+// ToolProgressEvent
+func (h *Handler) HandleToolProgress(ctx context.Context, tool string, progress mcp.Progress) {
+	toolCallID := h.callID(ctx)
+	if toolCallID == "" {
+		return
+	}
 
-		// Send tool call end event
-		toolEndEvent := events.NewToolCallEndEvent(h.toolCallID)
-		if jsonData, err := toolEndEvent.ToJSON(); err == nil {
-			h.returnChan <- string(jsonData)
-		}
+	value := map[string]any{
+		"toolCallId": toolCallID,
+		"toolName":   tool,
+		"progress":   progress.Progress,
+	}
+	if progress.Total > 0 {
+		value["total"] = progress.Total
+	}
+	if progress.Message != "" {
+		value["message"] = progress.Message
+	}
+	emit(h.returnChan, events.NewCustomEvent(ToolProgressEvent, events.WithValue(value)))
+}
+
+// finishToolCall ends the tool call ctx belongs to with content as its result
+func (h *Handler) finishToolCall(ctx context.Context, content string) {
+	toolCallID, ok := ctx.Value(toolCallIDKey{}).(string)
+	if !ok {
+		toolCallID = h.closeToolCall()
+	}
+	h.endToolCall(toolCallID, content)
+}
 
-		h.toolCallID = ""
+// endToolCall sends the end and result events of toolCallID
+func (h *Handler) endToolCall(toolCallID, content string) {
+	toolEndEvent := events.NewToolCallEndEvent(toolCallID)
+	if jsonData, err := toolEndEvent.ToJSON(); err == nil {
+		h.returnChan <- string(jsonData)
+	}
+
+	resultMessageID := events.GenerateMessageID()
+	toolResultEvent := events.NewToolCallResultEvent(resultMessageID, toolCallID, content)
+	if jsonData, err := toolResultEvent.ToJSON(); err == nil {
+		h.returnChan <- string(jsonData)
 	}
 }
 
 func (h *Handler) HandleAgentAction(ctx context.Context, action schema.AgentAction) {
-	// Agent is taking an action (usually a tool call)
-	// Generate tool call ID if not already set
-	if h.toolCallID == "" {
-		h.toolCallID = events.GenerateToolCallID()
+	// Agent is taking an action (usually a tool call). Calls started by the
+	// executor carry their ID, others reuse the open one or get a new ID.
+	toolCallID, ok := ctx.Value(toolCallIDKey{}).(string)
+	if !ok {
+		h.mu.Lock()
+		if h.toolCallID == "" {
+			h.toolCallID = events.GenerateToolCallID()
+		}
+		toolCallID = h.toolCallID
+		h.mu.Unlock()
 	}
 
 	// Send tool call start event for the action
-	toolStartEvent := events.NewToolCallStartEvent(h.toolCallID, action.Tool)
+	h.correlate(map[string]any{"toolCallId": toolCallID, "toolName": action.Tool})
+	toolStartEvent := events.NewToolCallStartEvent(toolCallID, action.Tool)
 	if h.messageID != "" {
-		toolStartEvent = events.NewToolCallStartEvent(h.toolCallID, action.Tool, events.WithParentMessageID(h.messageID))
+		toolStartEvent = events.NewToolCallStartEvent(toolCallID, action.Tool, events.WithParentMessageID(h.messageID))
 	}
 	if jsonData, err := toolStartEvent.ToJSON(); err == nil {
 		h.returnChan <- string(jsonData)
 	}
 
 	// Send the tool input as arguments
-	toolArgsEvent := events.NewToolCallArgsEvent(h.toolCallID, action.ToolInput)
+	toolArgsEvent := events.NewToolCallArgsEvent(toolCallID, action.ToolInput)
 	if jsonData, err := toolArgsEvent.ToJSON(); err == nil {
 		h.returnChan <- string(jsonData)
 	}
@@ -237,11 +379,16 @@ func (h *Handler) HandleAgentAction(ctx context.Context, action schema.AgentActi
 
 func (h *Handler) HandleAgentFinish(ctx context.Context, finish schema.AgentFinish) {
 	// Agent has finished its run
-	// Send final message if we have output
-	if finish.ReturnValues != nil {
+	// Send final message if we have output, a sub-agent's answer is instead
+	// the result of the call that delegated to it
+	if finish.ReturnValues != nil && h.parent == nil {
 		if output, ok := finish.ReturnValues["output"].(string); ok && output != "" {
 			if h.messageID == "" {
 				h.messageID = events.GenerateMessageID()
+				textStartEvent := events.NewTextMessageStartEvent(h.messageID, events.WithRole("assistant"))
+				if jsonData, err := textStartEvent.ToJSON(); err == nil {
+					h.returnChan <- string(jsonData)
+				}
 			}
 			finalMessage := events.NewTextMessageContentEvent(h.messageID, output)
 			if jsonData, err := finalMessage.ToJSON(); err == nil {
@@ -253,39 +400,34 @@ func (h *Handler) HandleAgentFinish(ctx context.Context, finish schema.AgentFini
 			if jsonData, err := textEndEvent.ToJSON(); err == nil {
 				h.returnChan <- string(jsonData)
 			}
+			h.messageID = ""
 		}
 	}
-
-	// Send run finished event
-	runFinishedEvent := events.NewRunFinishedEvent(h.threadID, h.runID)
-	if jsonData, err := runFinishedEvent.ToJSON(); err == nil {
-		h.returnChan <- string(jsonData)
-	}
 }
 
+// HandleRetrieverStart announces a search as a RetrievalToolName call unless
+// the agent already started one for it
 func (h *Handler) HandleRetrieverStart(ctx context.Context, query string) {
-	// Start a retrieval operation
-	if h.messageID == "" {
-		h.messageID = events.GenerateMessageID()
+	if h.callID(ctx) != "" {
+		return
 	}
-
-	// Send a message indicating retrieval is starting
-	retrievalMessage := events.NewTextMessageContentEvent(h.messageID, "Searching for: "+query)
-	if jsonData, err := retrievalMessage.ToJSON(); err == nil {
-		h.returnChan <- string(jsonData)
+	args, err := json.Marshal(map[string]string{"query": query})
+	if err != nil {
+		return
 	}
+	h.HandleAgentAction(ctx, schema.AgentAction{Tool: RetrievalToolName, ToolInput: string(args)})
 }
 
+// HandleRetrieverEnd sends the documents found as the search's result, see RetrievalResult
 func (h *Handler) HandleRetrieverEnd(ctx context.Context, query string, documents []schema.Document) {
-	// Retrieval operation completed
-	if h.messageID != "" {
-		// Send message about retrieval results
-		resultMsg := fmt.Sprintf("Found %d documents for query: %s", len(documents), query)
-		retrievalResult := events.NewTextMessageContentEvent(h.messageID, resultMsg)
-		if jsonData, err := retrievalResult.ToJSON(); err == nil {
-			h.returnChan <- string(jsonData)
-		}
+	if h.callID(ctx) == "" {
+		return
+	}
+	content, err := json.Marshal(RetrievalResult{Query: query, Documents: citations(documents)})
+	if err != nil {
+		content = []byte(fmt.Sprintf("Found %d documents for query: %s", len(documents), query))
 	}
+	h.finishToolCall(ctx, string(content))
 }
 
 func (h *Handler) HandleStreamingFunc(ctx context.Context, chunk []byte) {
@@ -0,0 +1,46 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+)
+
+func TestSubAgentCorrelation(t *testing.T) {
+	sent := make(chan string, 10)
+	decode := func() map[string]any {
+		var event map[string]any
+		require.NoError(t, json.Unmarshal([]byte(<-sent), &event))
+		return event
+	}
+
+	// The run's own agent sends no correlation
+	root := NewHandler(sent)
+	root.HandleLLMStart(context.Background(), nil)
+	assert.Equal(t, "TEXT_MESSAGE_START", decode()["type"])
+
+	// A sub-agent's messages and tool calls name it and the delegating call
+	child := root.child("reviewer", "call-1")
+	child.HandleLLMStart(context.Background(), nil)
+	correlation, start := decode(), decode()
+	assert.Equal(t, SubAgentEvent, correlation["name"])
+	assert.Equal(t, map[string]any{"agent": "reviewer", "parentToolCallId": "call-1", "messageId": start["messageId"]}, correlation["value"])
+	assert.Equal(t, "TEXT_MESSAGE_START", start["type"])
+	messageID := start["messageId"]
+
+	// Its tool calls belong to its own message, not the delegating call
+	child.HandleAgentAction(context.Background(), schema.AgentAction{Tool: "lint", ToolInput: "{}"})
+	correlation, start = decode(), decode()
+	assert.Equal(t, map[string]any{
+		"agent":            "reviewer",
+		"parentToolCallId": "call-1",
+		"toolCallId":       start["toolCallId"],
+		"toolName":         "lint",
+	}, correlation["value"])
+	assert.Equal(t, "TOOL_CALL_START", start["type"])
+	assert.Equal(t, messageID, start["parentMessageId"])
+}
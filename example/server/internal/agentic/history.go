@@ -0,0 +1,284 @@
+package agentic
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/config"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// maxCachedThreads bounds the summaries a HistoryCache keeps, the least
+// recently used thread is dropped first
+const maxCachedThreads = 1000
+
+// charsPerToken approximates the tokenizers of the providers by model prefix
+var charsPerToken = []struct {
+	prefix string
+	chars  float64
+}{
+	{"claude", 3.5},
+	{"gpt", 4},
+}
+
+// CountTokens estimates the tokens model needs for text from its length.
+// The providers do not expose their tokenizers offline, so the count can be
+// off by a few percent either way.
+func CountTokens(model, text string) int {
+	chars := 4.0
+	for _, p := range charsPerToken {
+		if strings.HasPrefix(model, p.prefix) {
+			chars = p.chars
+			break
+		}
+	}
+	return int(math.Ceil(float64(utf8.RuneCountInString(text)) / chars))
+}
+
+// HistoryMessage is an earlier message of the conversation
+type HistoryMessage struct {
+	Role    string
+	Content string
+}
+
+func (m HistoryMessage) String() string {
+	return fmt.Sprintf("%s: %s", m.Role, m.Content)
+}
+
+// HistoryPolicy decides how much of the conversation the agent sees
+type HistoryPolicy struct {
+	// Mode is config.HistoryTruncate, config.HistorySummarize or config.HistorySlidingWindow
+	Mode string
+	// MaxTokens bounds the system prompt, history and input together,
+	// 0 uses config.DefaultHistoryTokens. Tokens are estimated with
+	// CountTokens rather than the provider's tokenizer, so leave a margin
+	// below the model's context window.
+	MaxTokens int
+	// Turns is the number of user turns a sliding window keeps, 0 uses
+	// config.DefaultHistoryTurns
+	Turns int
+}
+
+// HistoryCache holds the rolling summary of each thread. It is shared between
+// runs because every request carries the thread from the start.
+type HistoryCache struct {
+	mu      sync.Mutex
+	threads map[string]*threadSummary
+}
+
+// threadSummary summarizes the first covered messages of a thread
+type threadSummary struct {
+	covered int
+	// digest identifies the covered messages, an edited thread is summarized again
+	digest   [sha256.Size]byte
+	text     string
+	lastUsed time.Time
+}
+
+func NewHistoryCache() *HistoryCache {
+	return &HistoryCache{threads: make(map[string]*threadSummary)}
+}
+
+func (c *HistoryCache) get(threadID string) (threadSummary, bool) {
+	if c == nil || threadID == "" {
+		return threadSummary{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	summary, ok := c.threads[threadID]
+	if !ok {
+		return threadSummary{}, false
+	}
+	summary.lastUsed = time.Now()
+	return *summary, true
+}
+
+func (c *HistoryCache) put(threadID string, summary threadSummary) {
+	if c == nil || threadID == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.threads[threadID]; !ok && len(c.threads) >= maxCachedThreads {
+		var oldest string
+		for id, s := range c.threads {
+			if oldest == "" || s.lastUsed.Before(c.threads[oldest].lastUsed) {
+				oldest = id
+			}
+		}
+		delete(c.threads, oldest)
+	}
+	summary.lastUsed = time.Now()
+	c.threads[threadID] = &summary
+}
+
+// digestOf identifies messages
+func digestOf(messages []HistoryMessage) [sha256.Size]byte {
+	h := sha256.New()
+	for _, m := range messages {
+		fmt.Fprintf(h, "%s\x00%s\x00", m.Role, m.Content)
+	}
+	var digest [sha256.Size]byte
+	copy(digest[:], h.Sum(nil))
+	return digest
+}
+
+// withHistory puts the part of the conversation the policy keeps in front of
+// input. reserved is the token count of the rest of the prompt.
+func withHistory(ctx context.Context, llm llms.Model, input string, reserved int, opts Options) (string, error) {
+	if len(opts.History) == 0 {
+		return input, nil
+	}
+
+	policy := opts.HistoryPolicy
+	if policy.MaxTokens == 0 {
+		policy.MaxTokens = config.DefaultHistoryTokens
+	}
+	if policy.Turns == 0 {
+		policy.Turns = config.DefaultHistoryTurns
+	}
+	count := func(text string) int { return CountTokens(opts.Model, text) }
+	budget := policy.MaxTokens - reserved - count(input)
+	if budget <= 0 {
+		// The request alone uses up the limit, no history fits
+		return input, nil
+	}
+
+	var summary string
+	kept := opts.History
+	switch policy.Mode {
+	case config.HistorySlidingWindow:
+		kept = keepTokens(lastTurns(kept, policy.Turns), budget, count)
+	case config.HistorySummarize:
+		// The summary may take a quarter of the budget, the recent messages the rest
+		kept = keepTokens(kept, budget-budget/4, count)
+		if older := opts.History[:len(opts.History)-len(kept)]; len(older) > 0 && budget/4 > 0 {
+			var err error
+			if summary, err = summarize(ctx, llm, opts, older, policy.MaxTokens, budget/4); err != nil {
+				return "", err
+			}
+		}
+	default:
+		kept = keepTokens(kept, budget, count)
+	}
+
+	var sections []string
+	if summary != "" {
+		sections = append(sections, "## Summary of the earlier conversation\n\n"+summary)
+	}
+	if len(kept) > 0 {
+		lines := make([]string, 0, len(kept))
+		for _, m := range kept {
+			lines = append(lines, m.String())
+		}
+		sections = append(sections, "## Conversation so far\n\n"+strings.Join(lines, "\n\n"))
+	}
+	if len(sections) == 0 {
+		return input, nil
+	}
+	sections = append(sections, "## Current request\n\n"+input)
+	return strings.Join(sections, "\n\n"), nil
+}
+
+// keepTokens returns the most recent messages that fit in budget tokens
+func keepTokens(messages []HistoryMessage, budget int, count func(string) int) []HistoryMessage {
+	start := len(messages)
+	for start > 0 {
+		cost := count(messages[start-1].String())
+		if cost > budget {
+			break
+		}
+		budget -= cost
+		start--
+	}
+	return messages[start:]
+}
+
+// lastTurns returns the messages from the turns-th last user message on
+func lastTurns(messages []HistoryMessage, turns int) []HistoryMessage {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != "user" {
+			continue
+		}
+		if turns--; turns == 0 {
+			return messages[i:]
+		}
+	}
+	return messages
+}
+
+// summarize returns the rolling summary of older, extending the thread's
+// cached summary with the messages it does not cover yet. The messages are
+// folded into the summary in chunks, so no prompt exceeds maxTokens, and the
+// summary is kept to summaryTokens.
+func summarize(ctx context.Context, llm llms.Model, opts Options, older []HistoryMessage, maxTokens, summaryTokens int) (string, error) {
+	cached, ok := opts.HistoryCache.get(opts.ThreadID)
+	if ok && cached.covered <= len(older) && cached.digest == digestOf(older[:cached.covered]) {
+		if cached.covered == len(older) {
+			return cached.text, nil
+		}
+	} else {
+		cached = threadSummary{}
+	}
+
+	count := func(text string) int { return CountTokens(opts.Model, text) }
+	instructions := fmt.Sprintf("Summarize the conversation below for an assistant that will continue it. "+
+		"Keep the user's goals, decisions, facts and open questions, drop small talk. "+
+		"Use at most %d words and answer with the summary only.\n\n", summaryTokens*3/4)
+	// The rest of the prompt is the instructions, the summary so far and the headings
+	chunkTokens := max(maxTokens-summaryTokens-count(instructions)-count(summaryHeading+messagesHeading), 1)
+
+	for cached.covered < len(older) {
+		var prompt strings.Builder
+		prompt.WriteString(instructions)
+		if cached.text != "" {
+			prompt.WriteString(summaryHeading + cached.text + "\n\n")
+		}
+		prompt.WriteString(messagesHeading)
+
+		// Every chunk takes at least one message, cut to fit if it is too long alone
+		end, used := cached.covered, 0
+		for end < len(older) {
+			text := older[end].String()
+			if used+count(text) > chunkTokens {
+				if end == cached.covered {
+					prompt.WriteString(truncateTokens(text, chunkTokens, count) + "\n\n")
+					end++
+				}
+				break
+			}
+			prompt.WriteString(text + "\n\n")
+			used += count(text)
+			end++
+		}
+
+		text, err := llms.GenerateFromSinglePrompt(ctx, llm, prompt.String(), llms.WithMaxTokens(summaryTokens))
+		if err != nil {
+			return "", fmt.Errorf("summarize history: %w", err)
+		}
+		cached = threadSummary{covered: end, digest: digestOf(older[:end]), text: strings.TrimSpace(text)}
+		opts.HistoryCache.put(opts.ThreadID, cached)
+	}
+	return cached.text, nil
+}
+
+// Headings of the summary prompt
+const (
+	summaryHeading  = "## Summary so far\n\n"
+	messagesHeading = "## Messages\n\n"
+)
+
+// truncateTokens cuts text to at most limit tokens
+func truncateTokens(text string, limit int, count func(string) int) string {
+	runes := []rune(text)
+	for len(runes) > 0 && count(string(runes)) > limit {
+		runes = runes[:len(runes)*limit/count(string(runes))]
+	}
+	return string(runes)
+}
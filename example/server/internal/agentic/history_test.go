@@ -0,0 +1,147 @@
+package agentic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// summaryModel answers every prompt with a numbered summary and keeps the
+// prompts and their token limits
+type summaryModel struct {
+	prompts   []string
+	maxTokens []int
+}
+
+func (m *summaryModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	var opts llms.CallOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	m.maxTokens = append(m.maxTokens, opts.MaxTokens)
+
+	var prompt strings.Builder
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				prompt.WriteString(text.Text)
+			}
+		}
+	}
+	m.prompts = append(m.prompts, prompt.String())
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: fmt.Sprintf("summary %d", len(m.prompts))}}}, nil
+}
+
+func (m *summaryModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func TestWithHistory(t *testing.T) {
+	// Every message costs 10 tokens with the default 4 characters per token
+	message := func(role string, i int) HistoryMessage {
+		return HistoryMessage{Role: role, Content: fmt.Sprintf("%-*d", 38-len(role), i)}
+	}
+	var history []HistoryMessage
+	for i := range 6 {
+		history = append(history, message("user", 2*i), message("assistant", 2*i+1))
+	}
+
+	run := func(llm llms.Model, history []HistoryMessage, policy HistoryPolicy, cache *HistoryCache) string {
+		input, err := withHistory(context.Background(), llm, "next", 0, Options{
+			Model:         "test",
+			History:       history,
+			HistoryPolicy: policy,
+			HistoryCache:  cache,
+			ThreadID:      "thread-1",
+		})
+		require.NoError(t, err)
+		return input
+	}
+
+	t.Run("truncate", func(t *testing.T) {
+		input := run(nil, history, HistoryPolicy{Mode: config.HistoryTruncate, MaxTokens: 31}, nil)
+		assert.NotContains(t, input, "user: 8 ")
+		assert.Contains(t, input, "assistant: 9 ")
+		assert.True(t, strings.HasSuffix(input, "## Current request\n\nnext"))
+	})
+
+	t.Run("sliding window", func(t *testing.T) {
+		input := run(nil, history, HistoryPolicy{Mode: config.HistorySlidingWindow, MaxTokens: 1000, Turns: 2}, nil)
+		assert.NotContains(t, input, "assistant: 7 ")
+		assert.Contains(t, input, "user: 8 ")
+	})
+
+	t.Run("summarize", func(t *testing.T) {
+		llm := &summaryModel{}
+		cache := NewHistoryCache()
+		policy := HistoryPolicy{Mode: config.HistorySummarize, MaxTokens: 141}
+
+		input := run(llm, history, policy, cache)
+		assert.Contains(t, llm.prompts[0], "user: 0 ")
+		assert.Contains(t, input, fmt.Sprintf("## Summary of the earlier conversation\n\nsummary %d", len(llm.prompts)))
+		assert.Contains(t, input, "user: 10 ")
+		summaries := len(llm.prompts)
+
+		// The same thread reuses the summary
+		run(llm, history, policy, cache)
+		require.Len(t, llm.prompts, summaries)
+
+		// Messages that no longer fit extend the summary
+		input = run(llm, append(history, message("user", 12), message("assistant", 13)), policy, cache)
+		require.Greater(t, len(llm.prompts), summaries)
+		assert.Contains(t, llm.prompts[summaries], fmt.Sprintf("## Summary so far\n\nsummary %d", summaries))
+		assert.NotContains(t, llm.prompts[summaries], "user: 0 ")
+		assert.Contains(t, input, fmt.Sprintf("summary %d", len(llm.prompts)))
+		summaries = len(llm.prompts)
+
+		// An edited thread is summarized again
+		edited := append([]HistoryMessage{message("user", 99)}, history[1:]...)
+		run(llm, edited, policy, cache)
+		require.Greater(t, len(llm.prompts), summaries)
+		assert.Contains(t, llm.prompts[summaries], "user: 99 ")
+	})
+
+	t.Run("summarize in chunks", func(t *testing.T) {
+		var long []HistoryMessage
+		for i := range 12 {
+			long = append(long, message("user", 2*i), message("assistant", 2*i+1))
+		}
+		llm := &summaryModel{}
+		policy := HistoryPolicy{Mode: config.HistorySummarize, MaxTokens: 141}
+
+		input := run(llm, long, policy, nil)
+		require.Greater(t, len(llm.prompts), 1)
+		for i, prompt := range llm.prompts {
+			assert.LessOrEqual(t, CountTokens("test", prompt)+llm.maxTokens[i], policy.MaxTokens)
+			assert.Equal(t, 35, llm.maxTokens[i])
+			if i > 0 {
+				assert.Contains(t, prompt, fmt.Sprintf("## Summary so far\n\nsummary %d", i))
+			}
+		}
+		assert.Contains(t, llm.prompts[0], "user: 0 ")
+		assert.Contains(t, llm.prompts[len(llm.prompts)-1], "assistant: 13 ")
+		assert.Contains(t, input, fmt.Sprintf("summary %d", len(llm.prompts)))
+		assert.Contains(t, input, "user: 14 ")
+	})
+
+	t.Run("no budget left", func(t *testing.T) {
+		// The request alone exceeds the limit, nothing is summarized
+		llm := &summaryModel{}
+		for _, maxTokens := range []int{1, 3} {
+			input := run(llm, history, HistoryPolicy{Mode: config.HistorySummarize, MaxTokens: maxTokens}, nil)
+			assert.Equal(t, "next", input)
+		}
+		assert.Empty(t, llm.prompts)
+	})
+}
+
+func TestCountTokens(t *testing.T) {
+	assert.Equal(t, 2, CountTokens("claude-3-haiku-20240307", "1234567"))
+	assert.Equal(t, 3, CountTokens("other", "123456789"))
+}
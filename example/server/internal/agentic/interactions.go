@@ -0,0 +1,229 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ag-ui-protocol/ag-ui/sdks/community/go/pkg/core/events"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/mcp"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/schema"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// ElicitToolName is the frontend tool MCP elicitation requests are forwarded as.
+// Its arguments are {"message": "...", "requestedSchema": {...}} and the client
+// answers by sending a tool message for the call:
+//
+//	{"action": "accept", "content": {"name": "Ada"}}
+//
+// A bare object is taken as accepted content. The answer has to arrive before
+// the MCP tool call that asked for it times out.
+const ElicitToolName = "elicit_user_input"
+
+// Interactions holds the elicitations waiting for the user, keyed by the tool
+// call ID they were sent as. It is shared between runs because the answer
+// arrives in a request of its own.
+type Interactions struct {
+	mu      sync.Mutex
+	pending map[string]chan string
+}
+
+func NewInteractions() *Interactions {
+	return &Interactions{pending: make(map[string]chan string)}
+}
+
+// Resolve delivers answer to the elicitation sent as tool call id and reports
+// whether one was waiting for it
+func (i *Interactions) Resolve(id, answer string) bool {
+	i.mu.Lock()
+	answers, ok := i.pending[id]
+	delete(i.pending, id)
+	i.mu.Unlock()
+
+	if ok {
+		answers <- answer
+	}
+	return ok
+}
+
+func (i *Interactions) register(id string) <-chan string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	answers := make(chan string, 1)
+	i.pending[id] = answers
+	return answers
+}
+
+func (i *Interactions) remove(id string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.pending, id)
+}
+
+// callHandlers answers the elicitation and sampling requests of the run's MCP
+// tool calls. Elicitations are declined when the run has no Interactions.
+func callHandlers(llm llms.Model, opts Options, returnChan chan<- string) mcp.CallHandlers {
+	handlers := mcp.CallHandlers{Sample: sampler(llm, opts.Model)}
+	if opts.Interactions != nil {
+		handlers.Elicit = elicitor(opts.Interactions, returnChan)
+	}
+	return handlers
+}
+
+// elicitor forwards elicitation requests to the client as ElicitToolName calls
+// and waits for the answer
+func elicitor(interactions *Interactions, returnChan chan<- string) mcp.ElicitFunc {
+	return func(ctx context.Context, request mcpgo.ElicitationRequest) (*mcpgo.ElicitationResult, error) {
+		requested, err := toObject(request.Params.RequestedSchema)
+		if err != nil {
+			return nil, fmt.Errorf("requested schema: %w", err)
+		}
+		validator, err := schema.Compile("elicitation", requested)
+		if err != nil {
+			return nil, err
+		}
+		args, err := json.Marshal(map[string]any{
+			"message":         request.Params.Message,
+			"requestedSchema": requested,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("marshal elicitation: %w", err)
+		}
+
+		toolCallID := events.GenerateToolCallID()
+		answers := interactions.register(toolCallID)
+		defer interactions.remove(toolCallID)
+
+		emit(returnChan, events.NewToolCallStartEvent(toolCallID, ElicitToolName))
+		emit(returnChan, events.NewToolCallArgsEvent(toolCallID, string(args)))
+		emit(returnChan, events.NewToolCallEndEvent(toolCallID))
+
+		select {
+		case answer := <-answers:
+			response, err := parseElicitAnswer(answer)
+			if err != nil {
+				return nil, err
+			}
+			if response.Action == mcpgo.ElicitationResponseActionAccept {
+				if err := validator.Validate(response.Content); err != nil {
+					return nil, fmt.Errorf("answer does not match the requested schema: %w", err)
+				}
+			}
+			return &mcpgo.ElicitationResult{ElicitationResponse: response}, nil
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for answer to %s: %w", toolCallID, ctx.Err())
+		}
+	}
+}
+
+// parseElicitAnswer reads the client's answer, see ElicitToolName
+func parseElicitAnswer(answer string) (mcpgo.ElicitationResponse, error) {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(answer), &fields); err != nil {
+		return mcpgo.ElicitationResponse{}, fmt.Errorf("answer must be a JSON object: %w", err)
+	}
+
+	action, ok := fields["action"].(string)
+	if !ok {
+		return mcpgo.ElicitationResponse{Action: mcpgo.ElicitationResponseActionAccept, Content: fields}, nil
+	}
+	switch response := mcpgo.ElicitationResponseAction(action); response {
+	case mcpgo.ElicitationResponseActionAccept:
+		return mcpgo.ElicitationResponse{Action: response, Content: fields["content"]}, nil
+	case mcpgo.ElicitationResponseActionDecline, mcpgo.ElicitationResponseActionCancel:
+		return mcpgo.ElicitationResponse{Action: response}, nil
+	default:
+		return mcpgo.ElicitationResponse{}, fmt.Errorf("unknown answer action '%s', expected accept, decline or cancel", action)
+	}
+}
+
+// sampler answers sampling requests with the run's model
+func sampler(llm llms.Model, model string) mcp.SampleFunc {
+	return func(ctx context.Context, request mcpgo.CreateMessageRequest) (*mcpgo.CreateMessageResult, error) {
+		var messages []llms.MessageContent
+		if request.SystemPrompt != "" {
+			messages = append(messages, llms.TextParts(llms.ChatMessageTypeSystem, request.SystemPrompt))
+		}
+		for _, message := range request.Messages {
+			text, ok := message.Content.(mcpgo.TextContent)
+			if !ok {
+				return nil, fmt.Errorf("sampling supports text content only, got %T", message.Content)
+			}
+			role := llms.ChatMessageTypeHuman
+			if message.Role == mcpgo.RoleAssistant {
+				role = llms.ChatMessageTypeAI
+			}
+			messages = append(messages, llms.TextParts(role, text.Text))
+		}
+
+		var options []llms.CallOption
+		if request.MaxTokens > 0 {
+			options = append(options, llms.WithMaxTokens(request.MaxTokens))
+		}
+		if request.Temperature > 0 {
+			options = append(options, llms.WithTemperature(request.Temperature))
+		}
+		if len(request.StopSequences) > 0 {
+			options = append(options, llms.WithStopWords(request.StopSequences))
+		}
+
+		response, err := llm.GenerateContent(ctx, messages, options...)
+		if err != nil {
+			return nil, fmt.Errorf("generate content: %w", err)
+		}
+		if len(response.Choices) == 0 {
+			return nil, errors.New("model returned no content")
+		}
+		choice := response.Choices[0]
+
+		return &mcpgo.CreateMessageResult{
+			SamplingMessage: mcpgo.SamplingMessage{
+				Role:    mcpgo.RoleAssistant,
+				Content: mcpgo.NewTextContent(choice.Content),
+			},
+			Model:      model,
+			StopReason: stopReason(choice.StopReason),
+		}, nil
+	}
+}
+
+// stopReason maps Anthropic stop reasons to their MCP names
+func stopReason(reason string) string {
+	switch reason {
+	case "end_turn":
+		return "endTurn"
+	case "max_tokens":
+		return "maxTokens"
+	case "stop_sequence":
+		return "stopSequence"
+	default:
+		return reason
+	}
+}
+
+// toObject converts a decoded JSON object of any Go type to a map
+func toObject(value any) (map[string]any, error) {
+	if object, ok := value.(map[string]any); ok {
+		return object, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]any
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// emit sends event to the client
+func emit(returnChan chan<- string, event events.Event) {
+	if jsonData, err := event.ToJSON(); err == nil {
+		returnChan <- string(jsonData)
+	}
+}
@@ -0,0 +1,55 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElicitor(t *testing.T) {
+	interactions := NewInteractions()
+	returnChan := make(chan string, 3)
+	elicit := elicitor(interactions, returnChan)
+
+	var request mcpgo.ElicitationRequest
+	request.Params.Message = "How many?"
+	request.Params.RequestedSchema = map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"count": map[string]any{"type": "integer"}},
+		"required":   []string{"count"},
+	}
+
+	// answer reads the forwarded tool call and replies to it
+	answer := func(reply string) {
+		var start struct {
+			ToolCallID   string `json:"toolCallId"`
+			ToolCallName string `json:"toolCallName"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(<-returnChan), &start))
+		assert.Equal(t, ElicitToolName, start.ToolCallName)
+		assert.Contains(t, <-returnChan, `How many?`)
+		<-returnChan
+		assert.True(t, interactions.Resolve(start.ToolCallID, reply))
+	}
+
+	go answer(`{"count": 3}`)
+	result, err := elicit(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, mcpgo.ElicitationResponseActionAccept, result.Action)
+	assert.Equal(t, map[string]any{"count": float64(3)}, result.Content)
+
+	go answer(`{"action": "decline"}`)
+	result, err = elicit(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, mcpgo.ElicitationResponseActionDecline, result.Action)
+
+	go answer(`{"action": "accept", "content": {"count": "three"}}`)
+	_, err = elicit(context.Background(), request)
+	assert.ErrorContains(t, err, "does not match the requested schema")
+
+	assert.False(t, interactions.Resolve("unknown", `{}`))
+}
@@ -0,0 +1,47 @@
+package agentic
+
+import (
+	"context"
+	"slices"
+	"strconv"
+
+	"github.com/tmc/langchaingo/llms"
+	langchaingoTools "github.com/tmc/langchaingo/tools"
+)
+
+// promptPrefix is the agent's default prompt prefix with systemPrompt in
+// front. The prompt is a Go template, so systemPrompt is quoted as a literal.
+func promptPrefix(systemPrompt string) string {
+	return "{{" + strconv.Quote(systemPrompt) + "}}\n\n" +
+		"Today is {{.today}}.\n" +
+		"Answer the following questions as best you can. You have access to the following tools:\n\n" +
+		"{{.tool_descriptions}}"
+}
+
+// allowTools returns the tools named in allowed, or every tool when allowed is empty
+func allowTools(tools []langchaingoTools.Tool, allowed []string) []langchaingoTools.Tool {
+	if len(allowed) == 0 {
+		return tools
+	}
+	return slices.DeleteFunc(slices.Clone(tools), func(tool langchaingoTools.Tool) bool {
+		return !slices.Contains(allowed, tool.Name())
+	})
+}
+
+// withTemperature returns llm sampling at temperature on every call
+func withTemperature(llm llms.Model, temperature float64) llms.Model {
+	return &temperatureModel{Model: llm, temperature: temperature}
+}
+
+type temperatureModel struct {
+	llms.Model
+	temperature float64
+}
+
+func (m *temperatureModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	return m.Model.GenerateContent(ctx, messages, append(options, llms.WithTemperature(m.temperature))...)
+}
+
+func (m *temperatureModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
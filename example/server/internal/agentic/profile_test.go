@@ -0,0 +1,18 @@
+package agentic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/prompts"
+)
+
+func TestPromptPrefix(t *testing.T) {
+	template := prompts.NewPromptTemplate(promptPrefix("Answer in {{.language}}, \"briefly\"."), []string{"today", "tool_descriptions"})
+	prompt, err := template.Format(map[string]any{"today": "Monday", "tool_descriptions": "echo: Echo the input"})
+	require.NoError(t, err)
+	require.Equal(t, "Answer in {{.language}}, \"briefly\".\n\n"+
+		"Today is Monday.\n"+
+		"Answer the following questions as best you can. You have access to the following tools:\n\n"+
+		"echo: Echo the input", prompt)
+}
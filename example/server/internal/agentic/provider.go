@@ -0,0 +1,87 @@
+package agentic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	anthropicAPIKeyEnv  = "ANTHROPIC_API_KEY"
+	anthropicBaseURLEnv = "ANTHROPIC_BASE_URL"
+	anthropicBaseURL    = "https://api.anthropic.com/v1"
+	anthropicVersion    = "2023-06-01"
+
+	// providerCheckTTL keeps readiness probes from hitting the provider on every request
+	providerCheckTTL = 30 * time.Second
+)
+
+var providerCheck struct {
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+}
+
+// CheckProvider reports whether the LLM provider is configured and reachable.
+// Results are cached for providerCheckTTL.
+func CheckProvider(ctx context.Context) error {
+	providerCheck.mu.Lock()
+	defer providerCheck.mu.Unlock()
+
+	if !providerCheck.checked.IsZero() && time.Since(providerCheck.checked) < providerCheckTTL {
+		return providerCheck.err
+	}
+
+	err := checkAnthropic(ctx)
+	// Don't cache a probe that was cut short by the caller
+	if ctx.Err() == nil {
+		providerCheck.checked = time.Now()
+		providerCheck.err = err
+	}
+	return err
+}
+
+// checkAnthropic lists the available models, which needs a valid key but no tokens
+func checkAnthropic(ctx context.Context) error {
+	baseURL, apiKey, err := anthropicAPI()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/models?limit=1", nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("x-api-key", apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("reach anthropic: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("anthropic returned %s", resp.Status)
+	}
+	return nil
+}
+
+// anthropicAPI returns the Anthropic API base URL and key from the environment
+func anthropicAPI() (string, string, error) {
+	apiKey := os.Getenv(anthropicAPIKeyEnv)
+	if apiKey == "" {
+		return "", "", errors.New(anthropicAPIKeyEnv + " is not set")
+	}
+
+	baseURL := anthropicBaseURL
+	if v := os.Getenv(anthropicBaseURLEnv); v != "" {
+		baseURL = strings.TrimSuffix(v, "/")
+	}
+	return baseURL, apiKey, nil
+}
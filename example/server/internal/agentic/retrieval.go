@@ -0,0 +1,117 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/mcp"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/rag"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// RetrievalToolName is the built-in tool searching Options.Documents
+const RetrievalToolName = "search_documents"
+
+// Number of documents a search returns, the agent may ask for up to maxRetrievalResults
+const (
+	defaultRetrievalResults = 4
+	maxRetrievalResults     = 10
+)
+
+// RetrievalResult is the TOOL_CALL_RESULT content of a document search
+type RetrievalResult struct {
+	Query     string     `json:"query"`
+	Documents []Citation `json:"documents"`
+}
+
+// Citation is a document found by a search. The agent cites it as [Index].
+type Citation struct {
+	Index   int     `json:"index"`
+	Source  string  `json:"source"`
+	Title   string  `json:"title,omitempty"`
+	Section string  `json:"section,omitempty"`
+	Score   float32 `json:"score"`
+	Content string  `json:"content"`
+}
+
+// citations numbers docs from 1
+func citations(docs []schema.Document) []Citation {
+	cited := make([]Citation, len(docs))
+	for i, doc := range docs {
+		metadata := func(key string) string {
+			value, _ := doc.Metadata[key].(string)
+			return value
+		}
+		cited[i] = Citation{
+			Index:   i + 1,
+			Source:  metadata(rag.MetaSource),
+			Title:   metadata(rag.MetaTitle),
+			Section: metadata(rag.MetaSection),
+			Score:   doc.Score,
+			Content: doc.PageContent,
+		}
+	}
+	return cited
+}
+
+// retrievalTool searches an indexed document directory. The handler reports
+// the search as the tool call's result through the retriever callbacks.
+type retrievalTool struct {
+	store   *rag.Store
+	handler *Handler
+}
+
+func (t *retrievalTool) Name() string { return RetrievalToolName }
+
+func (t *retrievalTool) Description() string {
+	return `Search the documentation for passages relevant to a question. ` +
+		`Input is the search text, or {"query": "...", "limit": 4} to return up to 10 passages. ` +
+		`Cite the passages you use as [n] with their source.`
+}
+
+func (t *retrievalTool) Call(ctx context.Context, input string) (string, error) {
+	query, limit := strings.TrimSpace(input), defaultRetrievalResults
+	var args struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+	if json.Unmarshal([]byte(input), &args) == nil && args.Query != "" {
+		query = args.Query
+		if args.Limit > 0 {
+			limit = min(args.Limit, maxRetrievalResults)
+		}
+	}
+	if query == "" {
+		return t.fail(ctx, mcp.ToolErrorInvalidInput, "the search text is empty"), nil
+	}
+
+	retriever := vectorstores.ToRetriever(t.store, limit)
+	retriever.CallbacksHandler = t.handler
+	docs, err := retriever.GetRelevantDocuments(ctx, query)
+	if err != nil {
+		return t.fail(ctx, mcp.ToolErrorUnavailable, "the search failed: "+err.Error()), nil
+	}
+	if len(docs) == 0 {
+		return "No documents matched the search.", nil
+	}
+
+	var out strings.Builder
+	for _, c := range citations(docs) {
+		fmt.Fprintf(&out, "[%d] %s", c.Index, c.Source)
+		if c.Section != "" {
+			fmt.Fprintf(&out, " (%s)", c.Section)
+		}
+		fmt.Fprintf(&out, "\n%s\n\n", c.Content)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// fail reports the call as failed with a tool error and returns its text for the agent
+func (t *retrievalTool) fail(ctx context.Context, code mcp.ToolErrorCode, message string) string {
+	result := (&mcp.ToolError{Code: code, Message: message}).Result()
+	t.handler.HandleToolResult(ctx, t.Name(), result)
+	return mcp.ResultText(result)
+}
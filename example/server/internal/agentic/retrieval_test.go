@@ -0,0 +1,57 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/rag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+)
+
+func TestRetrievalTool(t *testing.T) {
+	ctx := context.Background()
+	store := rag.NewStore(rag.HashEmbedder{})
+	_, err := store.AddDocuments(ctx, []schema.Document{
+		{PageContent: "Go ships a single static binary.", Metadata: map[string]any{rag.MetaSource: "go.md", rag.MetaSection: "Deploying"}},
+		{PageContent: "Python notebooks suit data science.", Metadata: map[string]any{rag.MetaSource: "python.md"}},
+	})
+	require.NoError(t, err)
+
+	returnChan := make(chan string, 10)
+	handler := NewHandler(returnChan)
+	tool := &retrievalTool{store: store, handler: handler}
+
+	handler.HandleAgentAction(ctx, schema.AgentAction{Tool: RetrievalToolName, ToolInput: `{"query": "static binary", "limit": 1}`})
+	output, err := tool.Call(ctx, `{"query": "static binary", "limit": 1}`)
+	require.NoError(t, err)
+	assert.Equal(t, "[1] go.md (Deploying)\nGo ships a single static binary.", output)
+
+	// TOOL_CALL_START and _ARGS come from the action, then the search's end and result
+	<-returnChan
+	<-returnChan
+	assert.Contains(t, <-returnChan, "TOOL_CALL_END")
+	var event struct {
+		Type    string `json:"type"`
+		Content string `json:"content"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(<-returnChan), &event))
+	assert.Equal(t, "TOOL_CALL_RESULT", event.Type)
+	var result RetrievalResult
+	require.NoError(t, json.Unmarshal([]byte(event.Content), &result))
+	assert.Equal(t, "static binary", result.Query)
+	require.Len(t, result.Documents, 1)
+	assert.Equal(t, 1, result.Documents[0].Index)
+	assert.Equal(t, "go.md", result.Documents[0].Source)
+	assert.Equal(t, "Deploying", result.Documents[0].Section)
+
+	// Searches outside an agent action start their own tool call
+	_, err = tool.Call(ctx, "notebooks")
+	require.NoError(t, err)
+	assert.Contains(t, <-returnChan, `"toolCallName":"search_documents"`)
+	assert.Contains(t, <-returnChan, `notebooks`)
+	assert.Contains(t, <-returnChan, "TOOL_CALL_END")
+	assert.Contains(t, <-returnChan, "python.md")
+}
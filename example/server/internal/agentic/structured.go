@@ -0,0 +1,108 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/schema"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// StructuredOutputEvent names the CUSTOM event sent after the final answer of
+// a run with an output schema. Its value is the answer as an object matching
+// the schema.
+const StructuredOutputEvent = "structured_output"
+
+// ErrOutputSchema is returned when the answer still does not match the
+// agent's output schema after the retries, the run's text answer was sent
+var ErrOutputSchema = errors.New("answer does not match the output schema")
+
+// respondToolName is the tool the model is made to call with the structured answer
+const respondToolName = "respond"
+
+// outputHint tells the agent which data its final answer has to contain
+const outputHint = "Your final answer will be converted to JSON matching this schema, so make sure it contains everything the schema asks for:\n"
+
+// messagesModel sends prompts to the Messages API directly, the anthropic
+// provider does not pass the tool choice on
+type messagesModel struct {
+	model string
+}
+
+func (m *messagesModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	var opts llms.CallOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	return createMessage(ctx, m.model, messages, opts)
+}
+
+func (m *messagesModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// structureAnswer has llm call the respond tool with answer as an object
+// matching outputSchema. Replies that do not match are sent back with the
+// validation errors, up to retries times.
+func structureAnswer(ctx context.Context, llm llms.Model, answer string, outputSchema map[string]any, retries int) (any, error) {
+	validator, err := schema.Compile("output", outputSchema)
+	if err != nil {
+		return nil, fmt.Errorf("output schema: %w", err)
+	}
+	options := []llms.CallOption{
+		llms.WithTools([]llms.Tool{{
+			Type: "function",
+			Function: &llms.FunctionDefinition{
+				Name:        respondToolName,
+				Description: "Respond with the answer as structured data.",
+				Parameters:  outputSchema,
+			},
+		}}),
+		llms.WithToolChoice(llms.ToolChoice{Type: "function", Function: &llms.FunctionReference{Name: respondToolName}}),
+	}
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman,
+		"Call the "+respondToolName+" tool with the following answer as its input, leave nothing out and add nothing:\n\n"+answer)}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := llm.GenerateContent(ctx, messages, options...)
+		if err != nil {
+			return nil, fmt.Errorf("structure answer: %w", err)
+		}
+		data := structuredData(resp)
+		if err = validator.ValidateJSON([]byte(data)); err == nil {
+			var value any
+			return value, json.Unmarshal([]byte(data), &value)
+		}
+		if attempt >= retries {
+			return nil, fmt.Errorf("%w after %d attempts: %v", ErrOutputSchema, attempt+1, err)
+		}
+		messages = append(messages,
+			llms.TextParts(llms.ChatMessageTypeAI, data),
+			llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf("That input does not match the schema: %v\nCall %s again with the corrected input.", err, respondToolName)))
+	}
+}
+
+// structuredData returns the input of the model's respond call, or the JSON
+// object in its text when it answered without calling the tool
+func structuredData(resp *llms.ContentResponse) string {
+	for _, choice := range resp.Choices {
+		for _, call := range choice.ToolCalls {
+			if call.FunctionCall != nil && call.FunctionCall.Name == respondToolName {
+				return call.FunctionCall.Arguments
+			}
+		}
+	}
+	if len(resp.Choices) == 0 {
+		return ""
+	}
+
+	text := resp.Choices[0].Content
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return strings.TrimSpace(text)
+	}
+	return text[start : end+1]
+}
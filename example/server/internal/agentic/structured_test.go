@@ -0,0 +1,93 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// respondModel calls the respond tool with its inputs in order and keeps the prompts
+type respondModel struct {
+	inputs   []string
+	messages [][]llms.MessageContent
+}
+
+func (m *respondModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.messages = append(m.messages, messages)
+	input := m.inputs[0]
+	m.inputs = m.inputs[1:]
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{ToolCalls: []llms.ToolCall{{
+		ID:           "call-1",
+		Type:         "function",
+		FunctionCall: &llms.FunctionCall{Name: respondToolName, Arguments: input},
+	}}}}}, nil
+}
+
+func (m *respondModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+var weatherSchema = map[string]any{
+	"type":     "object",
+	"required": []any{"city", "celsius"},
+	"properties": map[string]any{
+		"city":    map[string]any{"type": "string"},
+		"celsius": map[string]any{"type": "number"},
+	},
+}
+
+func TestStructureAnswer(t *testing.T) {
+	llm := &respondModel{inputs: []string{`{"city": "Paris", "celsius": "warm"}`, `{"city": "Paris", "celsius": 21}`}}
+	value, err := structureAnswer(context.Background(), llm, "It is 21 degrees in Paris", weatherSchema, 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"city": "Paris", "celsius": float64(21)}, value)
+
+	// The invalid input is sent back with what is wrong with it
+	require.Len(t, llm.messages, 2)
+	retry := llm.messages[1]
+	require.Len(t, retry, 3)
+	assert.Equal(t, llms.ChatMessageTypeAI, retry[1].Role)
+	assert.Contains(t, retry[2].Parts[0].(llms.TextContent).Text, "celsius: ")
+
+	llm = &respondModel{inputs: []string{`{"city": "Paris"}`, `{"city": "Paris"}`}}
+	_, err = structureAnswer(context.Background(), llm, "It is warm in Paris", weatherSchema, 1)
+	assert.ErrorIs(t, err, ErrOutputSchema)
+	assert.ErrorContains(t, err, "answer does not match the output schema after 2 attempts")
+}
+
+func TestStructuredData(t *testing.T) {
+	text := func(content string) *llms.ContentResponse {
+		return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: content}}}
+	}
+	assert.Equal(t, `{"a": 1}`, structuredData(text("Here it is:\n```json\n{\"a\": 1}\n```")))
+	assert.Equal(t, "no json", structuredData(text(" no json ")))
+	assert.Empty(t, structuredData(&llms.ContentResponse{}))
+}
+
+func TestMessagesModelForcesTool(t *testing.T) {
+	var request map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content": [{"type": "tool_use", "id": "toolu_1", "name": "respond", "input": {"city": "Oslo", "celsius": 3}}]}`))
+	}))
+	defer server.Close()
+	t.Setenv(anthropicAPIKeyEnv, "test-key")
+	t.Setenv(anthropicBaseURLEnv, server.URL)
+
+	value, err := structureAnswer(context.Background(), &messagesModel{model: "claude-test"}, "3 degrees in Oslo", weatherSchema, 0)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"city": "Oslo", "celsius": float64(3)}, value)
+
+	assert.Equal(t, map[string]any{"type": "tool", "name": "respond"}, request["tool_choice"])
+	tools := request["tools"].([]any)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "respond", tools[0].(map[string]any)["name"])
+	assert.Equal(t, "object", tools[0].(map[string]any)["input_schema"].(map[string]any)["type"])
+}
@@ -0,0 +1,114 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/mcp"
+	"github.com/tmc/langchaingo/chains"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/anthropic"
+	langchaingoTools "github.com/tmc/langchaingo/tools"
+)
+
+// SubAgent is an agent the run's agent delegates tasks to, offered to it as a
+// tool named after the sub-agent. Approvals, elicitations, timeouts and the
+// cassette are shared with the run.
+type SubAgent struct {
+	Name string
+	// Description tells the delegating agent what the sub-agent does
+	Description string
+	// Model is the Anthropic model name
+	Model string
+	// SystemPrompt is put in front of the sub-agent's instructions
+	SystemPrompt string
+	// Tools limits the sub-agent to the named tools, empty allows every tool
+	Tools []string
+	// MaxIterations bounds the sub-agent's reasoning steps, 0 uses config.DefaultMaxIterations
+	MaxIterations int
+	// Temperature overrides the model's sampling temperature
+	Temperature *float64
+}
+
+// subAgentTool runs a SubAgent on the task given as its input. The
+// sub-agent's events are sent by a child of handler, see SubAgentEvent.
+type subAgentTool struct {
+	agent       SubAgent
+	llm         llms.Model
+	tools       []langchaingoTools.Tool
+	opts        Options
+	parallelism int
+	handler     *Handler
+}
+
+// newSubAgentTool returns the tool delegating to agent, which picks its
+// tools from the run's
+func newSubAgentTool(agent SubAgent, tools []langchaingoTools.Tool, opts Options, parallelism int, handler *Handler) (*subAgentTool, error) {
+	var llm llms.Model
+	if !opts.Cassette.Replaying() {
+		var err error
+		if llm, err = anthropic.New(anthropic.WithModel(agent.Model)); err != nil {
+			return nil, fmt.Errorf("failed to create LLM client for agent %s: %w", agent.Name, err)
+		}
+	}
+	llm = opts.Cassette.Model(llm)
+	if agent.Temperature != nil {
+		llm = withTemperature(llm, *agent.Temperature)
+	}
+
+	return &subAgentTool{
+		agent:       agent,
+		llm:         llm,
+		tools:       slices.Clip(allowTools(tools, agent.Tools)),
+		opts:        opts,
+		parallelism: parallelism,
+		handler:     handler,
+	}, nil
+}
+
+func (t *subAgentTool) Name() string {
+	return t.agent.Name
+}
+
+func (t *subAgentTool) Description() string {
+	description := t.agent.Description
+	if description == "" {
+		description = "The " + t.agent.Name + " agent."
+	}
+	return description + " Delegate a task to it by describing the task in plain text as the input, it answers with its result."
+}
+
+// Call runs the sub-agent on the task in input and returns its answer, which
+// also ends the call that delegated the task. Failures are returned as the
+// answer so the delegating agent can try something else.
+func (t *subAgentTool) Call(ctx context.Context, input string) (string, error) {
+	var request struct {
+		Task string `json:"task"`
+	}
+	task := input
+	if err := json.Unmarshal([]byte(input), &request); err == nil && request.Task != "" {
+		task = request.Task
+	}
+
+	handler := t.handler.child(t.agent.Name, t.handler.callID(ctx))
+	tools := gateTools(withRetrieval(t.tools, t.opts.Documents, t.agent.Tools, handler), t.opts, handler)
+	executor := newAgentExecutor(t.llm, tools, t.agent.SystemPrompt, t.agent.MaxIterations, t.parallelism, handler)
+
+	agentCtx := mcp.WithToolResultHandler(ctx, handler.HandleToolResult)
+	agentCtx = mcp.WithProgressHandler(agentCtx, handler.HandleToolProgress)
+	outputs, err := chains.Call(agentCtx, executor, map[string]any{"input": task + "\n" + reminder})
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
+	answer, _ := outputs["output"].(string)
+	answer = strings.TrimSpace(answer)
+	if err != nil {
+		answer = fmt.Sprintf("agent %s could not finish the task: %v", t.agent.Name, err)
+	}
+	t.handler.finishToolCall(ctx, answer)
+	return answer, nil
+}
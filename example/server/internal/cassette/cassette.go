@@ -0,0 +1,371 @@
+// Package cassette records the LLM requests and MCP exchanges of an agent run
+// to a file and serves them back, so runs can be replayed offline with the
+// same event sequence.
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/mcp"
+	"github.com/tmc/langchaingo/llms"
+	langchaingoTools "github.com/tmc/langchaingo/tools"
+)
+
+// Mode selects whether a run is recorded or replayed
+type Mode string
+
+const (
+	ModeRecord Mode = "record"
+	ModeReplay Mode = "replay"
+)
+
+// Kind is the kind of exchange an Interaction holds
+type Kind string
+
+const (
+	KindLLM      Kind = "llm"
+	KindTool     Kind = "tool"
+	KindPrompt   Kind = "prompt"
+	KindResource Kind = "resource"
+)
+
+// Interaction is one exchange with the model or an MCP server
+type Interaction struct {
+	Kind Kind `json:"kind"`
+	// Name is the tool or prompt name, or the resource URI
+	Name string `json:"name,omitempty"`
+	// Request is the prompt sent to the model, the tool input or the prompt arguments
+	Request string `json:"request,omitempty"`
+	// Response is the model's completion, the tool observation or the prompt or resource text
+	Response string `json:"response"`
+	// Result is the MCP result of a tool call, sent to the run's
+	// ToolResultHandler, or the tool calls of the model's completion
+	Result json.RawMessage `json:"result,omitempty"`
+	// Error is set when the exchange failed
+	Error string `json:"error,omitempty"`
+}
+
+// ToolInfo describes a tool that was offered to the agent
+type ToolInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Cassette holds the recording of a single agent run. Replays serve the
+// interactions in the order they were recorded and fail when the run asks for
+// something else, except that model prompts are not compared because they
+// contain the date.
+type Cassette struct {
+	Tools        []ToolInfo    `json:"tools"`
+	Interactions []Interaction `json:"interactions"`
+
+	path string
+	mode Mode
+
+	mu   sync.Mutex
+	next int
+}
+
+// Open prepares the cassette at path for one run. Replays load the recording,
+// recordings start empty and are written by Close. An empty mode returns nil,
+// which every method accepts and passes through.
+func Open(path string, mode Mode) (*Cassette, error) {
+	switch mode {
+	case "":
+		return nil, nil
+	case ModeRecord:
+		return &Cassette{path: path, mode: mode}, nil
+	case ModeReplay:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read cassette: %w", err)
+		}
+		c := &Cassette{path: path, mode: mode}
+		if err := json.Unmarshal(data, c); err != nil {
+			return nil, fmt.Errorf("parse cassette %s: %w", path, err)
+		}
+		return c, nil
+	default:
+		return nil, fmt.Errorf("unknown cassette mode '%s', must be one of: record, replay", mode)
+	}
+}
+
+// Replaying reports whether the run is served from the recording
+func (c *Cassette) Replaying() bool {
+	return c != nil && c.mode == ModeReplay
+}
+
+// Close writes a recording to its file
+func (c *Cassette) Close() error {
+	if c == nil || c.mode != ModeRecord {
+		return nil
+	}
+
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("encode cassette: %w", err)
+	}
+	if err := os.WriteFile(c.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	return nil
+}
+
+func (c *Cassette) record(interaction Interaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Interactions = append(c.Interactions, interaction)
+}
+
+// play returns the next recorded interaction, which must match the request
+func (c *Cassette) play(kind Kind, name, request string) (Interaction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.next >= len(c.Interactions) {
+		return Interaction{}, fmt.Errorf("cassette %s has no interaction left for %s %s", c.path, kind, name)
+	}
+	// Tools called in one turn run concurrently and are recorded as they
+	// finish, so any tool call of the current run of them may come first
+	if kind == KindTool {
+		for i := c.next; i < len(c.Interactions) && c.Interactions[i].Kind == KindTool; i++ {
+			if c.Interactions[i].Name == name && c.Interactions[i].Request == request {
+				c.Interactions[c.next], c.Interactions[i] = c.Interactions[i], c.Interactions[c.next]
+				break
+			}
+		}
+	}
+	interaction := c.Interactions[c.next]
+	c.next++
+
+	if interaction.Kind != kind || interaction.Name != name {
+		return Interaction{}, fmt.Errorf("cassette %s: run asked for %s %s, recording has %s %s",
+			c.path, kind, name, interaction.Kind, interaction.Name)
+	}
+	if kind != KindLLM && interaction.Request != request {
+		return Interaction{}, fmt.Errorf("cassette %s: %s %s was called with %s, recording has %s",
+			c.path, kind, name, request, interaction.Request)
+	}
+	return interaction, nil
+}
+
+// Exchange returns the result of fn, recording it, or the recorded result when replaying
+func (c *Cassette) Exchange(kind Kind, name, request string, fn func() (string, error)) (string, error) {
+	if c == nil {
+		return fn()
+	}
+	if c.Replaying() {
+		interaction, err := c.play(kind, name, request)
+		if err != nil {
+			return "", err
+		}
+		if interaction.Error != "" {
+			return "", errors.New(interaction.Error)
+		}
+		return interaction.Response, nil
+	}
+
+	response, err := fn()
+	interaction := Interaction{Kind: kind, Name: name, Request: request, Response: response}
+	if err != nil {
+		interaction.Error = err.Error()
+	}
+	c.record(interaction)
+	return response, err
+}
+
+// Model returns llm recording its completions, or a model answering from the
+// recording when replaying, in which case llm may be nil
+func (c *Cassette) Model(llm llms.Model) llms.Model {
+	if c == nil {
+		return llm
+	}
+	return &model{cassette: c, llm: llm}
+}
+
+type model struct {
+	cassette *Cassette
+	llm      llms.Model
+}
+
+func (m *model) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	var opts llms.CallOptions
+	for _, option := range options {
+		option(&opts)
+	}
+
+	if !m.cassette.Replaying() {
+		return m.record(ctx, messages, options...)
+	}
+
+	interaction, err := m.cassette.play(KindLLM, "", "")
+	if err != nil {
+		return nil, err
+	}
+	if interaction.Error != "" {
+		return nil, errors.New(interaction.Error)
+	}
+	choice := &llms.ContentChoice{Content: interaction.Response}
+	if interaction.Result != nil {
+		var calls []toolCall
+		if err := json.Unmarshal(interaction.Result, &calls); err != nil {
+			return nil, fmt.Errorf("cassette tool calls: %w", err)
+		}
+		for _, call := range calls {
+			choice.ToolCalls = append(choice.ToolCalls, llms.ToolCall{
+				ID:           call.ID,
+				Type:         "function",
+				FunctionCall: &llms.FunctionCall{Name: call.Name, Arguments: call.Arguments},
+			})
+		}
+	}
+
+	// Replayed completions are streamed in one chunk
+	if opts.StreamingFunc != nil {
+		if err := opts.StreamingFunc(ctx, []byte(choice.Content)); err != nil {
+			return nil, err
+		}
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{choice}}, nil
+}
+
+// toolCall is the recording of a tool call the model made, llms.ToolCall
+// does not decode the JSON it encodes to
+type toolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// record keeps the completion's first choice and the tool calls of every choice
+func (m *model) record(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	resp, err := m.llm.GenerateContent(ctx, messages, options...)
+	interaction := Interaction{Kind: KindLLM, Request: promptText(messages)}
+	if err != nil {
+		interaction.Error = err.Error()
+	} else {
+		var toolCalls []toolCall
+		for i, choice := range resp.Choices {
+			if i == 0 {
+				interaction.Response = choice.Content
+			}
+			for _, call := range choice.ToolCalls {
+				if call.FunctionCall != nil {
+					toolCalls = append(toolCalls, toolCall{ID: call.ID, Name: call.FunctionCall.Name, Arguments: call.FunctionCall.Arguments})
+				}
+			}
+		}
+		if len(toolCalls) > 0 {
+			if interaction.Result, err = json.Marshal(toolCalls); err != nil {
+				return nil, fmt.Errorf("encode tool calls: %w", err)
+			}
+		}
+	}
+	m.cassette.record(interaction)
+	return resp, err
+}
+
+func (m *model) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// promptText renders the text of messages, prefixed with their roles
+func promptText(messages []llms.MessageContent) string {
+	var parts []string
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				parts = append(parts, fmt.Sprintf("%s: %s", message.Role, text.Text))
+			}
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// WrapTools returns tools recording their calls, or when replaying stand-ins
+// for the recorded tools, in which case tools is ignored
+func (c *Cassette) WrapTools(tools []langchaingoTools.Tool) []langchaingoTools.Tool {
+	if c == nil {
+		return tools
+	}
+
+	var wrapped []langchaingoTools.Tool
+	if c.Replaying() {
+		for _, info := range c.Tools {
+			wrapped = append(wrapped, &tool{cassette: c, name: info.Name, description: info.Description})
+		}
+		return wrapped
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, t := range tools {
+		c.Tools = append(c.Tools, ToolInfo{Name: t.Name(), Description: t.Description()})
+		wrapped = append(wrapped, &tool{cassette: c, name: t.Name(), description: t.Description(), tool: t})
+	}
+	return wrapped
+}
+
+// tool records the calls to a tool, or replays them when it has none
+type tool struct {
+	cassette    *Cassette
+	name        string
+	description string
+	tool        langchaingoTools.Tool
+}
+
+func (t *tool) Name() string {
+	return t.name
+}
+
+func (t *tool) Description() string {
+	return t.description
+}
+
+func (t *tool) Call(ctx context.Context, input string) (string, error) {
+	if t.tool == nil {
+		return t.replay(ctx, input)
+	}
+
+	// Capture the full result on its way to the run's handler
+	var result json.RawMessage
+	callCtx := mcp.WithToolResultHandler(ctx, func(_ context.Context, name string, r *mcpgo.CallToolResult) {
+		result, _ = json.Marshal(r)
+		mcp.ReportToolResult(ctx, name, r)
+	})
+	output, err := t.tool.Call(callCtx, input)
+
+	interaction := Interaction{Kind: KindTool, Name: t.name, Request: input, Response: output, Result: result}
+	if err != nil {
+		interaction.Error = err.Error()
+	}
+	t.cassette.record(interaction)
+	return output, err
+}
+
+func (t *tool) replay(ctx context.Context, input string) (string, error) {
+	interaction, err := t.cassette.play(KindTool, t.name, input)
+	if err != nil {
+		return "", err
+	}
+	if interaction.Result != nil {
+		result, err := mcpgo.ParseCallToolResult(&interaction.Result)
+		if err != nil {
+			return "", fmt.Errorf("cassette result of %s: %w", t.name, err)
+		}
+		mcp.ReportToolResult(ctx, t.name, result)
+	}
+	if interaction.Error != "" {
+		return "", errors.New(interaction.Error)
+	}
+	return interaction.Response, nil
+}
@@ -0,0 +1,139 @@
+package cassette
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+	langchaingoTools "github.com/tmc/langchaingo/tools"
+)
+
+// fakeModel answers every prompt with the same completion
+type fakeModel struct{ calls int }
+
+func (m *fakeModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.calls++
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "Final Answer: 42"}}}, nil
+}
+
+func (m *fakeModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// upperTool reports a structured result for its input
+type upperTool struct{}
+
+func (upperTool) Name() string        { return "upper" }
+func (upperTool) Description() string { return "Uppercase the input" }
+func (upperTool) Call(ctx context.Context, input string) (string, error) {
+	result := mcpgo.NewToolResultStructured(map[string]any{"input": input}, "done")
+	mcp.ReportToolResult(ctx, "upper", result)
+	return "done", nil
+}
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+
+	// run calls the model, the tool and a prompt, returning what the run saw
+	run := func(c *Cassette, llm llms.Model, tools []langchaingoTools.Tool) (string, string, *mcpgo.CallToolResult) {
+		completion, err := llms.GenerateFromSinglePrompt(context.Background(), c.Model(llm), "question")
+		require.NoError(t, err)
+
+		tools = c.WrapTools(tools)
+		require.Len(t, tools, 1)
+		assert.Equal(t, "Uppercase the input", tools[0].Description())
+
+		var result *mcpgo.CallToolResult
+		ctx := mcp.WithToolResultHandler(context.Background(), func(ctx context.Context, tool string, r *mcpgo.CallToolResult) {
+			result = r
+		})
+		output, err := tools[0].Call(ctx, `{"text":"hi"}`)
+		require.NoError(t, err)
+
+		prompt, err := c.Exchange(KindPrompt, "greeting", "{}", func() (string, error) { return "Hello", nil })
+		require.NoError(t, err)
+		return completion + " " + prompt, output, result
+	}
+
+	recorder, err := Open(path, ModeRecord)
+	require.NoError(t, err)
+	llm := &fakeModel{}
+	recorded, output, result := run(recorder, llm, []langchaingoTools.Tool{upperTool{}})
+	require.NoError(t, recorder.Close())
+	assert.Equal(t, "Final Answer: 42 Hello", recorded)
+
+	player, err := Open(path, ModeReplay)
+	require.NoError(t, err)
+	replayed, replayedOutput, replayedResult := run(player, nil, nil)
+	assert.Equal(t, recorded, replayed)
+	assert.Equal(t, output, replayedOutput)
+	assert.Equal(t, result.StructuredContent, replayedResult.StructuredContent)
+	assert.Equal(t, 1, llm.calls)
+
+	// A run that departs from the recording fails instead of getting wrong answers
+	_, err = player.Exchange(KindResource, "docs://README.md", "", nil)
+	assert.ErrorContains(t, err, "no interaction left")
+	player, err = Open(path, ModeReplay)
+	require.NoError(t, err)
+	_, err = player.WrapTools(nil)[0].Call(context.Background(), `{"text":"hi"}`)
+	assert.ErrorContains(t, err, "run asked for tool upper, recording has llm")
+}
+
+func TestReplayConcurrentTools(t *testing.T) {
+	player := &Cassette{mode: ModeReplay, path: "run.json", Interactions: []Interaction{
+		{Kind: KindTool, Name: "a", Request: "1", Response: "a1"},
+		{Kind: KindTool, Name: "b", Request: "2", Response: "b2"},
+		{Kind: KindLLM, Response: "Final Answer: done"},
+	}}
+	exchange := func(kind Kind, name, request string) (string, error) {
+		return player.Exchange(kind, name, request, nil)
+	}
+
+	// Calls of one turn may finish in another order than they were recorded
+	output, err := exchange(KindTool, "b", "2")
+	require.NoError(t, err)
+	assert.Equal(t, "b2", output)
+	output, err = exchange(KindTool, "a", "1")
+	require.NoError(t, err)
+	assert.Equal(t, "a1", output)
+
+	_, err = exchange(KindTool, "a", "1")
+	assert.ErrorContains(t, err, "run asked for tool a, recording has llm")
+}
+
+// toolCallModel answers by calling a tool
+type toolCallModel struct{}
+
+func (toolCallModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{ToolCalls: []llms.ToolCall{{
+		ID:           "call-1",
+		Type:         "function",
+		FunctionCall: &llms.FunctionCall{Name: "respond", Arguments: `{"answer":42}`},
+	}}}}}, nil
+}
+
+func (m toolCallModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func TestReplayToolCalls(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	prompt := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "question")}
+
+	recorder, err := Open(path, ModeRecord)
+	require.NoError(t, err)
+	recorded, err := recorder.Model(toolCallModel{}).GenerateContent(context.Background(), prompt)
+	require.NoError(t, err)
+	require.NoError(t, recorder.Close())
+
+	player, err := Open(path, ModeReplay)
+	require.NoError(t, err)
+	replayed, err := player.Model(nil).GenerateContent(context.Background(), prompt)
+	require.NoError(t, err)
+	assert.Equal(t, recorded.Choices[0].ToolCalls, replayed.Choices[0].ToolCalls)
+}
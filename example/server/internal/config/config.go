@@ -5,14 +5,22 @@ import (
 	"flag"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/schema"
 )
 
 // Config holds all server configuration values
 type Config struct {
+	// ConfigFile is the optional YAML or TOML file the values were loaded from
+	ConfigFile string
+
 	// Server settings
 	Host string
 	Port int
@@ -28,14 +36,188 @@ type Config struct {
 	WriteTimeout time.Duration
 	SSEKeepAlive time.Duration
 
+	// ShutdownDrainDelay is how long readiness reports failing before the listener closes
+	ShutdownDrainDelay time.Duration
+	// ShutdownGracePeriod is how long in-flight agent runs may take to finish on shutdown
+	ShutdownGracePeriod time.Duration
+
 	// CORS settings
 	CORSEnabled        bool
 	CORSAllowedOrigins []string
 
 	// Streaming settings
 	StreamingChunkDelay time.Duration
+
+	// Agent settings
+	Model string
+
+	// MCP settings. The embedded server runs inside this process, MCPServers
+	// lists external servers (http(s)://, sse+http(s):// or stdio:command).
+	// MCPToolsDir, MCPPromptsDir and MCPResourcesDir hold tool manifests,
+	// prompt templates and docs served by the embedded server.
+	EmbeddedMCP     bool
+	MCPPort         int
+	MCPToolsDir     string
+	MCPPromptsDir   string
+	MCPResourcesDir string
+	MCPServers      []string
+
+	// ToolPolicies maps tool names to PolicyAuto, PolicyConfirm or PolicyDeny.
+	// The "*" entry applies to tools without their own, the default is PolicyAuto.
+	ToolPolicies map[string]string
+	// ToolTimeouts maps tool names to how long a call may take, in
+	// time.ParseDuration syntax. The "*" entry applies to tools without their
+	// own, without one tools keep the MCP client's timeout.
+	ToolTimeouts map[string]string
+	// ToolParallelism bounds the tool calls of one agent step that run at the same time
+	ToolParallelism int
+
+	// Cassette is the file agent runs are recorded to or replayed from,
+	// depending on CassetteMode ("record", "replay" or empty for neither)
+	Cassette     string
+	CassetteMode string
+
+	// PromptTemplatesDir holds the system and user prompt templates of agent
+	// runs, reloaded when its files change
+	PromptTemplatesDir string
+
+	// FilesRoot confines the embedded MCP server's read_file and list_files
+	// tools, which are only served when it is set
+	FilesRoot string
+	// FetchAllowedHosts are the hosts the fetch_url tool may contact,
+	// "*.example.com" allows the subdomains of example.com
+	FetchAllowedHosts []string
+	// FetchMirrors maps URL prefixes to local directories fetch_url reads
+	// instead of the network
+	FetchMirrors map[string]string
+	// BuiltinTools configures the embedded MCP server's built-in tools by name
+	BuiltinTools map[string]BuiltinTool
+	// CodeExecution serves the run_code tool, which runs programs in a
+	// WebAssembly sandbox. Its timeout and output size are set in BuiltinTools.
+	CodeExecution bool
+	// CodeMemoryLimit is the memory in MiB a sandboxed program may use
+	CodeMemoryLimit int
+	// CodeConcurrency bounds the programs built or run by run_code at the same time
+	CodeConcurrency int
+	// CodeRuntimes adds run_code languages by interpreter compiled to WASI,
+	// only set from the config file as their argument lists do not fit a
+	// comma separated env value
+	CodeRuntimes map[string]CodeRuntime
+
+	// DocsDir holds the Markdown and text files indexed for the search_documents
+	// tool, the tool is disabled when empty
+	DocsDir string
+	// EmbeddingProvider embeds the documents and queries, see the Embedding* constants
+	EmbeddingProvider string
+	// EmbeddingModel is the provider's embedding model, empty uses its default
+	EmbeddingModel string
+
+	// AllowedOverrides lists the run parameters clients may override through
+	// forwardedProps, see the Override* constants. None are allowed by default.
+	AllowedOverrides []string
+
+	// Agents are the named agent profiles served at /agents/:name, only set
+	// from the config file as profiles hold prompts, tool lists and schemas
+	Agents map[string]AgentProfile
 }
 
+// AgentProfile configures a named agent
+type AgentProfile struct {
+	// Description tells agents that delegate to this one what it does
+	Description string `yaml:"description" toml:"description"`
+	// SystemPrompt is put in front of the agent's instructions, rendered as a prompt template
+	SystemPrompt string `yaml:"system_prompt" toml:"system_prompt"`
+	// SystemTemplate and UserTemplate name templates in PromptTemplatesDir,
+	// rendering the system prompt after SystemPrompt and the user's input.
+	// When empty the "system" and "user" templates are used if they exist.
+	SystemTemplate string `yaml:"system_template" toml:"system_template"`
+	UserTemplate   string `yaml:"user_template" toml:"user_template"`
+	// Model overrides Config.Model
+	Model string `yaml:"model" toml:"model"`
+	// Tools lists the tools the agent may use, empty allows every tool
+	Tools []string `yaml:"tools" toml:"tools"`
+	// MaxIterations bounds the agent's reasoning steps, 0 uses DefaultMaxIterations
+	MaxIterations int `yaml:"max_iterations" toml:"max_iterations"`
+	// Temperature overrides the model's sampling temperature, between 0 and 1
+	Temperature *float64 `yaml:"temperature" toml:"temperature"`
+	// History is how earlier messages are kept, HistoryTruncate when empty
+	History string `yaml:"history" toml:"history"`
+	// HistoryTokens bounds the prompt with its history, 0 uses DefaultHistoryTokens
+	HistoryTokens int `yaml:"history_tokens" toml:"history_tokens"`
+	// HistoryTurns is the number of user turns HistorySlidingWindow keeps,
+	// 0 uses DefaultHistoryTurns
+	HistoryTurns int `yaml:"history_turns" toml:"history_turns"`
+	// SubAgents names the agents this one can delegate tasks to, each offered
+	// to it as a tool named after the agent. Sub-agents cannot delegate further.
+	SubAgents []string `yaml:"sub_agents" toml:"sub_agents"`
+	// OutputSchema is the JSON Schema of an object the agent's answer is
+	// turned into and validated against, no structured answer when empty
+	OutputSchema map[string]any `yaml:"output_schema" toml:"output_schema"`
+	// OutputRetries is how often the model is asked again for an answer that
+	// does not match OutputSchema, 0 uses DefaultOutputRetries
+	OutputRetries int `yaml:"output_retries" toml:"output_retries"`
+}
+
+// BuiltinTool configures a tool of the embedded MCP server's built-in pack
+type BuiltinTool struct {
+	// Enabled set to false stops serving the tool
+	Enabled *bool `yaml:"enabled" toml:"enabled"`
+	// Timeout bounds a call in time.ParseDuration syntax, empty uses the pack default
+	Timeout string `yaml:"timeout" toml:"timeout"`
+	// MaxOutput is the size in bytes results are truncated to, 0 uses the pack default
+	MaxOutput int `yaml:"max_output" toml:"max_output"`
+}
+
+// CodeRuntime is an interpreter compiled to a WASI module that runs the code
+// of a run_code language
+type CodeRuntime struct {
+	// Module is the path of the .wasm file
+	Module string `yaml:"module" toml:"module"`
+	// Args are passed to the interpreter, "{code}" is replaced with the code
+	Args []string `yaml:"args" toml:"args"`
+}
+
+// agentNamePattern matches the names agents can be served under
+var agentNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// Tool call policies
+const (
+	// PolicyAuto runs the tool without asking
+	PolicyAuto = "auto"
+	// PolicyConfirm pauses the run until the user approves or rejects the call
+	PolicyConfirm = "confirm"
+	// PolicyDeny never runs the tool, it is not offered to the agent
+	PolicyDeny = "deny"
+)
+
+// History policies
+const (
+	// HistoryTruncate keeps the most recent messages that fit the token budget
+	HistoryTruncate = "truncate"
+	// HistorySummarize replaces the messages that do not fit with a rolling summary
+	HistorySummarize = "summarize"
+	// HistorySlidingWindow keeps the last HistoryTurns turns that fit the token budget
+	HistorySlidingWindow = "sliding_window"
+)
+
+// Embedding providers
+const (
+	// EmbeddingHash hashes words into vectors locally, deterministic and offline
+	EmbeddingHash = "hash"
+	// EmbeddingOpenAI uses the OpenAI embeddings API with OPENAI_API_KEY
+	EmbeddingOpenAI = "openai"
+	// EmbeddingOllama uses a local Ollama server, at OLLAMA_HOST when set
+	EmbeddingOllama = "ollama"
+)
+
+// Run parameters clients may override through forwardedProps
+const (
+	OverrideAgent         = "agent"
+	OverrideModel         = "model"
+	OverrideTemperature   = "temperature"
+	OverrideMaxIterations = "max_iterations"
+)
+
 // envVar defines an environment variable handler
 type envVar struct {
 	key   string
@@ -45,6 +227,7 @@ type envVar struct {
 // getEnvHandlers builds handlers for environment variables
 func getEnvHandlers(c *Config) []envVar {
 	return []envVar{
+		{"AGUI_CONFIG", func(v string) error { c.ConfigFile = v; return nil }},
 		{"AGUI_HOST", func(v string) error { c.Host = v; return nil }},
 		{"AGUI_PORT", func(v string) error {
 			port, err := strconv.Atoi(v)
@@ -54,7 +237,203 @@ func getEnvHandlers(c *Config) []envVar {
 			c.Port = port
 			return nil
 		}},
+		{"AGUI_LOG_LEVEL", func(v string) error { c.LogLevel = strings.ToLower(v); return nil }},
+		{"AGUI_ENABLE_SSE", boolEnv("AGUI_ENABLE_SSE", &c.EnableSSE)},
+		{"AGUI_READ_TIMEOUT", durationEnv("AGUI_READ_TIMEOUT", &c.ReadTimeout)},
+		{"AGUI_WRITE_TIMEOUT", durationEnv("AGUI_WRITE_TIMEOUT", &c.WriteTimeout)},
+		{"AGUI_SSE_KEEPALIVE", durationEnv("AGUI_SSE_KEEPALIVE", &c.SSEKeepAlive)},
+		{"AGUI_SHUTDOWN_DRAIN_DELAY", durationEnv("AGUI_SHUTDOWN_DRAIN_DELAY", &c.ShutdownDrainDelay)},
+		{"AGUI_SHUTDOWN_GRACE_PERIOD", durationEnv("AGUI_SHUTDOWN_GRACE_PERIOD", &c.ShutdownGracePeriod)},
+		{"AGUI_CORS_ENABLED", boolEnv("AGUI_CORS_ENABLED", &c.CORSEnabled)},
+		{"AGUI_CORS_ALLOWED_ORIGINS", func(v string) error { c.CORSAllowedOrigins = splitList(v); return nil }},
+		{"AGUI_STREAMING_CHUNK_DELAY", durationEnv("AGUI_STREAMING_CHUNK_DELAY", &c.StreamingChunkDelay)},
+		{"AGUI_MODEL", func(v string) error { c.Model = v; return nil }},
+		{"AGUI_EMBEDDED_MCP", boolEnv("AGUI_EMBEDDED_MCP", &c.EmbeddedMCP)},
+		{"AGUI_MCP_PORT", func(v string) error {
+			port, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid AGUI_MCP_PORT value '%s': %w", v, err)
+			}
+			c.MCPPort = port
+			return nil
+		}},
+		{"AGUI_MCP_TOOLS_DIR", func(v string) error { c.MCPToolsDir = v; return nil }},
+		{"AGUI_MCP_PROMPTS_DIR", func(v string) error { c.MCPPromptsDir = v; return nil }},
+		{"AGUI_MCP_RESOURCES_DIR", func(v string) error { c.MCPResourcesDir = v; return nil }},
+		{"AGUI_PROMPT_TEMPLATES_DIR", func(v string) error { c.PromptTemplatesDir = v; return nil }},
+		{"AGUI_FILES_ROOT", func(v string) error { c.FilesRoot = v; return nil }},
+		{"AGUI_FETCH_ALLOWED_HOSTS", func(v string) error { c.FetchAllowedHosts = splitList(v); return nil }},
+		{"AGUI_FETCH_MIRRORS", func(v string) error {
+			mirrors, err := parseMirrors(v)
+			if err != nil {
+				return fmt.Errorf("invalid AGUI_FETCH_MIRRORS value '%s': %w", v, err)
+			}
+			c.FetchMirrors = mirrors
+			return nil
+		}},
+		{"AGUI_BUILTIN_TOOLS", func(v string) error {
+			tools, err := parseBuiltinTools(v)
+			if err != nil {
+				return fmt.Errorf("invalid AGUI_BUILTIN_TOOLS value '%s': %w", v, err)
+			}
+			c.BuiltinTools = tools
+			return nil
+		}},
+		{"AGUI_CODE_EXECUTION", boolEnv("AGUI_CODE_EXECUTION", &c.CodeExecution)},
+		{"AGUI_CODE_MEMORY_LIMIT", func(v string) error {
+			limit, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid AGUI_CODE_MEMORY_LIMIT value '%s': %w", v, err)
+			}
+			c.CodeMemoryLimit = limit
+			return nil
+		}},
+		{"AGUI_CODE_CONCURRENCY", func(v string) error {
+			concurrency, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid AGUI_CODE_CONCURRENCY value '%s': %w", v, err)
+			}
+			c.CodeConcurrency = concurrency
+			return nil
+		}},
+		{"AGUI_DOCS_DIR", func(v string) error { c.DocsDir = v; return nil }},
+		{"AGUI_EMBEDDING_PROVIDER", func(v string) error { c.EmbeddingProvider = strings.ToLower(v); return nil }},
+		{"AGUI_EMBEDDING_MODEL", func(v string) error { c.EmbeddingModel = v; return nil }},
+		{"AGUI_ALLOWED_OVERRIDES", func(v string) error { c.AllowedOverrides = splitList(v); return nil }},
+		{"AGUI_MCP_SERVERS", func(v string) error { c.MCPServers = splitList(v); return nil }},
+		{"AGUI_CASSETTE", func(v string) error { c.Cassette = v; return nil }},
+		{"AGUI_CASSETTE_MODE", func(v string) error { c.CassetteMode = strings.ToLower(v); return nil }},
+		{"AGUI_TOOL_POLICIES", func(v string) error {
+			policies, err := parseToolValues(v)
+			if err != nil {
+				return fmt.Errorf("invalid AGUI_TOOL_POLICIES value '%s': %w", v, err)
+			}
+			c.ToolPolicies = policies
+			return nil
+		}},
+		{"AGUI_TOOL_TIMEOUTS", func(v string) error {
+			timeouts, err := parseToolValues(v)
+			if err != nil {
+				return fmt.Errorf("invalid AGUI_TOOL_TIMEOUTS value '%s': %w", v, err)
+			}
+			c.ToolTimeouts = timeouts
+			return nil
+		}},
+		{"AGUI_TOOL_PARALLELISM", func(v string) error {
+			parallelism, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid AGUI_TOOL_PARALLELISM value '%s': %w", v, err)
+			}
+			c.ToolParallelism = parallelism
+			return nil
+		}},
+	}
+}
+
+// boolEnv returns an envVar apply func that parses a boolean into dst
+func boolEnv(key string, dst *bool) func(string) error {
+	return func(v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s value '%s': %w", key, v, err)
+		}
+		*dst = b
+		return nil
+	}
+}
+
+// durationEnv returns an envVar apply func that parses a duration into dst
+func durationEnv(key string, dst *time.Duration) func(string) error {
+	return func(v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s value '%s': %w", key, v, err)
+		}
+		*dst = d
+		return nil
+	}
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// parseToolValues parses a comma separated list of tool=value pairs, values
+// are lowercased
+func parseToolValues(v string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, item := range splitList(v) {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected tool=value, got '%s'", item)
+		}
+		values[strings.TrimSpace(name)] = strings.ToLower(strings.TrimSpace(value))
+	}
+	return values, nil
+}
+
+// parseMirrors parses comma separated prefix=directory pairs
+func parseMirrors(v string) (map[string]string, error) {
+	mirrors := map[string]string{}
+	for _, item := range splitList(v) {
+		prefix, dir, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected prefix=directory, got '%s'", item)
+		}
+		mirrors[strings.TrimSpace(prefix)] = strings.TrimSpace(dir)
+	}
+	return mirrors, nil
+}
+
+// parseBuiltinTools parses comma separated tool.setting=value pairs, the
+// settings are enabled, timeout and max_output
+func parseBuiltinTools(v string) (map[string]BuiltinTool, error) {
+	tools := map[string]BuiltinTool{}
+	for _, item := range splitList(v) {
+		key, value, ok := strings.Cut(item, "=")
+		name, setting, hasSetting := strings.Cut(strings.TrimSpace(key), ".")
+		if !ok || !hasSetting {
+			return nil, fmt.Errorf("expected tool.setting=value, got '%s'", item)
+		}
+		value = strings.TrimSpace(value)
+		tool := tools[name]
+		switch setting {
+		case "enabled":
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value '%s': %w", key, value, err)
+			}
+			tool.Enabled = &enabled
+		case "timeout":
+			tool.Timeout = value
+		case "max_output":
+			maxOutput, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value '%s': %w", key, value, err)
+			}
+			tool.MaxOutput = maxOutput
+		default:
+			return nil, fmt.Errorf("unknown setting '%s' of tool %s", setting, name)
+		}
+		tools[name] = tool
+	}
+	return tools, nil
+}
+
+// formatToolValues is the inverse of parseToolValues
+func formatToolValues(values map[string]string) string {
+	pairs := make([]string, 0, len(values))
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		pairs = append(pairs, name+"="+values[name])
 	}
+	return strings.Join(pairs, ",")
 }
 
 // Default configuration values
@@ -67,6 +446,19 @@ const (
 	DefaultWriteTimeout        = 30 * time.Second
 	DefaultSSEKeepAlive        = 15 * time.Second
 	DefaultStreamingChunkDelay = 200 * time.Millisecond
+	DefaultShutdownDrainDelay  = 5 * time.Second
+	DefaultShutdownGracePeriod = 30 * time.Second
+	DefaultModel               = "claude-3-haiku-20240307"
+	DefaultEmbeddedMCP         = true
+	DefaultMCPPort             = 3217
+	DefaultMaxIterations       = 50
+	DefaultHistoryTokens       = 16000
+	DefaultHistoryTurns        = 10
+	DefaultCodeMemoryLimit     = 128
+	DefaultCodeConcurrency     = 4
+	DefaultToolParallelism     = 4
+	DefaultOutputRetries       = 2
+	DefaultEmbeddingProvider   = EmbeddingHash
 )
 
 // Default CORS allowed origins
@@ -90,9 +482,18 @@ func New() *Config {
 		ReadTimeout:         DefaultReadTimeout,
 		WriteTimeout:        DefaultWriteTimeout,
 		SSEKeepAlive:        DefaultSSEKeepAlive,
+		ShutdownDrainDelay:  DefaultShutdownDrainDelay,
+		ShutdownGracePeriod: DefaultShutdownGracePeriod,
 		CORSEnabled:         true,
-		CORSAllowedOrigins:  DefaultCORSAllowedOrigins,
+		CORSAllowedOrigins:  slices.Clone(DefaultCORSAllowedOrigins),
 		StreamingChunkDelay: DefaultStreamingChunkDelay,
+		Model:               DefaultModel,
+		EmbeddedMCP:         DefaultEmbeddedMCP,
+		MCPPort:             DefaultMCPPort,
+		CodeMemoryLimit:     DefaultCodeMemoryLimit,
+		CodeConcurrency:     DefaultCodeConcurrency,
+		ToolParallelism:     DefaultToolParallelism,
+		EmbeddingProvider:   DefaultEmbeddingProvider,
 	}
 }
 
@@ -140,10 +541,138 @@ func (c *Config) Validate() error {
 		errs = append(errs, fmt.Errorf("SSE keep-alive must be non-negative, got %v", c.SSEKeepAlive))
 	}
 
+	if c.ShutdownDrainDelay < 0 {
+		errs = append(errs, fmt.Errorf("shutdown drain delay must be non-negative, got %v", c.ShutdownDrainDelay))
+	}
+
+	if c.ShutdownGracePeriod < 0 {
+		errs = append(errs, fmt.Errorf("shutdown grace period must be non-negative, got %v", c.ShutdownGracePeriod))
+	}
+
 	if c.StreamingChunkDelay < 0 {
 		errs = append(errs, fmt.Errorf("streaming chunk delay must be non-negative, got %v", c.StreamingChunkDelay))
 	}
 
+	if c.Model == "" {
+		errs = append(errs, errors.New("model must not be empty"))
+	}
+
+	if c.EmbeddedMCP && (c.MCPPort < 1 || c.MCPPort > 65535) {
+		errs = append(errs, fmt.Errorf("MCP port must be between 1 and 65535, got %d", c.MCPPort))
+	}
+
+	for _, server := range c.MCPServers {
+		if !validMCPServer(server) {
+			errs = append(errs, fmt.Errorf("MCP server '%s' must be an http(s)://, sse+http(s):// or stdio: endpoint", server))
+		}
+	}
+
+	switch c.CassetteMode {
+	case "":
+	case "record", "replay":
+		if c.Cassette == "" {
+			errs = append(errs, fmt.Errorf("cassette mode '%s' requires a cassette file", c.CassetteMode))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid cassette mode '%s', must be one of: record, replay", c.CassetteMode))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.BuiltinTools)) {
+		tool := c.BuiltinTools[name]
+		if tool.Timeout != "" {
+			if d, err := time.ParseDuration(tool.Timeout); err != nil || d <= 0 {
+				errs = append(errs, fmt.Errorf("builtin tool %s: invalid timeout '%s'", name, tool.Timeout))
+			}
+		}
+		if tool.MaxOutput < 0 {
+			errs = append(errs, fmt.Errorf("builtin tool %s: max output must be non-negative, got %d", name, tool.MaxOutput))
+		}
+	}
+
+	if c.CodeMemoryLimit < 1 || c.CodeMemoryLimit > 4096 {
+		errs = append(errs, fmt.Errorf("code memory limit must be between 1 and 4096 MiB, got %d", c.CodeMemoryLimit))
+	}
+	if c.CodeConcurrency < 1 {
+		errs = append(errs, fmt.Errorf("code concurrency must be at least 1, got %d", c.CodeConcurrency))
+	}
+	for _, language := range slices.Sorted(maps.Keys(c.CodeRuntimes)) {
+		if c.CodeRuntimes[language].Module == "" {
+			errs = append(errs, fmt.Errorf("code runtime %s: module is required", language))
+		}
+	}
+
+	switch c.EmbeddingProvider {
+	case EmbeddingHash, EmbeddingOpenAI, EmbeddingOllama:
+	default:
+		errs = append(errs, fmt.Errorf("invalid embedding provider '%s', must be one of: hash, openai, ollama", c.EmbeddingProvider))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.Agents)) {
+		profile := c.Agents[name]
+		if !agentNamePattern.MatchString(name) {
+			errs = append(errs, fmt.Errorf("invalid agent name '%s', use lowercase letters, digits, _ and -", name))
+		}
+		if profile.MaxIterations < 0 {
+			errs = append(errs, fmt.Errorf("agent %s: max iterations must be non-negative, got %d", name, profile.MaxIterations))
+		}
+		if t := profile.Temperature; t != nil && (*t < 0 || *t > 1) {
+			errs = append(errs, fmt.Errorf("agent %s: temperature must be between 0 and 1, got %v", name, *t))
+		}
+		switch profile.History {
+		case "", HistoryTruncate, HistorySummarize, HistorySlidingWindow:
+		default:
+			errs = append(errs, fmt.Errorf("agent %s: invalid history '%s', must be one of: truncate, summarize, sliding_window", name, profile.History))
+		}
+		if profile.HistoryTokens < 0 || profile.HistoryTurns < 0 {
+			errs = append(errs, fmt.Errorf("agent %s: history tokens and turns must be non-negative", name))
+		}
+		if len(profile.OutputSchema) > 0 {
+			if _, err := schema.Compile("output", profile.OutputSchema); err != nil {
+				errs = append(errs, fmt.Errorf("agent %s: invalid output schema: %w", name, err))
+			} else if profile.OutputSchema["type"] != "object" {
+				errs = append(errs, fmt.Errorf("agent %s: output schema must have type object", name))
+			}
+		}
+		if profile.OutputRetries < 0 {
+			errs = append(errs, fmt.Errorf("agent %s: output retries must be non-negative, got %d", name, profile.OutputRetries))
+		}
+		for _, sub := range profile.SubAgents {
+			subProfile, ok := c.Agents[sub]
+			switch {
+			case !ok:
+				errs = append(errs, fmt.Errorf("agent %s: unknown sub-agent '%s'", name, sub))
+			case len(subProfile.SubAgents) > 0:
+				errs = append(errs, fmt.Errorf("agent %s: sub-agent %s cannot have sub-agents of its own", name, sub))
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.ToolPolicies)) {
+		switch c.ToolPolicies[name] {
+		case PolicyAuto, PolicyConfirm, PolicyDeny:
+		default:
+			errs = append(errs, fmt.Errorf("invalid policy '%s' for tool %s, must be one of: auto, confirm, deny", c.ToolPolicies[name], name))
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.ToolTimeouts)) {
+		if d, err := time.ParseDuration(c.ToolTimeouts[name]); err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("invalid timeout '%s' for tool %s", c.ToolTimeouts[name], name))
+		}
+	}
+
+	if c.ToolParallelism < 1 {
+		errs = append(errs, fmt.Errorf("tool parallelism must be at least 1, got %d", c.ToolParallelism))
+	}
+
+	for _, override := range c.AllowedOverrides {
+		switch override {
+		case OverrideAgent, OverrideModel, OverrideTemperature, OverrideMaxIterations:
+		default:
+			errs = append(errs, fmt.Errorf("invalid override '%s', must be one of: agent, model, temperature, max_iterations", override))
+		}
+	}
+
 	if len(errs) > 0 {
 		return errors.Join(errs...)
 	}
@@ -151,6 +680,109 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// validMCPServer reports whether server uses a supported endpoint scheme
+func validMCPServer(server string) bool {
+	for _, prefix := range []string{"http://", "https://", "sse+http://", "sse+https://", "stdio:"} {
+		if strings.HasPrefix(server, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// MCPEndpoints returns every MCP endpoint the agent loads tools from,
+// the embedded server first when it is enabled
+func (c *Config) MCPEndpoints() []string {
+	var endpoints []string
+	if c.EmbeddedMCP {
+		endpoints = append(endpoints, fmt.Sprintf("http://127.0.0.1:%d/mcp", c.MCPPort))
+	}
+	return append(endpoints, c.MCPServers...)
+}
+
+// Agent returns the named agent profile with its defaults filled in. The empty
+// name is the default agent served at /agentic.
+func (c *Config) Agent(name string) (AgentProfile, bool) {
+	var profile AgentProfile
+	if name != "" {
+		var ok bool
+		if profile, ok = c.Agents[name]; !ok {
+			return AgentProfile{}, false
+		}
+	}
+	if profile.Model == "" {
+		profile.Model = c.Model
+	}
+	if profile.MaxIterations == 0 {
+		profile.MaxIterations = DefaultMaxIterations
+	}
+	if profile.History == "" {
+		profile.History = HistoryTruncate
+	}
+	if profile.HistoryTokens == 0 {
+		profile.HistoryTokens = DefaultHistoryTokens
+	}
+	if profile.HistoryTurns == 0 {
+		profile.HistoryTurns = DefaultHistoryTurns
+	}
+	if profile.OutputRetries == 0 {
+		profile.OutputRetries = DefaultOutputRetries
+	}
+	return profile, true
+}
+
+// BuiltinToolEnabled reports whether the named built-in tool is served,
+// tools are enabled unless configured otherwise
+func (c *Config) BuiltinToolEnabled(name string) bool {
+	return c.BuiltinTools[name].enabled()
+}
+
+// TimeoutDuration returns the tool's timeout, 0 when it is not set
+func (t BuiltinTool) TimeoutDuration() time.Duration {
+	d, _ := time.ParseDuration(t.Timeout)
+	return d
+}
+
+func (t BuiltinTool) equal(other BuiltinTool) bool {
+	return t.Timeout == other.Timeout && t.MaxOutput == other.MaxOutput && t.enabled() == other.enabled()
+}
+
+func (t BuiltinTool) enabled() bool {
+	return t.Enabled == nil || *t.Enabled
+}
+
+func (r CodeRuntime) equal(other CodeRuntime) bool {
+	return r.Module == other.Module && slices.Equal(r.Args, other.Args)
+}
+
+// OverrideAllowed reports whether clients may override the run parameter
+func (c *Config) OverrideAllowed(name string) bool {
+	return slices.Contains(c.AllowedOverrides, name)
+}
+
+// ToolPolicy returns the policy for the named tool
+func (c *Config) ToolPolicy(name string) string {
+	if policy, ok := c.ToolPolicies[name]; ok {
+		return policy
+	}
+	if policy, ok := c.ToolPolicies["*"]; ok {
+		return policy
+	}
+	return PolicyAuto
+}
+
+// ToolTimeout returns how long a call to the named tool may take, 0 when
+// neither the tool nor "*" has a timeout
+func (c *Config) ToolTimeout(name string) time.Duration {
+	timeout, ok := c.ToolTimeouts[name]
+	if !ok {
+		timeout = c.ToolTimeouts["*"]
+	}
+	// Validate rejects timeouts that do not parse
+	d, _ := time.ParseDuration(timeout)
+	return d
+}
+
 // LogLevel returns the slog.Level for the configured log level
 func (c *Config) GetLogLevel() slog.Level {
 	level, ok := ValidLogLevels[c.LogLevel]
@@ -160,22 +792,74 @@ func (c *Config) GetLogLevel() slog.Level {
 	return level
 }
 
-// LoadFromFlags loads configuration from command line flags with precedence over env vars
-func (c *Config) LoadFromFlags() error {
+// OriginAllowed reports whether origin matches CORSAllowedOrigins.
+// Entries may be "*", an exact origin or a subdomain wildcard such as "https://*.example.com".
+func (c *Config) OriginAllowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range c.CORSAllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		if i := strings.Index(allowed, "://*."); i != -1 {
+			scheme, suffix := allowed[:i+3], allowed[i+4:]
+			if strings.HasPrefix(origin, scheme) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LoadFromFlags loads configuration from command line flags with precedence over env vars.
+// Flag defaults are taken from c, so flags that are not set leave the current values untouched.
+func (c *Config) LoadFromFlags(args []string) error {
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
 	var (
-		host         = flag.String("host", c.Host, "Server host address")
-		port         = flag.Int("port", c.Port, "Server port (1-65535)")
-		logLevel     = flag.String("log-level", c.LogLevel, "Log level (debug, info, warn, error)")
-		enableSSE    = flag.Bool("enable-sse", c.EnableSSE, "Enable Server-Sent Events")
-		readTimeout  = flag.Duration("read-timeout", c.ReadTimeout, "Read timeout duration")
-		writeTimeout = flag.Duration("write-timeout", c.WriteTimeout, "Write timeout duration")
-		sseKeepAlive = flag.Duration("sse-keepalive", c.SSEKeepAlive, "SSE keep-alive duration")
-		corsEnabled  = flag.Bool("cors-enabled", c.CORSEnabled, "Enable CORS")
+		configFile          = fs.String("config", c.ConfigFile, "Path to a YAML or TOML config file")
+		host                = fs.String("host", c.Host, "Server host address")
+		port                = fs.Int("port", c.Port, "Server port (1-65535)")
+		logLevel            = fs.String("log-level", c.LogLevel, "Log level (debug, info, warn, error)")
+		enableSSE           = fs.Bool("enable-sse", c.EnableSSE, "Enable Server-Sent Events")
+		readTimeout         = fs.Duration("read-timeout", c.ReadTimeout, "Read timeout duration")
+		writeTimeout        = fs.Duration("write-timeout", c.WriteTimeout, "Write timeout duration")
+		sseKeepAlive        = fs.Duration("sse-keepalive", c.SSEKeepAlive, "SSE keep-alive duration")
+		shutdownDrainDelay  = fs.Duration("shutdown-drain-delay", c.ShutdownDrainDelay, "How long readiness fails before the server stops listening")
+		shutdownGracePeriod = fs.Duration("shutdown-grace-period", c.ShutdownGracePeriod, "How long in-flight agent runs may take to finish on shutdown")
+		corsEnabled         = fs.Bool("cors-enabled", c.CORSEnabled, "Enable CORS")
+		corsAllowedOrigins  = fs.String("cors-allowed-origins", strings.Join(c.CORSAllowedOrigins, ","), "Comma separated list of allowed CORS origins")
+		streamingChunkDelay = fs.Duration("streaming-chunk-delay", c.StreamingChunkDelay, "Delay between streamed chunks")
+		model               = fs.String("model", c.Model, "LLM model used by the agent")
+		embeddedMCP         = fs.Bool("embedded-mcp", c.EmbeddedMCP, "Run the MCP server inside this process")
+		mcpPort             = fs.Int("mcp-port", c.MCPPort, "Port of the embedded MCP server (1-65535)")
+		mcpToolsDir         = fs.String("mcp-tools-dir", c.MCPToolsDir, "Directory of tool manifests for the embedded MCP server")
+		mcpPromptsDir       = fs.String("mcp-prompts-dir", c.MCPPromptsDir, "Directory of prompt templates for the embedded MCP server")
+		mcpResourcesDir     = fs.String("mcp-resources-dir", c.MCPResourcesDir, "Directory of docs exposed as resources by the embedded MCP server")
+		promptTemplatesDir  = fs.String("prompt-templates-dir", c.PromptTemplatesDir, "Directory of system and user prompt templates for agent runs")
+		filesRoot           = fs.String("files-root", c.FilesRoot, "Directory the read_file and list_files tools are confined to")
+		fetchAllowedHosts   = fs.String("fetch-allowed-hosts", strings.Join(c.FetchAllowedHosts, ","), "Comma separated hosts the fetch_url tool may contact")
+		codeExecution       = fs.Bool("code-execution", c.CodeExecution, "Serve the run_code tool, which runs programs in a WebAssembly sandbox")
+		codeMemoryLimit     = fs.Int("code-memory-limit", c.CodeMemoryLimit, "Memory in MiB a program run by run_code may use")
+		codeConcurrency     = fs.Int("code-concurrency", c.CodeConcurrency, "Programs run_code may build or run at the same time")
+		docsDir             = fs.String("docs-dir", c.DocsDir, "Directory of Markdown and text files the agent can search")
+		embeddingProvider   = fs.String("embedding-provider", c.EmbeddingProvider, "Embeddings for the document search (hash, openai, ollama)")
+		embeddingModel      = fs.String("embedding-model", c.EmbeddingModel, "Embedding model, empty uses the provider's default")
+		mcpServers          = fs.String("mcp-servers", strings.Join(c.MCPServers, ","), "Comma separated list of external MCP server endpoints")
+		cassette            = fs.String("cassette", c.Cassette, "File agent runs are recorded to or replayed from")
+		cassetteMode        = fs.String("cassette-mode", c.CassetteMode, "Record or replay agent runs with the cassette file (record, replay)")
+		allowedOverrides    = fs.String("allowed-overrides", strings.Join(c.AllowedOverrides, ","), "Comma separated run parameters clients may override (agent, model, temperature, max_iterations)")
+		toolPolicies        = fs.String("tool-policies", formatToolValues(c.ToolPolicies), "Comma separated tool=policy pairs (auto, confirm, deny), * sets the default")
+		toolTimeouts        = fs.String("tool-timeouts", formatToolValues(c.ToolTimeouts), "Comma separated tool=duration pairs bounding tool calls, * sets the default")
+		toolParallelism     = fs.Int("tool-parallelism", c.ToolParallelism, "Tool calls of one agent step that may run at the same time")
 	)
 
-	flag.Parse()
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
 
 	// Apply flag values with precedence over env vars
+	c.ConfigFile = *configFile
 	c.Host = *host
 	c.Port = *port
 	c.LogLevel = strings.ToLower(*logLevel)
@@ -183,26 +867,83 @@ func (c *Config) LoadFromFlags() error {
 	c.ReadTimeout = *readTimeout
 	c.WriteTimeout = *writeTimeout
 	c.SSEKeepAlive = *sseKeepAlive
+	c.ShutdownDrainDelay = *shutdownDrainDelay
+	c.ShutdownGracePeriod = *shutdownGracePeriod
 	c.CORSEnabled = *corsEnabled
+	c.CORSAllowedOrigins = splitList(*corsAllowedOrigins)
+	c.StreamingChunkDelay = *streamingChunkDelay
+	c.Model = *model
+	c.EmbeddedMCP = *embeddedMCP
+	c.MCPPort = *mcpPort
+	c.MCPToolsDir = *mcpToolsDir
+	c.MCPPromptsDir = *mcpPromptsDir
+	c.MCPResourcesDir = *mcpResourcesDir
+	c.PromptTemplatesDir = *promptTemplatesDir
+	c.FilesRoot = *filesRoot
+	c.FetchAllowedHosts = splitList(*fetchAllowedHosts)
+	c.CodeExecution = *codeExecution
+	c.CodeMemoryLimit = *codeMemoryLimit
+	c.CodeConcurrency = *codeConcurrency
+	c.DocsDir = *docsDir
+	c.EmbeddingProvider = strings.ToLower(*embeddingProvider)
+	c.EmbeddingModel = *embeddingModel
+	c.MCPServers = splitList(*mcpServers)
+	c.Cassette = *cassette
+	c.CassetteMode = strings.ToLower(*cassetteMode)
+	c.AllowedOverrides = splitList(*allowedOverrides)
+	policies, err := parseToolValues(*toolPolicies)
+	if err != nil {
+		return fmt.Errorf("invalid --tool-policies value '%s': %w", *toolPolicies, err)
+	}
+	c.ToolPolicies = policies
+	timeouts, err := parseToolValues(*toolTimeouts)
+	if err != nil {
+		return fmt.Errorf("invalid --tool-timeouts value '%s': %w", *toolTimeouts, err)
+	}
+	c.ToolTimeouts = timeouts
+	c.ToolParallelism = *toolParallelism
 
 	return nil
 }
 
-// LoadConfig creates and loads configuration with proper precedence: flags > env > defaults
+// LoadConfig creates and loads configuration with proper precedence: flags > env > file > defaults
 func LoadConfig() (*Config, error) {
+	return load(os.Args[1:])
+}
+
+// load builds a Config from args. The config file location can itself come from
+// env or flags, so it is resolved first and the layers are then applied in order.
+func load(args []string) (*Config, error) {
 	// Start with defaults
 	config := New()
 
-	// Load environment variables (override defaults)
+	// Resolve the config file path from env and flags
 	if err := config.LoadFromEnv(); err != nil {
 		return nil, fmt.Errorf("failed to load environment variables: %w", err)
 	}
-
-	// Load command line flags (override env vars)
-	if err := config.LoadFromFlags(); err != nil {
+	if err := config.LoadFromFlags(args); err != nil {
 		return nil, fmt.Errorf("failed to load command line flags: %w", err)
 	}
 
+	if path := config.ConfigFile; path != "" {
+		// Load the config file (override defaults)
+		config = New()
+		if err := config.LoadFromFile(path); err != nil {
+			return nil, fmt.Errorf("failed to load config file: %w", err)
+		}
+
+		// Load environment variables (override file)
+		if err := config.LoadFromEnv(); err != nil {
+			return nil, fmt.Errorf("failed to load environment variables: %w", err)
+		}
+
+		// Load command line flags (override env vars)
+		if err := config.LoadFromFlags(args); err != nil {
+			return nil, fmt.Errorf("failed to load command line flags: %w", err)
+		}
+		config.ConfigFile = path
+	}
+
 	// Validate the final configuration
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("configuration validation failed: %w", err)
@@ -211,17 +952,140 @@ func LoadConfig() (*Config, error) {
 	return config, nil
 }
 
+// ListenerChanges returns the names of settings that differ between c and other
+// but can only take effect on restart.
+func (c *Config) ListenerChanges(other *Config) []string {
+	var changed []string
+	if c.Host != other.Host {
+		changed = append(changed, "host")
+	}
+	if c.Port != other.Port {
+		changed = append(changed, "port")
+	}
+	if c.EnableSSE != other.EnableSSE {
+		changed = append(changed, "enable_sse")
+	}
+	if c.ReadTimeout != other.ReadTimeout {
+		changed = append(changed, "read_timeout")
+	}
+	if c.WriteTimeout != other.WriteTimeout {
+		changed = append(changed, "write_timeout")
+	}
+	if c.SSEKeepAlive != other.SSEKeepAlive {
+		changed = append(changed, "sse_keepalive")
+	}
+	if c.CORSEnabled != other.CORSEnabled {
+		changed = append(changed, "cors_enabled")
+	}
+	if c.EmbeddedMCP != other.EmbeddedMCP {
+		changed = append(changed, "embedded_mcp")
+	}
+	if c.MCPPort != other.MCPPort {
+		changed = append(changed, "mcp_port")
+	}
+	if c.MCPToolsDir != other.MCPToolsDir {
+		changed = append(changed, "mcp_tools_dir")
+	}
+	if c.MCPPromptsDir != other.MCPPromptsDir {
+		changed = append(changed, "mcp_prompts_dir")
+	}
+	if c.MCPResourcesDir != other.MCPResourcesDir {
+		changed = append(changed, "mcp_resources_dir")
+	}
+	if c.PromptTemplatesDir != other.PromptTemplatesDir {
+		changed = append(changed, "prompt_templates_dir")
+	}
+	if c.FilesRoot != other.FilesRoot || !slices.Equal(c.FetchAllowedHosts, other.FetchAllowedHosts) ||
+		!maps.Equal(c.FetchMirrors, other.FetchMirrors) || !maps.EqualFunc(c.BuiltinTools, other.BuiltinTools, BuiltinTool.equal) ||
+		c.CodeExecution != other.CodeExecution || c.CodeMemoryLimit != other.CodeMemoryLimit || c.CodeConcurrency != other.CodeConcurrency ||
+		!maps.EqualFunc(c.CodeRuntimes, other.CodeRuntimes, CodeRuntime.equal) {
+		changed = append(changed, "builtin_tools")
+	}
+	if c.DocsDir != other.DocsDir || c.EmbeddingProvider != other.EmbeddingProvider || c.EmbeddingModel != other.EmbeddingModel {
+		changed = append(changed, "docs_dir")
+	}
+	return changed
+}
+
+// retainListenerSettings copies the settings that cannot be reloaded from prev
+func (c *Config) retainListenerSettings(prev *Config) {
+	c.Host = prev.Host
+	c.Port = prev.Port
+	c.EnableSSE = prev.EnableSSE
+	c.ReadTimeout = prev.ReadTimeout
+	c.WriteTimeout = prev.WriteTimeout
+	c.SSEKeepAlive = prev.SSEKeepAlive
+	c.CORSEnabled = prev.CORSEnabled
+	c.EmbeddedMCP = prev.EmbeddedMCP
+	c.MCPPort = prev.MCPPort
+	c.MCPToolsDir = prev.MCPToolsDir
+	c.MCPPromptsDir = prev.MCPPromptsDir
+	c.MCPResourcesDir = prev.MCPResourcesDir
+	c.PromptTemplatesDir = prev.PromptTemplatesDir
+	c.FilesRoot = prev.FilesRoot
+	c.FetchAllowedHosts = prev.FetchAllowedHosts
+	c.FetchMirrors = prev.FetchMirrors
+	c.BuiltinTools = prev.BuiltinTools
+	c.CodeExecution = prev.CodeExecution
+	c.CodeMemoryLimit = prev.CodeMemoryLimit
+	c.CodeConcurrency = prev.CodeConcurrency
+	c.CodeRuntimes = prev.CodeRuntimes
+	c.DocsDir = prev.DocsDir
+	c.EmbeddingProvider = prev.EmbeddingProvider
+	c.EmbeddingModel = prev.EmbeddingModel
+}
+
+// LogFields returns the configuration as structured log fields without sensitive information
+func (c *Config) LogFields() map[string]any {
+	return map[string]any{
+		"config_file":           c.ConfigFile,
+		"host":                  c.Host,
+		"port":                  c.Port,
+		"log_level":             c.LogLevel,
+		"enable_sse":            c.EnableSSE,
+		"read_timeout":          c.ReadTimeout,
+		"write_timeout":         c.WriteTimeout,
+		"sse_keepalive":         c.SSEKeepAlive,
+		"shutdown_drain_delay":  c.ShutdownDrainDelay,
+		"shutdown_grace_period": c.ShutdownGracePeriod,
+		"cors_enabled":          c.CORSEnabled,
+		"cors_allowed_origins":  c.CORSAllowedOrigins,
+		"streaming_chunk_delay": c.StreamingChunkDelay,
+		"model":                 c.Model,
+		"embedded_mcp":          c.EmbeddedMCP,
+		"mcp_port":              c.MCPPort,
+		"mcp_tools_dir":         c.MCPToolsDir,
+		"mcp_prompts_dir":       c.MCPPromptsDir,
+		"mcp_resources_dir":     c.MCPResourcesDir,
+		"prompt_templates_dir":  c.PromptTemplatesDir,
+		"files_root":            c.FilesRoot,
+		"fetch_allowed_hosts":   c.FetchAllowedHosts,
+		"fetch_mirrors":         c.FetchMirrors,
+		"builtin_tools":         slices.Sorted(maps.Keys(c.BuiltinTools)),
+		"code_execution":        c.CodeExecution,
+		"code_memory_limit":     c.CodeMemoryLimit,
+		"code_concurrency":      c.CodeConcurrency,
+		"code_runtimes":         slices.Sorted(maps.Keys(c.CodeRuntimes)),
+		"docs_dir":              c.DocsDir,
+		"embedding_provider":    c.EmbeddingProvider,
+		"embedding_model":       c.EmbeddingModel,
+		"mcp_servers":           c.MCPServers,
+		"tool_policies":         c.ToolPolicies,
+		"tool_timeouts":         c.ToolTimeouts,
+		"tool_parallelism":      c.ToolParallelism,
+		"allowed_overrides":     c.AllowedOverrides,
+		"cassette":              c.Cassette,
+		"cassette_mode":         c.CassetteMode,
+		"agents":                slices.Sorted(maps.Keys(c.Agents)),
+	}
+}
+
 // LogSafeConfig logs the configuration without sensitive information
 func (c *Config) LogSafeConfig(logger *slog.Logger) {
-	logger.Info("Server configuration loaded",
-		"host", c.Host,
-		"port", c.Port,
-		"log_level", c.LogLevel,
-		"enable_sse", c.EnableSSE,
-		"read_timeout", c.ReadTimeout,
-		"write_timeout", c.WriteTimeout,
-		"sse_keepalive", c.SSEKeepAlive,
-		"cors_enabled", c.CORSEnabled,
-		"streaming_chunk_delay", c.StreamingChunkDelay,
-	)
+	fields := c.LogFields()
+	args := make([]any, 0, 2*len(fields))
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		args = append(args, key, fields[key])
+	}
+	logger.Info("Server configuration loaded", args...)
 }
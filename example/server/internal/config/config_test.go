@@ -0,0 +1,257 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+port: 9000
+log_level: debug
+model: file-model
+streaming_chunk_delay: 50ms
+cors_allowed_origins: ["https://*.example.com"]
+`), 0o600))
+
+	t.Setenv("AGUI_CONFIG", path)
+	t.Setenv("AGUI_MODEL", "env-model")
+	t.Setenv("AGUI_PORT", "9100")
+
+	cfg, err := load([]string{"-port", "9200"})
+	require.NoError(t, err)
+
+	require.Equal(t, path, cfg.ConfigFile)
+	require.Equal(t, 9200, cfg.Port)
+	require.Equal(t, "env-model", cfg.Model)
+	require.Equal(t, "debug", cfg.LogLevel)
+	require.Equal(t, 50*time.Millisecond, cfg.StreamingChunkDelay)
+	require.Equal(t, DefaultHost, cfg.Host)
+	require.True(t, cfg.OriginAllowed("https://app.example.com"))
+	require.False(t, cfg.OriginAllowed("https://example.org"))
+}
+
+func TestLoadFromFileTOML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.toml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+read_timeout = "5s"
+mcp_servers = ["http://mcp-a:3217/mcp", "http://mcp-b:3217/mcp"]
+docs_dir = "docs"
+embedding_provider = "Ollama"
+`), 0o600))
+
+	cfg := New()
+	require.NoError(t, cfg.LoadFromFile(path))
+	require.Equal(t, 5*time.Second, cfg.ReadTimeout)
+	require.Len(t, cfg.MCPServers, 2)
+	require.Equal(t, "docs", cfg.DocsDir)
+	require.Equal(t, EmbeddingOllama, cfg.EmbeddingProvider)
+	require.Contains(t, cfg.ListenerChanges(New()), "docs_dir")
+
+	cfg.EmbeddingProvider = "word2vec"
+	require.ErrorContains(t, cfg.Validate(), "invalid embedding provider 'word2vec'")
+
+	require.NoError(t, os.WriteFile(path, []byte(`unknown_key = true`), 0o600))
+	require.Error(t, New().LoadFromFile(path))
+}
+
+func TestToolPolicies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+tool_policies:
+  "*": confirm
+  word_count: Auto
+`), 0o600))
+
+	t.Setenv("AGUI_CONFIG", path)
+	cfg, err := load(nil)
+	require.NoError(t, err)
+	require.Equal(t, PolicyAuto, cfg.ToolPolicy("word_count"))
+	require.Equal(t, PolicyConfirm, cfg.ToolPolicy("delete_file"))
+
+	// Flags replace the file's policies
+	cfg, err = load([]string{"-tool-policies", "delete_file=deny"})
+	require.NoError(t, err)
+	require.Equal(t, PolicyDeny, cfg.ToolPolicy("delete_file"))
+	require.Equal(t, PolicyAuto, cfg.ToolPolicy("word_count"))
+
+	_, err = load([]string{"-tool-policies", "delete_file=maybe"})
+	require.ErrorContains(t, err, "invalid policy 'maybe'")
+}
+
+func TestToolTimeouts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+tool_timeouts:
+  "*": 30s
+  run_code: 2m
+tool_parallelism: 2
+`), 0o600))
+
+	t.Setenv("AGUI_CONFIG", path)
+	cfg, err := load(nil)
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Minute, cfg.ToolTimeout("run_code"))
+	require.Equal(t, 30*time.Second, cfg.ToolTimeout("word_count"))
+	require.Equal(t, 2, cfg.ToolParallelism)
+
+	t.Setenv("AGUI_TOOL_PARALLELISM", "8")
+	cfg, err = load([]string{"-tool-timeouts", "fetch_url=5s"})
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Second, cfg.ToolTimeout("fetch_url"))
+	require.Zero(t, cfg.ToolTimeout("run_code"))
+	require.Equal(t, 8, cfg.ToolParallelism)
+
+	_, err = load([]string{"-tool-timeouts", "fetch_url=soon", "-tool-parallelism", "0"})
+	require.ErrorContains(t, err, "invalid timeout 'soon' for tool fetch_url")
+	require.ErrorContains(t, err, "tool parallelism must be at least 1, got 0")
+}
+
+func TestAgentProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.toml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+model = "default-model"
+
+[agents.human_in_the_loop]
+system_prompt = "Ask before you act."
+tools = ["provide_language_options"]
+temperature = 0.2
+
+[agents.shared_state]
+model = "other-model"
+max_iterations = 5
+history = "summarize"
+history_tokens = 4000
+output_retries = 1
+
+[agents.shared_state.output_schema]
+type = "object"
+required = ["steps"]
+properties = { steps = { type = "array", items = { type = "string" } } }
+`), 0o600))
+
+	cfg := New()
+	require.NoError(t, cfg.LoadFromFile(path))
+	require.NoError(t, cfg.Validate())
+
+	profile, ok := cfg.Agent("human_in_the_loop")
+	require.True(t, ok)
+	require.Equal(t, "default-model", profile.Model)
+	require.Equal(t, DefaultMaxIterations, profile.MaxIterations)
+	require.Equal(t, []string{"provide_language_options"}, profile.Tools)
+	require.InDelta(t, 0.2, *profile.Temperature, 1e-9)
+
+	profile, ok = cfg.Agent("shared_state")
+	require.True(t, ok)
+	require.Equal(t, "other-model", profile.Model)
+	require.Equal(t, 5, profile.MaxIterations)
+	require.Equal(t, HistorySummarize, profile.History)
+	require.Equal(t, 4000, profile.HistoryTokens)
+	require.Equal(t, DefaultHistoryTurns, profile.HistoryTurns)
+	require.Equal(t, []any{"steps"}, profile.OutputSchema["required"])
+	require.Equal(t, 1, profile.OutputRetries)
+
+	profile, ok = cfg.Agent("")
+	require.True(t, ok)
+	require.Equal(t, "default-model", profile.Model)
+	require.Equal(t, HistoryTruncate, profile.History)
+	require.Equal(t, DefaultOutputRetries, profile.OutputRetries)
+	_, ok = cfg.Agent("unknown")
+	require.False(t, ok)
+
+	hot := 1.5
+	cfg.Agents["Bad Name"] = AgentProfile{Temperature: &hot, History: "forget", SubAgents: []string{"shared_state", "ghost", "Bad Name"},
+		OutputSchema: map[string]any{"type": "array"}, OutputRetries: -1}
+	cfg.Agents["broken"] = AgentProfile{OutputSchema: map[string]any{"type": "object", "required": "steps"}}
+	err := cfg.Validate()
+	require.ErrorContains(t, err, "invalid agent name 'Bad Name'")
+	require.ErrorContains(t, err, "temperature must be between 0 and 1")
+	require.ErrorContains(t, err, "invalid history 'forget'")
+	require.ErrorContains(t, err, "unknown sub-agent 'ghost'")
+	require.ErrorContains(t, err, "agent Bad Name: output schema must have type object")
+	require.ErrorContains(t, err, "output retries must be non-negative, got -1")
+	require.ErrorContains(t, err, "agent broken: invalid output schema")
+	require.ErrorContains(t, err, "sub-agent Bad Name cannot have sub-agents of its own")
+	require.NotContains(t, err.Error(), "sub-agent shared_state")
+}
+
+func TestAllowedOverrides(t *testing.T) {
+	t.Setenv("AGUI_ALLOWED_OVERRIDES", "model, temperature")
+	cfg, err := load(nil)
+	require.NoError(t, err)
+	require.True(t, cfg.OverrideAllowed(OverrideModel))
+	require.False(t, cfg.OverrideAllowed(OverrideAgent))
+
+	cfg.AllowedOverrides = append(cfg.AllowedOverrides, "api_key")
+	require.ErrorContains(t, cfg.Validate(), "invalid override 'api_key'")
+}
+
+func TestReloadKeepsListenerSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	write := func(port, concurrency int, model string) {
+		require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`
+port: %d
+mcp_port: %d
+read_timeout: %ds
+fetch_allowed_hosts: ["%d.example.com"]
+code_memory_limit: %d
+code_concurrency: %d
+model: %s
+`, port, port+1, concurrency, port, 64*concurrency, concurrency, model)), 0o600))
+	}
+	write(9000, 2, "first-model")
+	t.Setenv("AGUI_CONFIG", path)
+	cfg, err := load(nil)
+	require.NoError(t, err)
+
+	logger, hook := logtest.NewNullLogger()
+	w := NewWatcher(cfg, logger)
+	w.args = nil
+
+	// Settings that need a restart keep their running values, and each
+	// reload still warns about them
+	write(9100, 3, "second-model")
+	for i := 0; i < 2; i++ {
+		hook.Reset()
+		w.Reload()
+		current := w.Current()
+		require.Equal(t, "second-model", current.Model)
+		require.Equal(t, 2, current.CodeConcurrency)
+		require.Empty(t, current.ListenerChanges(cfg))
+
+		warning := hook.Entries[0]
+		require.Equal(t, logrus.WarnLevel, warning.Level)
+		require.ElementsMatch(t, []string{"port", "mcp_port", "read_timeout", "builtin_tools"}, warning.Data["settings"])
+	}
+}
+
+func TestBuiltinToolsFromEnv(t *testing.T) {
+	t.Setenv("AGUI_FETCH_MIRRORS", "https://docs.ag-ui.com/=./mirrors/Docs, https://example.com/=/srv/example")
+	t.Setenv("AGUI_BUILTIN_TOOLS", "fetch_url.timeout=20s,fetch_url.max_output=131072,current_time.enabled=false")
+	cfg, err := load(nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"https://docs.ag-ui.com/": "./mirrors/Docs",
+		"https://example.com/":    "/srv/example",
+	}, cfg.FetchMirrors)
+	require.Equal(t, "20s", cfg.BuiltinTools["fetch_url"].Timeout)
+	require.Equal(t, 131072, cfg.BuiltinTools["fetch_url"].MaxOutput)
+	require.False(t, cfg.BuiltinToolEnabled("current_time"))
+	require.True(t, cfg.BuiltinToolEnabled("calculate"))
+
+	t.Setenv("AGUI_BUILTIN_TOOLS", "fetch_url.retries=3")
+	_, err = load(nil)
+	require.ErrorContains(t, err, "unknown setting 'retries' of tool fetch_url")
+
+	t.Setenv("AGUI_FETCH_MIRRORS", "https://docs.ag-ui.com/")
+	_, err = load(nil)
+	require.ErrorContains(t, err, "expected prefix=directory, got 'https://docs.ag-ui.com/'")
+}
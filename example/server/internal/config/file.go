@@ -0,0 +1,220 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig mirrors Config for YAML and TOML files. Every field is optional,
+// durations use time.ParseDuration syntax ("30s", "200ms").
+type fileConfig struct {
+	Host                *string   `yaml:"host" toml:"host"`
+	Port                *int      `yaml:"port" toml:"port"`
+	LogLevel            *string   `yaml:"log_level" toml:"log_level"`
+	EnableSSE           *bool     `yaml:"enable_sse" toml:"enable_sse"`
+	ReadTimeout         *string   `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout        *string   `yaml:"write_timeout" toml:"write_timeout"`
+	SSEKeepAlive        *string   `yaml:"sse_keepalive" toml:"sse_keepalive"`
+	ShutdownDrainDelay  *string   `yaml:"shutdown_drain_delay" toml:"shutdown_drain_delay"`
+	ShutdownGracePeriod *string   `yaml:"shutdown_grace_period" toml:"shutdown_grace_period"`
+	CORSEnabled         *bool     `yaml:"cors_enabled" toml:"cors_enabled"`
+	CORSAllowedOrigins  *[]string `yaml:"cors_allowed_origins" toml:"cors_allowed_origins"`
+	StreamingChunkDelay *string   `yaml:"streaming_chunk_delay" toml:"streaming_chunk_delay"`
+	Model               *string   `yaml:"model" toml:"model"`
+	EmbeddedMCP         *bool     `yaml:"embedded_mcp" toml:"embedded_mcp"`
+	MCPPort             *int      `yaml:"mcp_port" toml:"mcp_port"`
+	MCPToolsDir         *string   `yaml:"mcp_tools_dir" toml:"mcp_tools_dir"`
+	MCPPromptsDir       *string   `yaml:"mcp_prompts_dir" toml:"mcp_prompts_dir"`
+	MCPResourcesDir     *string   `yaml:"mcp_resources_dir" toml:"mcp_resources_dir"`
+	MCPServers          *[]string `yaml:"mcp_servers" toml:"mcp_servers"`
+	PromptTemplatesDir  *string   `yaml:"prompt_templates_dir" toml:"prompt_templates_dir"`
+	FilesRoot           *string   `yaml:"files_root" toml:"files_root"`
+	FetchAllowedHosts   *[]string `yaml:"fetch_allowed_hosts" toml:"fetch_allowed_hosts"`
+	CodeExecution       *bool     `yaml:"code_execution" toml:"code_execution"`
+	CodeMemoryLimit     *int      `yaml:"code_memory_limit" toml:"code_memory_limit"`
+	CodeConcurrency     *int      `yaml:"code_concurrency" toml:"code_concurrency"`
+	DocsDir             *string   `yaml:"docs_dir" toml:"docs_dir"`
+	EmbeddingProvider   *string   `yaml:"embedding_provider" toml:"embedding_provider"`
+	EmbeddingModel      *string   `yaml:"embedding_model" toml:"embedding_model"`
+	// ToolPolicies maps tool names, or "*", to auto, confirm or deny
+	ToolPolicies *map[string]string `yaml:"tool_policies" toml:"tool_policies"`
+	// ToolTimeouts maps tool names, or "*", to durations
+	ToolTimeouts    *map[string]string `yaml:"tool_timeouts" toml:"tool_timeouts"`
+	ToolParallelism *int               `yaml:"tool_parallelism" toml:"tool_parallelism"`
+	Cassette        *string            `yaml:"cassette" toml:"cassette"`
+	CassetteMode    *string            `yaml:"cassette_mode" toml:"cassette_mode"`
+
+	AllowedOverrides *[]string `yaml:"allowed_overrides" toml:"allowed_overrides"`
+
+	Agents *map[string]AgentProfile `yaml:"agents" toml:"agents"`
+
+	FetchMirrors *map[string]string      `yaml:"fetch_mirrors" toml:"fetch_mirrors"`
+	BuiltinTools *map[string]BuiltinTool `yaml:"builtin_tools" toml:"builtin_tools"`
+	CodeRuntimes *map[string]CodeRuntime `yaml:"code_runtimes" toml:"code_runtimes"`
+}
+
+// LoadFromFile loads configuration from a YAML (.yaml, .yml) or TOML (.toml) file.
+// Keys that are absent from the file leave the current values untouched.
+func (c *Config) LoadFromFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var fc fileConfig
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&fc); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	case ".toml":
+		dec := toml.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&fc); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("unsupported config file extension '%s', expected .yaml, .yml or .toml", ext)
+	}
+
+	return fc.apply(c)
+}
+
+// apply copies the values present in the file onto c
+func (fc *fileConfig) apply(c *Config) error {
+	if fc.Host != nil {
+		c.Host = *fc.Host
+	}
+	if fc.Port != nil {
+		c.Port = *fc.Port
+	}
+	if fc.LogLevel != nil {
+		c.LogLevel = strings.ToLower(*fc.LogLevel)
+	}
+	if fc.EnableSSE != nil {
+		c.EnableSSE = *fc.EnableSSE
+	}
+	if fc.CORSEnabled != nil {
+		c.CORSEnabled = *fc.CORSEnabled
+	}
+	if fc.CORSAllowedOrigins != nil {
+		c.CORSAllowedOrigins = *fc.CORSAllowedOrigins
+	}
+	if fc.Model != nil {
+		c.Model = *fc.Model
+	}
+	if fc.EmbeddedMCP != nil {
+		c.EmbeddedMCP = *fc.EmbeddedMCP
+	}
+	if fc.MCPPort != nil {
+		c.MCPPort = *fc.MCPPort
+	}
+	if fc.MCPToolsDir != nil {
+		c.MCPToolsDir = *fc.MCPToolsDir
+	}
+	if fc.MCPPromptsDir != nil {
+		c.MCPPromptsDir = *fc.MCPPromptsDir
+	}
+	if fc.MCPResourcesDir != nil {
+		c.MCPResourcesDir = *fc.MCPResourcesDir
+	}
+	if fc.PromptTemplatesDir != nil {
+		c.PromptTemplatesDir = *fc.PromptTemplatesDir
+	}
+	if fc.FilesRoot != nil {
+		c.FilesRoot = *fc.FilesRoot
+	}
+	if fc.FetchAllowedHosts != nil {
+		c.FetchAllowedHosts = *fc.FetchAllowedHosts
+	}
+	if fc.FetchMirrors != nil {
+		c.FetchMirrors = *fc.FetchMirrors
+	}
+	if fc.BuiltinTools != nil {
+		c.BuiltinTools = *fc.BuiltinTools
+	}
+	if fc.CodeExecution != nil {
+		c.CodeExecution = *fc.CodeExecution
+	}
+	if fc.CodeMemoryLimit != nil {
+		c.CodeMemoryLimit = *fc.CodeMemoryLimit
+	}
+	if fc.CodeConcurrency != nil {
+		c.CodeConcurrency = *fc.CodeConcurrency
+	}
+	if fc.CodeRuntimes != nil {
+		c.CodeRuntimes = *fc.CodeRuntimes
+	}
+	if fc.DocsDir != nil {
+		c.DocsDir = *fc.DocsDir
+	}
+	if fc.EmbeddingProvider != nil {
+		c.EmbeddingProvider = strings.ToLower(*fc.EmbeddingProvider)
+	}
+	if fc.EmbeddingModel != nil {
+		c.EmbeddingModel = *fc.EmbeddingModel
+	}
+	if fc.MCPServers != nil {
+		c.MCPServers = *fc.MCPServers
+	}
+	if fc.Cassette != nil {
+		c.Cassette = *fc.Cassette
+	}
+	if fc.CassetteMode != nil {
+		c.CassetteMode = strings.ToLower(*fc.CassetteMode)
+	}
+	if fc.AllowedOverrides != nil {
+		c.AllowedOverrides = *fc.AllowedOverrides
+	}
+	if fc.Agents != nil {
+		c.Agents = *fc.Agents
+	}
+	if fc.ToolPolicies != nil {
+		c.ToolPolicies = map[string]string{}
+		for name, policy := range *fc.ToolPolicies {
+			c.ToolPolicies[name] = strings.ToLower(policy)
+		}
+	}
+	if fc.ToolTimeouts != nil {
+		c.ToolTimeouts = *fc.ToolTimeouts
+	}
+	if fc.ToolParallelism != nil {
+		c.ToolParallelism = *fc.ToolParallelism
+	}
+
+	durations := []struct {
+		key string
+		val *string
+		dst *time.Duration
+	}{
+		{"read_timeout", fc.ReadTimeout, &c.ReadTimeout},
+		{"write_timeout", fc.WriteTimeout, &c.WriteTimeout},
+		{"sse_keepalive", fc.SSEKeepAlive, &c.SSEKeepAlive},
+		{"shutdown_drain_delay", fc.ShutdownDrainDelay, &c.ShutdownDrainDelay},
+		{"shutdown_grace_period", fc.ShutdownGracePeriod, &c.ShutdownGracePeriod},
+		{"streaming_chunk_delay", fc.StreamingChunkDelay, &c.StreamingChunkDelay},
+	}
+	for _, d := range durations {
+		if d.val == nil {
+			continue
+		}
+		parsed, err := time.ParseDuration(*d.val)
+		if err != nil {
+			return fmt.Errorf("invalid %s value '%s': %w", d.key, *d.val, err)
+		}
+		*d.dst = parsed
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/watch"
+	"github.com/sirupsen/logrus"
+)
+
+// Provider gives access to the currently active configuration
+type Provider interface {
+	Current() *Config
+}
+
+// Current returns c itself, so a static Config can be used as a Provider
+func (c *Config) Current() *Config {
+	return c
+}
+
+// Watcher holds the active configuration and reloads it on SIGHUP or when the
+// config file changes. Listener settings (host, port, timeouts, SSE and CORS
+// toggles, the embedded MCP server) only take effect on restart and are kept
+// from the running config.
+type Watcher struct {
+	args    []string
+	logger  *logrus.Logger
+	current atomic.Pointer[Config]
+	// reloadMu serializes reloads from SIGHUP and file changes
+	reloadMu sync.Mutex
+
+	mu        sync.Mutex
+	listeners []func(*Config)
+}
+
+// NewWatcher creates a Watcher serving cfg until the first reload
+func NewWatcher(cfg *Config, logger *logrus.Logger) *Watcher {
+	w := &Watcher{
+		args:   os.Args[1:],
+		logger: logger,
+	}
+	w.current.Store(cfg)
+	return w
+}
+
+// Current returns the active configuration
+func (w *Watcher) Current() *Config {
+	return w.current.Load()
+}
+
+// OnReload registers fn to be called with the new configuration after each successful reload
+func (w *Watcher) OnReload(fn func(*Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.listeners = append(w.listeners, fn)
+}
+
+// Reload re-reads the config file, env and flags. Invalid configuration is
+// reported and the running configuration is kept.
+func (w *Watcher) Reload() {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	prev := w.Current()
+
+	next, err := load(w.args)
+	if err != nil {
+		w.logger.WithError(err).Error("Config reload failed, keeping current configuration")
+		return
+	}
+
+	if changed := next.ListenerChanges(prev); len(changed) > 0 {
+		w.logger.WithField("settings", changed).Warn("Listener settings changed, restart required for them to take effect")
+	}
+	next.retainListenerSettings(prev)
+
+	w.current.Store(next)
+	w.logger.WithFields(next.LogFields()).Info("Configuration reloaded")
+
+	w.mu.Lock()
+	listeners := append([]func(*Config){}, w.listeners...)
+	w.mu.Unlock()
+	for _, fn := range listeners {
+		fn(next)
+	}
+}
+
+// Run reloads on SIGHUP and on changes to the config file until ctx is done
+func (w *Watcher) Run(ctx context.Context) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	watchErr := make(chan error, 1)
+	if path := w.Current().ConfigFile; path != "" {
+		isConfig := func(name string) bool {
+			return filepath.Clean(name) == filepath.Clean(path)
+		}
+		reload := func() {
+			w.logger.WithField("file", path).Info("Config file changed, reloading configuration")
+			w.Reload()
+		}
+		onError := func(err error) {
+			w.logger.WithError(err).Warn("Config file watcher error")
+		}
+		go func() { watchErr <- watch.Dir(ctx, filepath.Dir(path), isConfig, reload, onError) }()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			w.logger.Info("Received SIGHUP, reloading configuration")
+			w.Reload()
+		case err := <-watchErr:
+			return err
+		}
+	}
+}
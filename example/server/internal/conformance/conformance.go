@@ -0,0 +1,184 @@
+// Package conformance checks the event stream of an AG-UI endpoint against the
+// protocol: the run lifecycle, pairing of messages, tool calls, steps and
+// thinking blocks, consistent IDs, valid JSON Patch state deltas and nothing
+// after the run has ended. It works against any server that accepts a
+// RunAgentInput and answers with server-sent events.
+package conformance
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ag-ui-protocol/ag-ui/sdks/community/go/pkg/core/events"
+)
+
+// maxFrameSize bounds a single SSE frame
+const maxFrameSize = 4 << 20
+
+// Rules, as reported in Violation.Rule
+const (
+	RuleTransport  = "transport"
+	RuleDecode     = "decode"
+	RuleValid      = "valid"
+	RuleLifecycle  = "lifecycle"
+	RuleAfterEnd   = "after-end"
+	RuleIDs        = "ids"
+	RuleMessages   = "messages"
+	RuleToolCalls  = "tool-calls"
+	RuleSteps      = "steps"
+	RuleThinking   = "thinking"
+	RuleStateDelta = "state-delta"
+)
+
+// Violation is a protocol rule the stream broke
+type Violation struct {
+	// Index is the position of the offending event, -1 for the stream as a whole
+	Index   int
+	Rule    string
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Index < 0 {
+		return fmt.Sprintf("[%s] %s", v.Rule, v.Message)
+	}
+	return fmt.Sprintf("#%d [%s] %s", v.Index, v.Rule, v.Message)
+}
+
+// Report is the outcome of checking one stream
+type Report struct {
+	Endpoint   string
+	Events     []events.Event
+	Violations []Violation
+}
+
+// Passed reports whether the stream broke no rules
+func (r *Report) Passed() bool {
+	return len(r.Violations) == 0
+}
+
+// String renders the report with every event and the violations it caused
+func (r *Report) String() string {
+	byIndex := map[int][]Violation{}
+	for _, v := range r.Violations {
+		byIndex[v.Index] = append(byIndex[v.Index], v)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "AG-UI conformance report for %s\n", r.Endpoint)
+	fmt.Fprintf(&b, "%d events, %d violations\n\n", len(r.Events), len(r.Violations))
+	for i, event := range r.Events {
+		fmt.Fprintf(&b, "  %3d  %s\n", i, event.Type())
+		for _, v := range byIndex[i] {
+			fmt.Fprintf(&b, "       ✗ [%s] %s\n", v.Rule, v.Message)
+		}
+	}
+	for _, v := range byIndex[-1] {
+		fmt.Fprintf(&b, "  ✗ [%s] %s\n", v.Rule, v.Message)
+	}
+
+	if r.Passed() {
+		b.WriteString("\nPASS\n")
+	} else {
+		b.WriteString("\nFAIL\n")
+	}
+	return b.String()
+}
+
+// Run posts input to endpoint, decodes the streamed events with the SDK
+// decoder and checks them. An error means no stream could be read at all.
+func Run(ctx context.Context, client *http.Client, endpoint string, input any) (*Report, error) {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("encode input: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("post to %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("post to %s: %s", endpoint, resp.Status)
+	}
+
+	report := &Report{Endpoint: endpoint}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/event-stream") {
+		report.Violations = append(report.Violations, Violation{Index: -1, Rule: RuleTransport,
+			Message: fmt.Sprintf("Content-Type is '%s', expected text/event-stream", contentType)})
+	}
+
+	decoder := events.NewEventDecoder(nil)
+	err = readFrames(resp, func(data []byte) {
+		event, err := decode(decoder, data)
+		if err != nil {
+			report.Violations = append(report.Violations, Violation{Index: len(report.Events), Rule: RuleDecode, Message: err.Error()})
+			return
+		}
+		report.Events = append(report.Events, event)
+	})
+	if err != nil {
+		report.Violations = append(report.Violations, Violation{Index: -1, Rule: RuleTransport, Message: err.Error()})
+	}
+
+	report.Violations = append(report.Violations, Check(report.Events)...)
+	return report, nil
+}
+
+// readFrames calls fn with the data of every SSE frame in the response
+func readFrames(resp *http.Response, fn func(data []byte)) error {
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxFrameSize)
+
+	var data []string
+	flush := func() {
+		if len(data) > 0 {
+			fn([]byte(strings.Join(data, "\n")))
+			data = nil
+		}
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read stream: %w", err)
+	}
+	return nil
+}
+
+// decode reads an event, taking its type from the frame's "type" field
+func decode(decoder *events.EventDecoder, data []byte) (events.Event, error) {
+	var frame struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return nil, fmt.Errorf("frame is not a JSON object: %w", err)
+	}
+	if frame.Type == "" {
+		return nil, fmt.Errorf("frame has no type: %s", data)
+	}
+	event, err := decoder.DecodeEvent(frame.Type, data)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", frame.Type, err)
+	}
+	return event, nil
+}
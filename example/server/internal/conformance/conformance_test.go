@@ -0,0 +1,147 @@
+package conformance
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ag-ui-protocol/ag-ui/sdks/community/go/pkg/core/events"
+	"github.com/gofiber/fiber/v3"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/agentic"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/config"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/mcp"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/routes"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/runs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// input is the RunAgentInput sent to the endpoints under test
+var input = map[string]any{
+	"threadId":       "thread-conformance",
+	"runId":          "run-conformance",
+	"state":          map[string]any{},
+	"messages":       []map[string]any{{"id": "msg-1", "role": "user", "content": "Which language should I learn?"}},
+	"tools":          []any{},
+	"context":        []any{},
+	"forwardedProps": map[string]any{},
+}
+
+func TestCheck(t *testing.T) {
+	started := events.NewRunStartedEvent("t", "r")
+	finished := events.NewRunFinishedEvent("t", "r")
+
+	tests := []struct {
+		name   string
+		events []events.Event
+		rules  []string
+	}{
+		{"complete run", []events.Event{
+			started,
+			events.NewTextMessageStartEvent("m", events.WithRole("assistant")),
+			events.NewTextMessageContentEvent("m", "hi"),
+			events.NewTextMessageEndEvent("m"),
+			events.NewToolCallStartEvent("c", "search"),
+			events.NewToolCallArgsEvent("c", "{}"),
+			events.NewToolCallEndEvent("c"),
+			events.NewToolCallResultEvent("m2", "c", "found"),
+			events.NewStateDeltaEvent([]events.JSONPatchOperation{{Op: "add", Path: "/a~1b", Value: 1}}),
+			finished,
+		}, nil},
+		{"no run started", []events.Event{finished}, []string{RuleLifecycle}},
+		{"never finished", []events.Event{started}, []string{RuleLifecycle}},
+		{"events after the end", []events.Event{started, finished, events.NewStepStartedEvent("s")}, []string{RuleAfterEnd}},
+		{"mismatched run", []events.Event{started, events.NewRunFinishedEvent("t", "other")}, []string{RuleIDs}},
+		{"content without start", []events.Event{started, events.NewTextMessageContentEvent("m", "hi"), finished}, []string{RuleMessages}},
+		{"message left open", []events.Event{started, events.NewTextMessageStartEvent("m"), finished}, []string{RuleMessages}},
+		{"result before end", []events.Event{
+			started,
+			events.NewToolCallStartEvent("c", "search"),
+			events.NewToolCallResultEvent("m", "c", "found"),
+			events.NewToolCallEndEvent("c"),
+			finished,
+		}, []string{RuleToolCalls}},
+		{"invalid patch", []events.Event{
+			started,
+			events.NewStateDeltaEvent([]events.JSONPatchOperation{{Op: "merge", Path: "/a"}, {Op: "move", Path: "a"}}),
+			finished,
+		}, []string{RuleStateDelta, RuleStateDelta}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules []string
+			for _, v := range Check(tt.events) {
+				rules = append(rules, v.Rule)
+			}
+			assert.Equal(t, tt.rules, rules)
+		})
+	}
+}
+
+// TestServerConformance checks our /agentic endpoint, with the model and MCP
+// tools replayed from a cassette. Runs that fail must end with a RUN_ERROR too.
+func TestServerConformance(t *testing.T) {
+	tests := []struct {
+		name     string
+		cassette string
+		code     string
+	}{
+		{"complete run", "../routes/testdata/languages.json", ""},
+		{"model error", "../routes/testdata/failing.json", "agent_error"},
+		{"missing cassette", "../routes/testdata/missing.json", "cassette"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.New()
+			cfg.EmbeddedMCP = false
+			cfg.Cassette = tt.cassette
+			cfg.CassetteMode = "replay"
+
+			registry := mcp.NewRegistry()
+			defer registry.Close()
+			app := fiber.New()
+			app.Post("/agentic", routes.AgenticHandler(cfg, routes.Deps{Tracker: runs.NewTracker(), Adapters: registry, Interactions: agentic.NewInteractions()}))
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			go app.Listener(listener, fiber.ListenConfig{DisableStartupMessage: true})
+			defer app.Shutdown()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			report, err := Run(ctx, http.DefaultClient, "http://"+listener.Addr().String()+"/agentic", input)
+			require.NoError(t, err)
+			assert.True(t, report.Passed(), report.String())
+			require.NotEmpty(t, report.Events)
+
+			last := report.Events[len(report.Events)-1]
+			if tt.code == "" {
+				assert.Equal(t, events.EventTypeRunFinished, last.Type())
+				return
+			}
+			require.Equal(t, events.EventTypeRunError, last.Type())
+			assert.Equal(t, tt.code, *last.(*events.RunErrorEvent).Code)
+		})
+	}
+}
+
+// TestEndpointConformance checks the AG-UI endpoint at AGUI_CONFORMANCE_URL,
+// which may be any server
+func TestEndpointConformance(t *testing.T) {
+	endpoint := os.Getenv("AGUI_CONFORMANCE_URL")
+	if endpoint == "" {
+		t.Skip("AGUI_CONFORMANCE_URL is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	report, err := Run(ctx, http.DefaultClient, endpoint, input)
+	require.NoError(t, err)
+	t.Log("\n" + report.String())
+	assert.True(t, report.Passed())
+}
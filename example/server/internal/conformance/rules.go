@@ -0,0 +1,230 @@
+package conformance
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/ag-ui-protocol/ag-ui/sdks/community/go/pkg/core/events"
+)
+
+// Check returns the protocol rules broken by a run's events
+func Check(evts []events.Event) []Violation {
+	c := &checker{
+		messages:       map[string]bool{},
+		toolCalls:      map[string]bool{},
+		endedToolCalls: map[string]bool{},
+		steps:          map[string]bool{},
+	}
+	for i, event := range evts {
+		c.index = i
+		c.check(event)
+	}
+
+	c.index = -1
+	if len(evts) == 0 {
+		c.fail(RuleLifecycle, "the stream has no events")
+	} else if !c.ended {
+		c.fail(RuleLifecycle, "the stream ended without RUN_FINISHED or RUN_ERROR")
+	}
+	return c.violations
+}
+
+// checker tracks what is open while walking a stream
+type checker struct {
+	index      int
+	violations []Violation
+
+	started  *events.RunStartedEvent
+	ended    bool
+	thinking bool
+	// thinkingMessage is set between THINKING_TEXT_MESSAGE_START and _END
+	thinkingMessage bool
+
+	// messages, toolCalls and steps hold the IDs or names that are open
+	messages       map[string]bool
+	toolCalls      map[string]bool
+	endedToolCalls map[string]bool
+	steps          map[string]bool
+}
+
+func (c *checker) fail(rule, format string, args ...any) {
+	c.violations = append(c.violations, Violation{Index: c.index, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) check(event events.Event) {
+	if err := event.Validate(); err != nil {
+		c.fail(RuleValid, "%s", err)
+	}
+	if c.ended {
+		c.fail(RuleAfterEnd, "%s sent after the run ended", event.Type())
+		return
+	}
+	if c.index == 0 && event.Type() != events.EventTypeRunStarted {
+		c.fail(RuleLifecycle, "the first event is %s, expected RUN_STARTED", event.Type())
+	}
+
+	switch e := event.(type) {
+	case *events.RunStartedEvent:
+		if c.started != nil {
+			c.fail(RuleLifecycle, "RUN_STARTED while run %s is active", c.started.RunID())
+			return
+		}
+		c.started = e
+		if e.ThreadID() == "" || e.RunID() == "" {
+			c.fail(RuleIDs, "RUN_STARTED needs a threadId and a runId")
+		}
+	case *events.RunFinishedEvent:
+		c.ended = true
+		if c.started == nil {
+			return
+		}
+		if e.ThreadID() != c.started.ThreadID() || e.RunID() != c.started.RunID() {
+			c.fail(RuleIDs, "RUN_FINISHED is for thread %s run %s, the run started was thread %s run %s",
+				e.ThreadID(), e.RunID(), c.started.ThreadID(), c.started.RunID())
+		}
+		c.failOpen(RuleMessages, "text message", c.messages)
+		c.failOpen(RuleToolCalls, "tool call", c.toolCalls)
+		c.failOpen(RuleSteps, "step", c.steps)
+		if c.thinking {
+			c.fail(RuleThinking, "the run finished while thinking")
+		}
+	case *events.RunErrorEvent:
+		// An error may abort the run at any point
+		c.ended = true
+
+	case *events.TextMessageStartEvent:
+		c.open(RuleMessages, "TEXT_MESSAGE_START", "message", e.MessageID, c.messages)
+	case *events.TextMessageContentEvent:
+		c.requireOpen(RuleMessages, "TEXT_MESSAGE_CONTENT", "message", e.MessageID, c.messages)
+		if e.Delta == "" {
+			c.fail(RuleMessages, "TEXT_MESSAGE_CONTENT for message %s has an empty delta", e.MessageID)
+		}
+	case *events.TextMessageEndEvent:
+		if c.requireOpen(RuleMessages, "TEXT_MESSAGE_END", "message", e.MessageID, c.messages) {
+			delete(c.messages, e.MessageID)
+		}
+
+	case *events.ToolCallStartEvent:
+		c.open(RuleToolCalls, "TOOL_CALL_START", "tool call", e.ToolCallID, c.toolCalls)
+		if e.ToolCallName == "" {
+			c.fail(RuleToolCalls, "TOOL_CALL_START for %s has no toolCallName", e.ToolCallID)
+		}
+	case *events.ToolCallArgsEvent:
+		c.requireOpen(RuleToolCalls, "TOOL_CALL_ARGS", "tool call", e.ToolCallID, c.toolCalls)
+	case *events.ToolCallEndEvent:
+		if c.requireOpen(RuleToolCalls, "TOOL_CALL_END", "tool call", e.ToolCallID, c.toolCalls) {
+			delete(c.toolCalls, e.ToolCallID)
+			c.endedToolCalls[e.ToolCallID] = true
+		}
+	case *events.ToolCallResultEvent:
+		if !c.endedToolCalls[e.ToolCallID] {
+			c.fail(RuleToolCalls, "TOOL_CALL_RESULT for tool call %s, which has not ended", e.ToolCallID)
+		}
+		if e.MessageID == "" {
+			c.fail(RuleIDs, "TOOL_CALL_RESULT for %s has no messageId", e.ToolCallID)
+		}
+
+	case *events.StepStartedEvent:
+		c.open(RuleSteps, "STEP_STARTED", "step", e.StepName, c.steps)
+	case *events.StepFinishedEvent:
+		if c.requireOpen(RuleSteps, "STEP_FINISHED", "step", e.StepName, c.steps) {
+			delete(c.steps, e.StepName)
+		}
+
+	case *events.ThinkingStartEvent:
+		if c.thinking {
+			c.fail(RuleThinking, "THINKING_START while already thinking")
+		}
+		c.thinking = true
+	case *events.ThinkingEndEvent:
+		if !c.thinking || c.thinkingMessage {
+			c.fail(RuleThinking, "THINKING_END without an open thinking block, or with its message still open")
+		}
+		c.thinking, c.thinkingMessage = false, false
+	case *events.ThinkingTextMessageStartEvent:
+		if !c.thinking || c.thinkingMessage {
+			c.fail(RuleThinking, "THINKING_TEXT_MESSAGE_START outside a thinking block or inside another message")
+		}
+		c.thinkingMessage = true
+	case *events.ThinkingTextMessageContentEvent:
+		if !c.thinkingMessage {
+			c.fail(RuleThinking, "THINKING_TEXT_MESSAGE_CONTENT without THINKING_TEXT_MESSAGE_START")
+		}
+	case *events.ThinkingTextMessageEndEvent:
+		if !c.thinkingMessage {
+			c.fail(RuleThinking, "THINKING_TEXT_MESSAGE_END without THINKING_TEXT_MESSAGE_START")
+		}
+		c.thinkingMessage = false
+
+	case *events.StateDeltaEvent:
+		for i, op := range e.Delta {
+			if err := checkPatchOperation(op); err != nil {
+				c.fail(RuleStateDelta, "operation %d: %s", i, err)
+			}
+		}
+	}
+}
+
+// open marks id as open, it must not be open already
+func (c *checker) open(rule, eventType, kind, id string, open map[string]bool) {
+	if id == "" {
+		c.fail(RuleIDs, "%s has no %s ID", eventType, kind)
+		return
+	}
+	if open[id] {
+		c.fail(rule, "%s for %s %s, which is already open", eventType, kind, id)
+	}
+	open[id] = true
+}
+
+// requireOpen reports whether id is open, failing rule when it is not
+func (c *checker) requireOpen(rule, eventType, kind, id string, open map[string]bool) bool {
+	if id == "" {
+		c.fail(RuleIDs, "%s has no %s ID", eventType, kind)
+		return false
+	}
+	if !open[id] {
+		c.fail(rule, "%s for %s %s, which was not started", eventType, kind, id)
+		return false
+	}
+	return true
+}
+
+// failOpen fails rule for every ID still open when the run finished
+func (c *checker) failOpen(rule, kind string, open map[string]bool) {
+	for _, id := range slices.Sorted(maps.Keys(open)) {
+		c.fail(rule, "the run finished with %s %s still open", kind, id)
+	}
+}
+
+// checkPatchOperation validates a JSON Patch (RFC 6902) operation
+func checkPatchOperation(op events.JSONPatchOperation) error {
+	switch op.Op {
+	case "add", "remove", "replace", "test":
+	case "move", "copy":
+		if err := checkPointer(op.From); err != nil {
+			return fmt.Errorf("%s from: %w", op.Op, err)
+		}
+	default:
+		return fmt.Errorf("unknown op '%s'", op.Op)
+	}
+	if err := checkPointer(op.Path); err != nil {
+		return fmt.Errorf("%s path: %w", op.Op, err)
+	}
+	return nil
+}
+
+// checkPointer validates a JSON Pointer (RFC 6901)
+func checkPointer(pointer string) error {
+	if pointer != "" && !strings.HasPrefix(pointer, "/") {
+		return fmt.Errorf("'%s' is not a JSON Pointer, it must be empty or start with /", pointer)
+	}
+	for i := 0; i < len(pointer); i++ {
+		if pointer[i] == '~' && (i+1 == len(pointer) || (pointer[i+1] != '0' && pointer[i+1] != '1')) {
+			return fmt.Errorf("'%s' has an invalid escape, ~ must be followed by 0 or 1", pointer)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCheckTimeout bounds how long a single readiness check may take
+const DefaultCheckTimeout = 3 * time.Second
+
+// Check reports whether a dependency is usable, returning nil when it is
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// CheckResult is the outcome of a single readiness check
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the outcome of all readiness checks
+type Report struct {
+	Ready  bool                   `json:"ready"`
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Checker runs the registered readiness checks. Once shutdown has started it
+// reports not ready without running any checks, so load balancers drain traffic.
+type Checker struct {
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+
+	mu     sync.RWMutex
+	checks []namedCheck
+}
+
+// NewChecker creates a Checker with the default per-check timeout
+func NewChecker() *Checker {
+	return &Checker{timeout: DefaultCheckTimeout}
+}
+
+// Register adds a named readiness check
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// SetShuttingDown marks the server as draining, readiness fails from now on
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// ShuttingDown reports whether SetShuttingDown has been called
+func (c *Checker) ShuttingDown() bool {
+	return c.shuttingDown.Load()
+}
+
+// Ready runs all checks concurrently and aggregates the results
+func (c *Checker) Ready(ctx context.Context) Report {
+	if c.ShuttingDown() {
+		return Report{Ready: false, Status: "shutting_down"}
+	}
+
+	c.mu.RLock()
+	checks := append([]namedCheck(nil), c.checks...)
+	c.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, nc := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, nc.check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Ready: true, Status: "ready", Checks: make(map[string]CheckResult, len(checks))}
+	for i, nc := range checks {
+		report.Checks[nc.name] = results[i]
+		if results[i].Error != "" {
+			report.Ready = false
+			report.Status = "not_ready"
+		}
+	}
+	return report
+}
+
+// run executes check with the per-check timeout. The check runs in its own
+// goroutine so one that ignores its context cannot stall the whole probe.
+func (c *Checker) run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timed out: %w", ctx.Err())
+	}
+
+	result := CheckResult{Status: "ok", Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = "failing"
+		result.Error = err.Error()
+	}
+	return result
+}
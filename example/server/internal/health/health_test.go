@@ -0,0 +1,42 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	checker := NewChecker()
+	checker.Register("ok", func(ctx context.Context) error { return nil })
+	report := checker.Ready(context.Background())
+	assert.True(t, report.Ready)
+	assert.Equal(t, "ready", report.Status)
+	assert.Equal(t, "ok", report.Checks["ok"].Status)
+
+	// One failing check fails readiness and is reported with its error
+	checker.Register("broken", func(ctx context.Context) error { return errors.New("connection refused") })
+	report = checker.Ready(context.Background())
+	assert.False(t, report.Ready)
+	assert.Equal(t, "not_ready", report.Status)
+	assert.Equal(t, CheckResult{Status: "failing", Error: "connection refused", Duration: report.Checks["broken"].Duration}, report.Checks["broken"])
+	assert.Equal(t, "ok", report.Checks["ok"].Status)
+
+	// A check ignoring its context times out
+	checker = &Checker{timeout: 10 * time.Millisecond}
+	checker.Register("hanging", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	report = checker.Ready(context.Background())
+	assert.False(t, report.Ready)
+	assert.Contains(t, report.Checks["hanging"].Error, "check timed out")
+
+	// Draining servers are not ready, without running the checks
+	checker.SetShuttingDown()
+	assert.True(t, checker.ShuttingDown())
+	assert.Equal(t, Report{Ready: false, Status: "shutting_down"}, checker.Ready(context.Background()))
+}
@@ -1,53 +1,306 @@
 package mcp
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"time"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
 
-	mcpadapter "github.com/i2y/langchaingo-mcp-adapter"
 	"github.com/mark3labs/mcp-go/client"
 	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
 	langchaingoTools "github.com/tmc/langchaingo/tools"
 )
 
+// Adapter exposes an MCP server's tools to langchaingo. The tool set is
+// refreshed whenever the server sends notifications/tools/list_changed, and
+// elicitation and sampling requests are answered by the CallHandlers of the
+// tool calls in flight, see WithCallHandlers. A connection that fails, for example because
+// the server restarted, is replaced by a newly initialized one.
 type Adapter struct {
-	adapter   *mcpadapter.MCPAdapter
-	mcpClient *client.Client
+	endpoint string
+	calls    *callRouter
+	progress *progressRouter
+	breaker  *breaker
+
+	// reconnectMu serializes reconnects so concurrent failures replace the
+	// connection once
+	reconnectMu sync.Mutex
+
+	mu         sync.Mutex
+	mcpClient  *client.Client
+	httpClient *http.Client // the connection's own pool, nil for stdio
+	generation uint64       // incremented on every reconnect
+	closed     bool
+	tools      []langchaingoTools.Tool
+	listeners  []func([]langchaingoTools.Tool)
 }
 
 func NewAdapter(endpoint string) (*Adapter, error) {
-	httpTransport, err := getTransport(endpoint)
-	if err != nil {
+	return newAdapter(endpoint, &breaker{})
+}
+
+// newAdapter connects to endpoint, counting call failures in breaker
+func newAdapter(endpoint string, breaker *breaker) (*Adapter, error) {
+	a := &Adapter{
+		endpoint: endpoint,
+		calls:    &callRouter{},
+		progress: &progressRouter{},
+		breaker:  breaker,
+	}
+	if err := a.connect(); err != nil {
+		a.Close()
 		return nil, err
 	}
-	mcpClient := client.NewClient(httpTransport)
-	mcpClient.List
+	return a, nil
+}
+
+// connect initializes a new connection, replaces the current one with it and
+// refreshes the tool set
+func (a *Adapter) connect() error {
+	// Each connection gets its own pool so Close can drop the idle sockets,
+	// which would otherwise hold up the MCP server's graceful shutdown
+	var httpClient *http.Client
+	if !strings.HasPrefix(a.endpoint, "stdio:") {
+		httpClient = &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+	}
+	httpTransport, err := getTransport(a.endpoint, httpClient)
+	if err != nil {
+		return err
+	}
+	mcpClient := client.NewClient(httpTransport,
+		client.WithElicitationHandler(a.calls),
+		client.WithSamplingHandler(a.calls),
+	)
+
+	// Stdio spawns the server process and SSE opens its event stream here
+	if err := mcpClient.Start(context.Background()); err != nil {
+		return fmt.Errorf("start mcp client: %w", err)
+	}
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    "ag-ui-example-server",
+		Version: "1.0.0",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultToolTimeout)
+	defer cancel()
+	if _, err := mcpClient.Initialize(ctx, initRequest); err != nil {
+		mcpClient.Close()
+		return fmt.Errorf("initialize mcp client: %w", err)
+	}
+
+	mcpClient.OnNotification(func(notification mcp.JSONRPCNotification) {
+		switch notification.Method {
+		case mcp.MethodNotificationToolsListChanged:
+			// Notifications arrive on the transport's read loop, which must not block on a request
+			go func() {
+				if err := a.refreshTools(); err != nil {
+					slog.Warn("Failed to refresh MCP tools", "endpoint", a.endpoint, "error", err)
+				}
+			}()
+		case methodNotificationProgress:
+			// Handled in order, before the result of the call they belong to
+			a.progress.dispatch(notification.Params.AdditionalFields)
+		}
+	})
+
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		closeClient(mcpClient, httpClient)
+		return errAdapterClosed
+	}
+	oldClient, oldHTTPClient := a.mcpClient, a.httpClient
+	a.mcpClient, a.httpClient = mcpClient, httpClient
+	a.generation++
+	a.mu.Unlock()
+
+	if oldClient != nil {
+		closeClient(oldClient, oldHTTPClient)
+	}
+	return a.refreshTools()
+}
+
+// errAdapterClosed is returned when reconnecting an adapter that was closed meanwhile
+var errAdapterClosed = errors.New("mcp adapter is closed")
+
+// client returns the current connection and its generation
+func (a *Adapter) client() (*client.Client, uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.mcpClient, a.generation
+}
+
+// reconnect replaces the connection of the given generation, unless another
+// caller already did
+func (a *Adapter) reconnect(failed uint64) error {
+	a.reconnectMu.Lock()
+	defer a.reconnectMu.Unlock()
+
+	if _, generation := a.client(); generation != failed {
+		return nil
+	}
+	if err := a.connect(); err != nil {
+		return fmt.Errorf("reconnect to %s: %w", a.endpoint, err)
+	}
+	slog.Info("Reconnected to MCP server", "endpoint", a.endpoint)
+	return nil
+}
+
+// Reconnect replaces the current connection with a new one
+func (a *Adapter) Reconnect() error {
+	_, generation := a.client()
+	return a.reconnect(generation)
+}
+
+// refreshTools fetches the current tool list and notifies OnToolsChanged listeners
+func (a *Adapter) refreshTools() error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultToolTimeout)
+	defer cancel()
 
-	adapter, err := mcpadapter.New(mcpClient, mcpadapter.WithToolTimeout(30*time.Second))
+	mcpClient, _ := a.client()
+	list, err := mcpClient.ListTools(ctx, mcp.ListToolsRequest{})
 	if err != nil {
-		return nil, fmt.Errorf("new mcp adapter: %w", err)
+		return fmt.Errorf("list tools from %s: %w", a.endpoint, err)
 	}
-	return &Adapter{
-		adapter:   adapter,
-		mcpClient: mcpClient,
-	}, nil
+
+	tools := make([]langchaingoTools.Tool, 0, len(list.Tools))
+	for _, mcpTool := range list.Tools {
+		tool, err := newTool(mcpTool, a)
+		if err != nil {
+			return err
+		}
+		tools = append(tools, tool)
+	}
+
+	a.mu.Lock()
+	a.tools = tools
+	listeners := append([]func([]langchaingoTools.Tool){}, a.listeners...)
+	a.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(tools)
+	}
+	return nil
+}
+
+// OnToolsChanged registers fn to be called with the new tool set after each refresh
+func (a *Adapter) OnToolsChanged(fn func([]langchaingoTools.Tool)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.listeners = append(a.listeners, fn)
+}
+
+// Ping checks the connection to the server is still usable
+func (a *Adapter) Ping(ctx context.Context) error {
+	mcpClient, _ := a.client()
+	return mcpClient.Ping(ctx)
 }
 
 func (a *Adapter) Close() error {
-	return a.mcpClient.Close()
+	a.mu.Lock()
+	a.closed = true
+	mcpClient, httpClient := a.mcpClient, a.httpClient
+	a.mu.Unlock()
+	if mcpClient == nil {
+		return nil
+	}
+	return closeClient(mcpClient, httpClient)
+}
+
+func closeClient(mcpClient *client.Client, httpClient *http.Client) error {
+	err := mcpClient.Close()
+	if httpClient != nil {
+		httpClient.CloseIdleConnections()
+	}
+	return err
 }
 
+// Tools returns the server's current tool set
 func (a *Adapter) Tools() ([]langchaingoTools.Tool, error) {
-	return a.adapter.Tools()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]langchaingoTools.Tool{}, a.tools...), nil
 }
 
-func getTransport(endpoint string) (transport.Interface, error) {
-	httpTransport, err := transport.NewStreamableHTTP(
-		endpoint, // Replace with your MCP server URL
-		// You can add HTTP-specific options here like headers, OAuth, etc.
-	)
+// Prompt renders the named prompt on the server, joining the text of its messages
+func (a *Adapter) Prompt(ctx context.Context, name string, args map[string]string) (string, error) {
+	var request mcp.GetPromptRequest
+	request.Params.Name = name
+	request.Params.Arguments = args
+	mcpClient, _ := a.client()
+	result, err := mcpClient.GetPrompt(ctx, request)
 	if err != nil {
-		return nil, fmt.Errorf("create transport: %w", err)
+		return "", fmt.Errorf("get prompt %s: %w", name, err)
+	}
+
+	var parts []string
+	for _, message := range result.Messages {
+		if text, ok := message.Content.(mcp.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// Resource reads the text contents of the resource at uri
+func (a *Adapter) Resource(ctx context.Context, uri string) (string, error) {
+	var request mcp.ReadResourceRequest
+	request.Params.URI = uri
+	mcpClient, _ := a.client()
+	result, err := mcpClient.ReadResource(ctx, request)
+	if err != nil {
+		return "", fmt.Errorf("read resource %s: %w", uri, err)
+	}
+
+	var parts []string
+	for _, contents := range result.Contents {
+		if text, ok := contents.(mcp.TextResourceContents); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("resource %s has no text contents", uri)
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// getTransport picks the client transport from the endpoint:
+//
+//	http(s)://host:port/mcp       streamable HTTP
+//	sse+http(s)://host:port/sse   SSE
+//	stdio:command [args...]       stdio, spawning command as a subprocess
+//
+// The HTTP transports send their requests with httpClient.
+func getTransport(endpoint string, httpClient *http.Client) (transport.Interface, error) {
+	switch {
+	case strings.HasPrefix(endpoint, "stdio:"):
+		fields := strings.Fields(strings.TrimPrefix(endpoint, "stdio:"))
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("stdio endpoint '%s' has no command", endpoint)
+		}
+		return transport.NewStdio(fields[0], os.Environ(), fields[1:]...), nil
+	case strings.HasPrefix(endpoint, "sse+"):
+		sseTransport, err := transport.NewSSE(strings.TrimPrefix(endpoint, "sse+"), transport.WithHTTPClient(httpClient))
+		if err != nil {
+			return nil, fmt.Errorf("create transport: %w", err)
+		}
+		return sseTransport, nil
+	default:
+		// Keep a stream open so list_changed notifications arrive between requests
+		httpTransport, err := transport.NewStreamableHTTP(endpoint,
+			transport.WithContinuousListening(),
+			transport.WithHTTPBasicClient(httpClient),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("create transport: %w", err)
+		}
+		return httpTransport, nil
 	}
-	return httpTransport, nil
 }
@@ -0,0 +1,262 @@
+package mcp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxExpressionLength bounds the expressions Evaluate accepts
+const maxExpressionLength = 1000
+
+// calcFuncs are the functions Evaluate knows, by name and argument count
+var calcFuncs = map[string]struct {
+	args int
+	fn   func(args []float64) float64
+}{
+	"abs":   {1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"ceil":  {1, func(a []float64) float64 { return math.Ceil(a[0]) }},
+	"floor": {1, func(a []float64) float64 { return math.Floor(a[0]) }},
+	"round": {1, func(a []float64) float64 { return math.Round(a[0]) }},
+	"sqrt":  {1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"exp":   {1, func(a []float64) float64 { return math.Exp(a[0]) }},
+	"ln":    {1, func(a []float64) float64 { return math.Log(a[0]) }},
+	"log":   {1, func(a []float64) float64 { return math.Log10(a[0]) }},
+	"sin":   {1, func(a []float64) float64 { return math.Sin(a[0]) }},
+	"cos":   {1, func(a []float64) float64 { return math.Cos(a[0]) }},
+	"tan":   {1, func(a []float64) float64 { return math.Tan(a[0]) }},
+	"min":   {2, func(a []float64) float64 { return math.Min(a[0], a[1]) }},
+	"max":   {2, func(a []float64) float64 { return math.Max(a[0], a[1]) }},
+	"pow":   {2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+}
+
+var calcConstants = map[string]float64{"pi": math.Pi, "e": math.E}
+
+// Evaluate computes an arithmetic expression. ^ is exponentiation and binds
+// tighter than unary minus, so -2^2 is -4.
+func Evaluate(expression string) (float64, error) {
+	if len(expression) > maxExpressionLength {
+		return 0, fmt.Errorf("the expression is longer than %d characters", maxExpressionLength)
+	}
+	p := &calcParser{input: expression}
+	value, err := p.expression()
+	if err != nil {
+		return 0, err
+	}
+	if p.skipSpace(); p.pos < len(p.input) {
+		return 0, p.errorf("unexpected '%c'", p.input[p.pos])
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("the result is not a finite number")
+	}
+	return value, nil
+}
+
+// formatNumber prints whole numbers without a fraction and others with the
+// fewest digits that round-trip
+func formatNumber(value float64) string {
+	if value == math.Trunc(value) && math.Abs(value) < 1e15 {
+		return strconv.FormatFloat(value, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// calcParser is a recursive descent parser evaluating as it goes
+type calcParser struct {
+	input string
+	pos   int
+	depth int
+}
+
+func (p *calcParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("at position %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *calcParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// peek returns the next character after spaces, 0 at the end
+func (p *calcParser) peek() byte {
+	p.skipSpace()
+	if p.pos == len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// expression := term (("+" | "-") term)*
+func (p *calcParser) expression() (float64, error) {
+	value, err := p.term()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return value, nil
+		}
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			value += right
+		} else {
+			value -= right
+		}
+	}
+}
+
+// term := unary (("*" | "/" | "%") unary)*
+func (p *calcParser) term() (float64, error) {
+	value, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return value, nil
+		}
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case op == '*':
+			value *= right
+		case right == 0:
+			return 0, fmt.Errorf("division by zero")
+		case op == '/':
+			value /= right
+		default:
+			value = math.Mod(value, right)
+		}
+	}
+}
+
+// unary := ("-" | "+") unary | power
+func (p *calcParser) unary() (float64, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		value, err := p.unary()
+		return -value, err
+	case '+':
+		p.pos++
+		return p.unary()
+	}
+	return p.power()
+}
+
+// power := primary ("^" unary)?, right associative
+func (p *calcParser) power() (float64, error) {
+	base, err := p.primary()
+	if err != nil {
+		return 0, err
+	}
+	if p.peek() != '^' {
+		return base, nil
+	}
+	p.pos++
+	exponent, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(base, exponent), nil
+}
+
+// primary := number | constant | function "(" args ")" | "(" expression ")"
+func (p *calcParser) primary() (float64, error) {
+	// Nesting is bounded by the expression length, but keep the stack small
+	if p.depth++; p.depth > 100 {
+		return 0, p.errorf("the expression is nested too deeply")
+	}
+	defer func() { p.depth-- }()
+
+	c := p.peek()
+	switch {
+	case c == 0:
+		return 0, p.errorf("unexpected end of the expression")
+	case c == '(':
+		p.pos++
+		value, err := p.expression()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, p.errorf("missing )")
+		}
+		p.pos++
+		return value, nil
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.input) && strings.IndexByte("0123456789.eE_", p.input[p.pos]) >= 0 {
+			// An exponent may be signed
+			if (p.input[p.pos] == 'e' || p.input[p.pos] == 'E') && p.pos+1 < len(p.input) && (p.input[p.pos+1] == '-' || p.input[p.pos+1] == '+') {
+				p.pos++
+			}
+			p.pos++
+		}
+		value, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+		if err != nil {
+			return 0, fmt.Errorf("at position %d: invalid number '%s'", start+1, p.input[start:p.pos])
+		}
+		return value, nil
+	case unicode.IsLetter(rune(c)):
+		start := p.pos
+		for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) || unicode.IsDigit(rune(p.input[p.pos]))) {
+			p.pos++
+		}
+		name := strings.ToLower(p.input[start:p.pos])
+		if value, ok := calcConstants[name]; ok {
+			return value, nil
+		}
+		fn, ok := calcFuncs[name]
+		if !ok {
+			return 0, fmt.Errorf("at position %d: unknown name '%s'", start+1, name)
+		}
+		args, err := p.arguments()
+		if err != nil {
+			return 0, err
+		}
+		if len(args) != fn.args {
+			return 0, fmt.Errorf("at position %d: %s takes %d arguments, got %d", start+1, name, fn.args, len(args))
+		}
+		return fn.fn(args), nil
+	default:
+		return 0, p.errorf("unexpected '%c'", c)
+	}
+}
+
+// arguments := "(" expression ("," expression)* ")"
+func (p *calcParser) arguments() ([]float64, error) {
+	if p.peek() != '(' {
+		return nil, p.errorf("expected ( after the function name")
+	}
+	p.pos++
+	var args []float64
+	for {
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return args, nil
+		default:
+			return nil, p.errorf("expected , or )")
+		}
+	}
+}
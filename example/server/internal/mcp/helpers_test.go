@@ -0,0 +1,68 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// stdioServerEnv makes the test binary serve greetTool over stdio instead of
+// running the tests, noting each start in the file it names
+const stdioServerEnv = "MCP_TEST_STDIO_SERVER"
+
+func TestMain(m *testing.M) {
+	if starts := os.Getenv(stdioServerEnv); starts != "" {
+		serveStdio(starts)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func serveStdio(starts string) {
+	f, err := os.OpenFile(starts, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err == nil {
+		fmt.Fprintln(f, os.Getpid())
+		f.Close()
+	}
+	server, err := NewServer(0, WithTransport(TransportStdio), WithTools(greetTool))
+	if err == nil {
+		err = server.Start()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func startServer(t *testing.T, port int, opts ...Option) *Server {
+	t.Helper()
+
+	server, err := NewServer(port, opts...)
+	require.NoError(t, err)
+	go server.Start()
+	require.Eventually(t, func() bool {
+		return server.CheckListening(context.Background()) == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return server
+}
+
+func stopServer(server *Server) {
+	// The adapter's notification stream keeps the server busy, don't wait for it
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	server.Shutdown(ctx)
+}
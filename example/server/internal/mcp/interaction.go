@@ -0,0 +1,156 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ElicitFunc asks the user for the input described by an elicitation request
+type ElicitFunc func(ctx context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error)
+
+// SampleFunc generates a completion for a sampling request with the host's model
+type SampleFunc func(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error)
+
+// CallHandlers answer the requests a server sends back while one of its tools
+// is running. Either may be nil, elicitations are then declined and sampling
+// requests fail.
+type CallHandlers struct {
+	Elicit ElicitFunc
+	Sample SampleFunc
+}
+
+type callHandlersKey struct{}
+
+// runCall holds the handlers of a context's tool calls and the connections
+// they are made on
+type runCall struct {
+	handlers    CallHandlers
+	connections *callConnections
+}
+
+// WithCallHandlers returns a context whose tool calls answer elicitation and
+// sampling requests with handlers. MCP requests do not say which call they are
+// for, so calls to tools marked with InteractiveMeta are made on connections
+// of their own, opened on first use of a server and closed by the returned
+// func, and their requests reach no other handlers. Other tools use the shared
+// connection, a request they send goes to the oldest call in flight on it.
+func WithCallHandlers(ctx context.Context, handlers CallHandlers) (context.Context, func()) {
+	connections := &callConnections{endpoints: map[string]*endpointAdapter{}}
+	return context.WithValue(ctx, callHandlersKey{}, runCall{handlers: handlers, connections: connections}), connections.close
+}
+
+// callConnections are the connections of the tool calls made with one set of
+// CallHandlers, one per server
+type callConnections struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpointAdapter
+}
+
+// adapter returns the connection to the server of shared, which shares its
+// circuit breaker
+func (c *callConnections) adapter(shared *Adapter) (*Adapter, error) {
+	c.mu.Lock()
+	entry, ok := c.endpoints[shared.endpoint]
+	if !ok {
+		entry = &endpointAdapter{}
+		c.endpoints[shared.endpoint] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.closed {
+		return nil, errAdapterClosed
+	}
+	if entry.adapter == nil {
+		adapter, err := newAdapter(shared.endpoint, shared.breaker)
+		if err != nil {
+			return nil, err
+		}
+		entry.adapter = adapter
+	}
+	return entry.adapter, nil
+}
+
+func (c *callConnections) close() {
+	c.mu.Lock()
+	endpoints := c.endpoints
+	c.endpoints = map[string]*endpointAdapter{}
+	c.mu.Unlock()
+	closeEndpoints(endpoints)
+}
+
+// errNoSampler is returned to servers that ask for sampling when no tool call
+// in flight can answer it
+var errNoSampler = errors.New("sampling is not available to this tool call")
+
+// callRouter hands server requests to the tool calls in flight on a connection.
+// MCP requests carry no reference to the call that caused them, so they go to
+// the oldest call with a handler for them. Interactive tools have a
+// connection per set of handlers, see WithCallHandlers, so there the handlers
+// found are those of the call.
+type callRouter struct {
+	mu     sync.Mutex
+	nextID uint64
+	calls  []activeCall
+}
+
+type activeCall struct {
+	id       uint64
+	handlers CallHandlers
+}
+
+// begin registers the handlers of a tool call until the returned func is called
+func (r *callRouter) begin(handlers CallHandlers) func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	id := r.nextID
+	r.calls = append(r.calls, activeCall{id: id, handlers: handlers})
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i, call := range r.calls {
+			if call.id == id {
+				r.calls = append(r.calls[:i], r.calls[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// find returns the oldest in-flight handlers accepted by has
+func (r *callRouter) find(has func(CallHandlers) bool) (CallHandlers, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, call := range r.calls {
+		if has(call.handlers) {
+			return call.handlers, true
+		}
+	}
+	return CallHandlers{}, false
+}
+
+// Elicit implements client.ElicitationHandler
+func (r *callRouter) Elicit(ctx context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	handlers, ok := r.find(func(h CallHandlers) bool { return h.Elicit != nil })
+	if !ok {
+		return &mcp.ElicitationResult{
+			ElicitationResponse: mcp.ElicitationResponse{Action: mcp.ElicitationResponseActionDecline},
+		}, nil
+	}
+	return handlers.Elicit(ctx, request)
+}
+
+// CreateMessage implements client.SamplingHandler
+func (r *callRouter) CreateMessage(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	handlers, ok := r.find(func(h CallHandlers) bool { return h.Sample != nil })
+	if !ok {
+		return nil, errNoSampler
+	}
+	return handlers.Sample(ctx, request)
+}
@@ -0,0 +1,194 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// greetTool asks the user for their name, then has the host's model greet them
+var greetTool = ToolDef{
+	Name:        "greet",
+	Description: "Greets the user",
+	Interactive: true,
+	Handler: func(ctx context.Context, args map[string]any) (any, error) {
+		s := server.ServerFromContext(ctx)
+
+		var elicit mcp.ElicitationRequest
+		elicit.Params.Message = "What is your name?"
+		elicit.Params.RequestedSchema = map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"name": map[string]any{"type": "string"}},
+		}
+		answer, err := s.RequestElicitation(ctx, elicit)
+		if err != nil {
+			return nil, err
+		}
+		if answer.Action != mcp.ElicitationResponseActionAccept {
+			return "no name given", nil
+		}
+		name := answer.Content.(map[string]any)["name"]
+
+		var sample mcp.CreateMessageRequest
+		sample.Messages = []mcp.SamplingMessage{{
+			Role:    mcp.RoleUser,
+			Content: mcp.NewTextContent(fmt.Sprintf("Greet %s", name)),
+		}}
+		sample.MaxTokens = 50
+		completion, err := s.RequestSampling(ctx, sample)
+		if err != nil {
+			return nil, err
+		}
+		return completion.Content.(mcp.TextContent).Text, nil
+	},
+}
+
+func TestAdapterCallHandlers(t *testing.T) {
+	mcpServer := startServer(t, freePort(t), WithTools(greetTool))
+	defer stopServer(mcpServer)
+
+	adapter, err := NewAdapter(mcpServer.Endpoint())
+	require.NoError(t, err)
+	defer adapter.Close()
+
+	tools, err := adapter.Tools()
+	require.NoError(t, err)
+	var greet *Tool
+	for _, tool := range tools {
+		if tool.Name() == "greet" {
+			greet = tool.(*Tool)
+		}
+	}
+	require.NotNil(t, greet)
+
+	ctx, closeCalls := WithCallHandlers(context.Background(), CallHandlers{
+		Elicit: func(ctx context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+			assert.Equal(t, "What is your name?", request.Params.Message)
+			return &mcp.ElicitationResult{ElicitationResponse: mcp.ElicitationResponse{
+				Action:  mcp.ElicitationResponseActionAccept,
+				Content: map[string]any{"name": "Ada"},
+			}}, nil
+		},
+		Sample: func(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+			prompt := request.Messages[0].Content.(mcp.TextContent).Text
+			return &mcp.CreateMessageResult{
+				SamplingMessage: mcp.SamplingMessage{Role: mcp.RoleAssistant, Content: mcp.NewTextContent(prompt + "? Hello Ada!")},
+				Model:           "test",
+			}, nil
+		},
+	})
+	defer closeCalls()
+	text, err := greet.Call(ctx, `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Greet Ada? Hello Ada!", text)
+
+	// Without handlers the elicitation is declined
+	text, err = greet.Call(context.Background(), `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, "no name given", text)
+}
+
+func TestConcurrentCallHandlers(t *testing.T) {
+	mcpServer := startServer(t, freePort(t), WithTools(greetTool))
+	defer stopServer(mcpServer)
+
+	// Two runs share the adapter, as runs share the registry's
+	adapter, err := NewAdapter(mcpServer.Endpoint())
+	require.NoError(t, err)
+	defer adapter.Close()
+	tools, err := adapter.Tools()
+	require.NoError(t, err)
+	var greet *Tool
+	for _, tool := range tools {
+		if tool.Name() == "greet" {
+			greet = tool.(*Tool)
+		}
+	}
+	require.NotNil(t, greet)
+
+	// Both calls are waiting for their user before either is answered
+	var asked sync.WaitGroup
+	asked.Add(2)
+	run := func(name string) string {
+		ctx, closeCalls := WithCallHandlers(context.Background(), CallHandlers{
+			Elicit: func(ctx context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+				asked.Done()
+				asked.Wait()
+				return &mcp.ElicitationResult{ElicitationResponse: mcp.ElicitationResponse{
+					Action:  mcp.ElicitationResponseActionAccept,
+					Content: map[string]any{"name": name},
+				}}, nil
+			},
+			Sample: func(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+				return &mcp.CreateMessageResult{
+					SamplingMessage: mcp.SamplingMessage{Role: mcp.RoleAssistant, Content: mcp.NewTextContent(name + " sampled")},
+					Model:           name,
+				}, nil
+			},
+		})
+		defer closeCalls()
+		text, err := greet.Call(ctx, `{}`)
+		require.NoError(t, err)
+		return text
+	}
+
+	results := make(chan string, 2)
+	go func() { results <- "Ada: " + run("Ada") }()
+	go func() { results <- "Bob: " + run("Bob") }()
+	got := []string{<-results, <-results}
+	assert.ElementsMatch(t, []string{"Ada: Ada sampled", "Bob: Bob sampled"}, got)
+}
+
+func TestSharedStdioSession(t *testing.T) {
+	starts := filepath.Join(t.TempDir(), "starts")
+	t.Setenv(stdioServerEnv, starts)
+	endpoint := "stdio:" + os.Args[0]
+	registry := NewRegistry()
+	defer registry.Close()
+
+	startCount := func() int {
+		data, err := os.ReadFile(starts)
+		require.NoError(t, err)
+		return strings.Count(string(data), "\n")
+	}
+	run := func(tool, input string) string {
+		ctx, closeCalls := WithCallHandlers(context.Background(), CallHandlers{
+			Elicit: func(ctx context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+				return &mcp.ElicitationResult{ElicitationResponse: mcp.ElicitationResponse{Action: mcp.ElicitationResponseActionDecline}}, nil
+			},
+		})
+		defer closeCalls()
+		adapter, err := registry.Adapter(ctx, endpoint)
+		require.NoError(t, err)
+		tools, err := adapter.Tools()
+		require.NoError(t, err)
+		for _, candidate := range tools {
+			if candidate.Name() == tool {
+				text, _ := candidate.Call(ctx, input)
+				return text
+			}
+		}
+		t.Fatalf("no tool %s", tool)
+		return ""
+	}
+
+	// Runs calling tools that send no requests share the registry's process
+	options := `{"option1": "Go", "option2": "Rust", "option3": "Zig", "option4": "Python"}`
+	for i := 0; i < 2; i++ {
+		assert.Contains(t, run("provide_language_options", options), "Go")
+	}
+	assert.Equal(t, 1, startCount())
+
+	// An interactive tool is called on a session of the run
+	assert.Equal(t, "no name given", run("greet", `{}`))
+	assert.Equal(t, 2, startCount())
+}
@@ -0,0 +1,52 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/itchyny/gojq"
+)
+
+const (
+	// maxQueryLength bounds the filters Query accepts
+	maxQueryLength = 1000
+	// maxQueryResults bounds the results of a filter, range(1e9) alone has plenty
+	maxQueryResults = 10000
+)
+
+// Query runs the jq filter over data, which holds decoded JSON, until ctx is
+// done. The filter cannot read environment variables or further inputs.
+func Query(ctx context.Context, data any, filter string) ([]any, error) {
+	if len(filter) > maxQueryLength {
+		return nil, fmt.Errorf("the query is longer than %d characters", maxQueryLength)
+	}
+	query, err := gojq.Parse(filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+
+	var results []any
+	iter := code.RunWithContext(ctx, data)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			return results, nil
+		}
+		if err, ok := v.(error); ok {
+			var halt *gojq.HaltError
+			if errors.As(err, &halt) && halt.Value() == nil {
+				return results, nil
+			}
+			return nil, err
+		}
+		if len(results) == maxQueryResults {
+			return nil, fmt.Errorf("the query has more than %d results", maxQueryResults)
+		}
+		results = append(results, v)
+	}
+}
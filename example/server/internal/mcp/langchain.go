@@ -0,0 +1,194 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DefaultToolTimeout bounds a single tool call made by the agent
+const DefaultToolTimeout = 30 * time.Second
+
+// ToolResultHandler receives the complete result of every tool call made with a
+// context carrying it, see WithToolResultHandler
+type ToolResultHandler func(ctx context.Context, tool string, result *mcp.CallToolResult)
+
+type toolResultHandlerKey struct{}
+
+// WithToolResultHandler returns a context whose tool calls report their full
+// results, including structured content, images and resources, to fn
+func WithToolResultHandler(ctx context.Context, fn ToolResultHandler) context.Context {
+	return context.WithValue(ctx, toolResultHandlerKey{}, fn)
+}
+
+// ReportToolResult passes result to the ToolResultHandler on ctx, if there is one
+func ReportToolResult(ctx context.Context, tool string, result *mcp.CallToolResult) {
+	if fn, ok := ctx.Value(toolResultHandlerKey{}).(ToolResultHandler); ok {
+		fn(ctx, tool, result)
+	}
+}
+
+// ToolTimeouts returns how long a call to the named tool may take, 0 keeps the
+// tool's default
+type ToolTimeouts func(tool string) time.Duration
+
+type toolTimeoutsKey struct{}
+
+// WithToolTimeouts returns a context whose tool calls are bounded by timeouts
+// rather than DefaultToolTimeout
+func WithToolTimeouts(ctx context.Context, timeouts ToolTimeouts) context.Context {
+	return context.WithValue(ctx, toolTimeoutsKey{}, timeouts)
+}
+
+// Tool is a langchaingo tool backed by a tool on an MCP server. The agent sees
+// a text rendering of the result, the full result goes to the ToolResultHandler.
+type Tool struct {
+	name        string
+	description string
+	inputSchema json.RawMessage
+	adapter     *Adapter
+	// idempotent tools are retried after connection failures
+	idempotent bool
+	// interactive tools are called on a connection per run, see WithCallHandlers
+	interactive bool
+	timeout     time.Duration
+}
+
+func newTool(tool mcp.Tool, adapter *Adapter) (*Tool, error) {
+	inputSchema, err := json.Marshal(tool.InputSchema)
+	if tool.RawInputSchema != nil {
+		inputSchema, err = tool.RawInputSchema, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("marshal input schema of %s: %w", tool.Name, err)
+	}
+
+	return &Tool{
+		name:        tool.Name,
+		description: tool.Description,
+		inputSchema: inputSchema,
+		adapter:     adapter,
+		idempotent:  isIdempotent(tool),
+		interactive: isInteractive(tool),
+		timeout:     DefaultToolTimeout,
+	}, nil
+}
+
+func (t *Tool) Name() string {
+	return t.name
+}
+
+// Description includes the input schema so the model knows how to call the tool
+func (t *Tool) Description() string {
+	return t.description + "\n The input schema is: " + string(t.inputSchema)
+}
+
+// Call invokes the tool with the JSON arguments in input. Failures are returned
+// as the observation so the agent can correct itself.
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
+	result := t.call(ctx, input)
+	ReportToolResult(ctx, t.name, result)
+	return ResultText(result), nil
+}
+
+func (t *Tool) call(ctx context.Context, input string) *mcp.CallToolResult {
+	var args map[string]any
+	if err := json.Unmarshal([]byte(input), &args); err != nil {
+		return (&ToolError{
+			Code:    ToolErrorInvalidInput,
+			Message: "input must be valid json, retry tool calling with correct json",
+		}).Result()
+	}
+
+	// Elicitation and sampling requests the server sends meanwhile go to the
+	// handlers on ctx. The timeout includes the time the user takes to answer.
+	adapter := t.adapter
+	if run, ok := ctx.Value(callHandlersKey{}).(runCall); ok {
+		if t.interactive {
+			if wait, open := t.adapter.breaker.opened(); open {
+				return t.adapter.circuitOpen(wait)
+			}
+			var err error
+			if adapter, err = run.connections.adapter(t.adapter); err != nil {
+				return (&ToolError{Code: ToolErrorUnavailable, Message: err.Error(), Retryable: true}).Result()
+			}
+		}
+		defer adapter.calls.begin(run.handlers)()
+	}
+
+	var request mcp.CallToolRequest
+	request.Params.Name = t.name
+	request.Params.Arguments = args
+	if fn, ok := ctx.Value(progressHandlerKey{}).(ProgressHandler); ok {
+		token, end := adapter.progress.begin(func(progress Progress) { fn(ctx, t.name, progress) })
+		defer end()
+		request.Params.Meta = &mcp.Meta{ProgressToken: token}
+	}
+
+	timeout := t.timeout
+	if timeouts, ok := ctx.Value(toolTimeoutsKey{}).(ToolTimeouts); ok {
+		if d := timeouts(t.name); d > 0 {
+			timeout = d
+		}
+	}
+	return adapter.callTool(ctx, request, t.idempotent, timeout)
+}
+
+// isIdempotent reports whether the server marked tool safe to call again
+func isIdempotent(tool mcp.Tool) bool {
+	hint := tool.Annotations.IdempotentHint
+	readOnly := tool.Annotations.ReadOnlyHint
+	return (hint != nil && *hint) || (readOnly != nil && *readOnly)
+}
+
+// isInteractive reports whether the server marked tool as sending requests
+// while it runs, see InteractiveMeta
+func isInteractive(tool mcp.Tool) bool {
+	if tool.Meta == nil {
+		return false
+	}
+	interactive, _ := tool.Meta.AdditionalFields[InteractiveMeta].(bool)
+	return interactive
+}
+
+// ResultText renders a tool result for the model. Text and text resources are
+// kept, binary content is summarized, and structured content is used as JSON
+// when the server sent no text.
+func ResultText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		switch c := content.(type) {
+		case mcp.TextContent:
+			parts = append(parts, c.Text)
+		case mcp.ImageContent:
+			parts = append(parts, fmt.Sprintf("[image: %s]", c.MIMEType))
+		case mcp.AudioContent:
+			parts = append(parts, fmt.Sprintf("[audio: %s]", c.MIMEType))
+		case mcp.ResourceLink:
+			parts = append(parts, fmt.Sprintf("[resource: %s]", c.URI))
+		case mcp.EmbeddedResource:
+			switch r := c.Resource.(type) {
+			case mcp.TextResourceContents:
+				parts = append(parts, fmt.Sprintf("[resource: %s]\n%s", r.URI, r.Text))
+			case mcp.BlobResourceContents:
+				parts = append(parts, fmt.Sprintf("[resource: %s, %s]", r.URI, r.MIMEType))
+			}
+		}
+	}
+
+	if len(parts) == 0 && result.StructuredContent != nil {
+		if data, err := json.Marshal(result.StructuredContent); err == nil {
+			parts = append(parts, string(data))
+		}
+	}
+
+	text := strings.Join(parts, "\n")
+	if result.IsError {
+		return "call the tool error: " + text
+	}
+	return text
+}
@@ -0,0 +1,292 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultHandlerTimeout bounds shell and webhook tool handlers
+	DefaultHandlerTimeout = 30 * time.Second
+
+	// maxHandlerOutput caps how much a shell or webhook handler may return
+	maxHandlerOutput = 1 << 20
+)
+
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Manifest declares a tool in a YAML or JSON file, one tool per file:
+//
+//	name: word_count
+//	description: Count the words in a text
+//	input_schema:
+//	  type: object
+//	  properties:
+//	    text: {type: string}
+//	  required: [text]
+//	handler:
+//	  type: shell
+//	  command: ["wc", "-w"]
+//
+// With an output_schema the handler must print, or respond with, JSON
+// matching it, which is sent as structured content. Tools without side
+// effects should set idempotent: true so clients may retry them, and tools
+// whose results are options for the user to pick from choices: true.
+type Manifest struct {
+	Name         string          `yaml:"name"`
+	Description  string          `yaml:"description"`
+	InputSchema  map[string]any  `yaml:"input_schema"`
+	OutputSchema map[string]any  `yaml:"output_schema"`
+	Idempotent   bool            `yaml:"idempotent"`
+	Choices      bool            `yaml:"choices"`
+	Handler      ManifestHandler `yaml:"handler"`
+}
+
+// ManifestHandler configures how a manifest tool is executed
+type ManifestHandler struct {
+	// Type is "shell" or "http"
+	Type string `yaml:"type"`
+
+	// Command is run for shell handlers. The arguments are passed as JSON on
+	// stdin and as MCP_ARG_<NAME> environment variables, stdout is the result.
+	Command []string `yaml:"command"`
+
+	// URL receives the arguments as a JSON body for http handlers, the
+	// response body is the result. Image responses are sent as image content.
+	URL     string            `yaml:"url"`
+	Method  string            `yaml:"method"`
+	Headers map[string]string `yaml:"headers"`
+
+	Timeout string `yaml:"timeout"`
+}
+
+// LoadManifests reads every .yaml, .yml and .json tool manifest in dir
+func LoadManifests(dir string) ([]ToolDef, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read tools dir: %w", err)
+	}
+
+	var defs []ToolDef
+	seen := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !isManifest(entry.Name()) {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		def, err := LoadManifest(path)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := seen[def.Name]; ok {
+			return nil, fmt.Errorf("tool %s is declared in both %s and %s", def.Name, other, path)
+		}
+		seen[def.Name] = path
+		defs = append(defs, def)
+	}
+
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs, nil
+}
+
+func isManifest(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+// LoadManifest reads a single tool manifest
+func LoadManifest(path string) (ToolDef, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ToolDef{}, fmt.Errorf("read manifest: %w", err)
+	}
+
+	// JSON is valid YAML, so one decoder handles both formats
+	var m Manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&m); err != nil {
+		return ToolDef{}, fmt.Errorf("parse manifest %s: %w", path, err)
+	}
+
+	def, err := m.toolDef(filepath.Dir(path))
+	if err != nil {
+		return ToolDef{}, fmt.Errorf("manifest %s: %w", path, err)
+	}
+	if _, err := def.serverTool(); err != nil {
+		return ToolDef{}, fmt.Errorf("manifest %s: %w", path, err)
+	}
+	return def, nil
+}
+
+func (m Manifest) toolDef(dir string) (ToolDef, error) {
+	if !toolNamePattern.MatchString(m.Name) {
+		return ToolDef{}, fmt.Errorf("invalid tool name '%s'", m.Name)
+	}
+
+	timeout := DefaultHandlerTimeout
+	if m.Handler.Timeout != "" {
+		d, err := time.ParseDuration(m.Handler.Timeout)
+		if err != nil || d <= 0 {
+			return ToolDef{}, fmt.Errorf("invalid handler timeout '%s'", m.Handler.Timeout)
+		}
+		timeout = d
+	}
+
+	var handler ToolFunc
+	switch m.Handler.Type {
+	case "shell":
+		if len(m.Handler.Command) == 0 {
+			return ToolDef{}, errors.New("shell handler requires a command")
+		}
+		handler = shellHandler(m.Name, m.Handler.Command, dir, timeout)
+	case "http":
+		if !strings.HasPrefix(m.Handler.URL, "http://") && !strings.HasPrefix(m.Handler.URL, "https://") {
+			return ToolDef{}, fmt.Errorf("http handler requires an http(s) url, got '%s'", m.Handler.URL)
+		}
+		method := strings.ToUpper(m.Handler.Method)
+		if method == "" {
+			method = http.MethodPost
+		}
+		handler = webhookHandler(m.Handler.URL, method, m.Handler.Headers, timeout)
+	default:
+		return ToolDef{}, fmt.Errorf("unknown handler type '%s', must be one of: shell, http", m.Handler.Type)
+	}
+
+	return ToolDef{
+		Name:         m.Name,
+		Description:  m.Description,
+		InputSchema:  m.InputSchema,
+		OutputSchema: m.OutputSchema,
+		Idempotent:   m.Idempotent,
+		Choices:      m.Choices,
+		Handler:      handler,
+	}, nil
+}
+
+// shellHandler runs command in dir for each call
+func shellHandler(name string, command []string, dir string, timeout time.Duration) ToolFunc {
+	return func(ctx context.Context, args map[string]any) (any, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		input, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("encode arguments: %w", err)
+		}
+
+		cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+		cmd.Dir = dir
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Env = append(os.Environ(), "MCP_TOOL_NAME="+name)
+		for key, value := range args {
+			cmd.Env = append(cmd.Env, "MCP_ARG_"+strings.ToUpper(key)+"="+argString(value))
+		}
+
+		var stdout, stderr limitedBuffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("%s timed out after %s", name, timeout)
+			}
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("%s failed: %s", name, msg)
+			}
+			return nil, fmt.Errorf("%s failed: %w", name, err)
+		}
+		return strings.TrimRight(stdout.String(), "\n"), nil
+	}
+}
+
+// argString renders an argument for an environment variable, scalars as-is
+// and everything else as JSON
+func argString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// webhookHandler sends each call to url
+func webhookHandler(url, method string, headers map[string]string, timeout time.Duration) ToolFunc {
+	client := &http.Client{Timeout: timeout}
+
+	return func(ctx context.Context, args map[string]any) (any, error) {
+		body, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("encode arguments: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for key, value := range headers {
+			req.Header.Set(key, os.ExpandEnv(value))
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("webhook request failed: %w", err)
+		}
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxHandlerOutput))
+		if err != nil {
+			return nil, fmt.Errorf("read webhook response: %w", err)
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+		}
+		if contentType := resp.Header.Get("Content-Type"); strings.HasPrefix(contentType, "image/") {
+			mimeType, _, _ := strings.Cut(contentType, ";")
+			return mcp.NewToolResultImage("", base64.StdEncoding.EncodeToString(data), mimeType), nil
+		}
+		return string(data), nil
+	}
+}
+
+// limitedBuffer keeps the first maxHandlerOutput bytes written to it
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := maxHandlerOutput - b.Len(); room < len(p) {
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
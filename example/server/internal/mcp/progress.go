@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// methodNotificationProgress is the notification servers send about a request
+// that carried a progress token
+const methodNotificationProgress = "notifications/progress"
+
+// Progress is what a long-running tool reported about itself. Total is 0 when
+// the tool does not know how much work is left.
+type Progress struct {
+	Progress float64
+	Total    float64
+	Message  string
+}
+
+// ProgressHandler receives the progress reported by tool calls made with a
+// context carrying it, see WithProgressHandler
+type ProgressHandler func(ctx context.Context, tool string, progress Progress)
+
+type progressHandlerKey struct{}
+
+// WithProgressHandler returns a context whose tool calls ask the server for
+// progress notifications and pass them to fn
+func WithProgressHandler(ctx context.Context, fn ProgressHandler) context.Context {
+	return context.WithValue(ctx, progressHandlerKey{}, fn)
+}
+
+type progressTokenKey struct{}
+
+// ReportProgress sends progress to the client of the tool call ctx belongs to.
+// It does nothing when the client did not ask for progress.
+func ReportProgress(ctx context.Context, progress Progress) {
+	token := ctx.Value(progressTokenKey{})
+	mcpServer := server.ServerFromContext(ctx)
+	if token == nil || mcpServer == nil {
+		return
+	}
+
+	params := map[string]any{"progressToken": token, "progress": progress.Progress}
+	if progress.Total > 0 {
+		params["total"] = progress.Total
+	}
+	if progress.Message != "" {
+		params["message"] = progress.Message
+	}
+	// Progress is advisory, a client that went away is noticed by the call itself
+	_ = mcpServer.SendNotificationToClient(ctx, methodNotificationProgress, params)
+}
+
+// withProgressToken returns ctx for handling request, ReportProgress uses its token
+func withProgressToken(ctx context.Context, request mcp.CallToolRequest) context.Context {
+	if meta := request.Params.Meta; meta != nil && meta.ProgressToken != nil {
+		return context.WithValue(ctx, progressTokenKey{}, meta.ProgressToken)
+	}
+	return ctx
+}
+
+// progressRouter hands progress notifications to the tool calls that asked
+// for them, by the token each call sent
+type progressRouter struct {
+	mu       sync.Mutex
+	nextID   uint64
+	handlers map[string]func(Progress)
+}
+
+// begin registers fn under a new token until the returned func is called
+func (r *progressRouter) begin(fn func(Progress)) (string, func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	token := fmt.Sprintf("progress-%d", r.nextID)
+	if r.handlers == nil {
+		r.handlers = map[string]func(Progress){}
+	}
+	r.handlers[token] = fn
+	return token, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.handlers, token)
+	}
+}
+
+// dispatch passes a notifications/progress notification to its call, late
+// notifications for calls that already returned are dropped
+func (r *progressRouter) dispatch(params map[string]any) {
+	token, _ := params["progressToken"].(string)
+	r.mu.Lock()
+	fn, ok := r.handlers[token]
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	var progress Progress
+	progress.Progress, _ = params["progress"].(float64)
+	progress.Total, _ = params["total"].(float64)
+	progress.Message, _ = params["message"].(string)
+	fn(progress)
+}
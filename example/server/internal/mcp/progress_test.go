@@ -0,0 +1,79 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolProgress(t *testing.T) {
+	count := ToolDef{
+		Name: "count",
+		Handler: func(ctx context.Context, args map[string]any) (any, error) {
+			for i := range 3 {
+				ReportProgress(ctx, Progress{Progress: float64(i), Total: 3, Message: "counting"})
+			}
+			// Notifications are streamed apart from the result, give them time
+			// to arrive before the call returns and stops listening
+			time.Sleep(100 * time.Millisecond)
+			return "done", nil
+		},
+	}
+	slow := ToolDef{
+		Name: "slow",
+		Handler: func(ctx context.Context, args map[string]any) (any, error) {
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+			}
+			return "done", nil
+		},
+	}
+	mcpServer := startServer(t, freePort(t), WithTools(count, slow))
+	defer stopServer(mcpServer)
+
+	adapter, err := NewAdapter(mcpServer.Endpoint())
+	require.NoError(t, err)
+	defer adapter.Close()
+	tools := map[string]*Tool{}
+	list, err := adapter.Tools()
+	require.NoError(t, err)
+	for _, tool := range list {
+		tools[tool.Name()] = tool.(*Tool)
+	}
+
+	var (
+		mu       sync.Mutex
+		reported []Progress
+	)
+	ctx := WithProgressHandler(context.Background(), func(ctx context.Context, tool string, progress Progress) {
+		assert.Equal(t, "count", tool)
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, progress)
+	})
+	text, err := tools["count"].Call(ctx, `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, "done", text)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []Progress{
+		{Progress: 0, Total: 3, Message: "counting"},
+		{Progress: 1, Total: 3, Message: "counting"},
+		{Progress: 2, Total: 3, Message: "counting"},
+	}, reported)
+
+	ctx = WithToolTimeouts(context.Background(), func(tool string) time.Duration {
+		if tool == "slow" {
+			return 50 * time.Millisecond
+		}
+		return 0
+	})
+	text, err = tools["slow"].Call(ctx, `{}`)
+	require.NoError(t, err)
+	assert.Contains(t, text, "no result after 50ms")
+}
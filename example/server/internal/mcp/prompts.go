@@ -0,0 +1,276 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gopkg.in/yaml.v3"
+)
+
+// builtinPrompts are served by every server, a prompts dir can add to or override them
+//
+//go:embed prompts/*.md
+var builtinPrompts embed.FS
+
+// PromptURIScheme prefixes the resource URIs prompt files are exposed under
+const PromptURIScheme = "prompt://"
+
+// PromptDef is a prompt template loaded from a markdown file. The file name is
+// the prompt name, {{.Field}} placeholders become required arguments, and an
+// optional front matter block describes the prompt:
+//
+//	---
+//	description: Generate a task YAML for a new client
+//	arguments:
+//	  Language: Programming language of the client
+//	---
+type PromptDef struct {
+	Name        string
+	Description string
+	Arguments   []PromptArgument
+
+	// Source is the raw file content, exposed as a resource
+	Source   string
+	template *template.Template
+}
+
+// PromptArgument is a value a prompt template needs to render
+type PromptArgument struct {
+	Name        string
+	Description string
+}
+
+type promptFrontMatter struct {
+	Description string            `yaml:"description"`
+	Arguments   map[string]string `yaml:"arguments"`
+}
+
+// LoadPrompts reads every .md prompt file in fsys
+func LoadPrompts(fsys fs.FS) ([]PromptDef, error) {
+	names, err := fs.Glob(fsys, "*.md")
+	if err != nil {
+		return nil, err
+	}
+
+	defs := make([]PromptDef, 0, len(names))
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("read prompt: %w", err)
+		}
+		def, err := ParsePrompt(strings.TrimSuffix(path.Base(name), ".md"), string(data))
+		if err != nil {
+			return nil, fmt.Errorf("prompt %s: %w", name, err)
+		}
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+// ParsePrompt parses a prompt template, see PromptDef for the format
+func ParsePrompt(name, source string) (PromptDef, error) {
+	var meta promptFrontMatter
+	body := source
+	if rest, ok := strings.CutPrefix(source, "---\n"); ok {
+		frontMatter, after, found := strings.Cut(rest, "\n---\n")
+		if !found {
+			return PromptDef{}, fmt.Errorf("unterminated front matter")
+		}
+		if err := yaml.Unmarshal([]byte(frontMatter), &meta); err != nil {
+			return PromptDef{}, fmt.Errorf("parse front matter: %w", err)
+		}
+		body = after
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(body)
+	if err != nil {
+		return PromptDef{}, fmt.Errorf("parse template: %w", err)
+	}
+
+	var args []PromptArgument
+	for _, field := range templateFields(tmpl.Root) {
+		args = append(args, PromptArgument{Name: field, Description: meta.Arguments[field]})
+	}
+	for declared := range meta.Arguments {
+		if !hasArgument(args, declared) {
+			return PromptDef{}, fmt.Errorf("argument %s is described but never used", declared)
+		}
+	}
+
+	description := meta.Description
+	if description == "" {
+		description = firstHeading(body)
+	}
+
+	return PromptDef{
+		Name:        name,
+		Description: description,
+		Arguments:   args,
+		Source:      source,
+		template:    tmpl,
+	}, nil
+}
+
+// Render executes the template, every argument must be set
+func (p PromptDef) Render(args map[string]string) (string, error) {
+	for _, arg := range p.Arguments {
+		if _, ok := args[arg.Name]; !ok {
+			return "", fmt.Errorf("prompt %s: missing argument %s", p.Name, arg.Name)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := p.template.Execute(&buf, args); err != nil {
+		return "", fmt.Errorf("render prompt %s: %w", p.Name, err)
+	}
+	return buf.String(), nil
+}
+
+func (p PromptDef) serverPrompt() server.ServerPrompt {
+	opts := []mcp.PromptOption{mcp.WithPromptDescription(p.Description)}
+	for _, arg := range p.Arguments {
+		argOpts := []mcp.ArgumentOption{mcp.RequiredArgument()}
+		if arg.Description != "" {
+			argOpts = append(argOpts, mcp.ArgumentDescription(arg.Description))
+		}
+		opts = append(opts, mcp.WithArgument(arg.Name, argOpts...))
+	}
+
+	handler := func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		text, err := p.Render(request.Params.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewGetPromptResult(p.Description, []mcp.PromptMessage{
+			mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+		}), nil
+	}
+
+	return server.ServerPrompt{Prompt: mcp.NewPrompt(p.Name, opts...), Handler: handler}
+}
+
+// serverResource exposes the raw prompt file
+func (p PromptDef) serverResource() server.ServerResource {
+	uri := PromptURIScheme + p.Name
+	resource := mcp.NewResource(uri, p.Name+".md",
+		mcp.WithResourceDescription(p.Description),
+		mcp.WithMIMEType("text/markdown"),
+	)
+	handler := func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return []mcp.ResourceContents{mcp.TextResourceContents{URI: uri, MIMEType: "text/markdown", Text: p.Source}}, nil
+	}
+	return server.ServerResource{Resource: resource, Handler: handler}
+}
+
+// loadPromptSet returns the built-in prompts merged with those in dir, which win on name clashes
+func loadPromptSet(dir string) ([]PromptDef, error) {
+	sub, err := fs.Sub(builtinPrompts, "prompts")
+	if err != nil {
+		return nil, err
+	}
+	defs, err := LoadPrompts(sub)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return defs, nil
+	}
+
+	extra, err := LoadPrompts(os.DirFS(dir))
+	if err != nil {
+		return nil, fmt.Errorf("load prompts dir: %w", err)
+	}
+
+	byName := map[string]PromptDef{}
+	for _, def := range append(defs, extra...) {
+		byName[def.Name] = def
+	}
+	merged := make([]PromptDef, 0, len(byName))
+	for _, def := range byName {
+		merged = append(merged, def)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name < merged[j].Name })
+	return merged, nil
+}
+
+// templateFields lists the top level fields a template reads, in order of first use
+func templateFields(node parse.Node) []string {
+	var fields []string
+	seen := map[string]bool{}
+	var walk func(parse.Node)
+	walk = func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			if name := n.Ident[0]; !seen[name] {
+				seen[name] = true
+				fields = append(fields, name)
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		}
+	}
+	walk(node)
+	return fields
+}
+
+func hasArgument(args []PromptArgument, name string) bool {
+	for _, arg := range args {
+		if arg.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// firstHeading returns the text of the first markdown heading
+func firstHeading(body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		if heading, ok := strings.CutPrefix(line, "#"); ok {
+			return strings.TrimSpace(strings.TrimLeft(heading, "#"))
+		}
+	}
+	return ""
+}
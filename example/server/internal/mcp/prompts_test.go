@@ -0,0 +1,40 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinPrompts(t *testing.T) {
+	prompts, err := loadPromptSet("")
+	require.NoError(t, err)
+	require.Len(t, prompts, 2)
+
+	client := prompts[0]
+	assert.Equal(t, "client_prompt", client.Name)
+	assert.Equal(t, []PromptArgument{{Name: "Language", Description: "Programming language the new AG-UI client is written in"}}, client.Arguments)
+
+	text, err := client.Render(map[string]string{"Language": "Rust"})
+	require.NoError(t, err)
+	assert.Contains(t, text, "./languages/Rust/")
+	assert.NotContains(t, text, "{{")
+	assert.True(t, strings.HasPrefix(text, "# Project Task YAML Generation Prompt"))
+
+	_, err = client.Render(map[string]string{})
+	assert.ErrorContains(t, err, "missing argument Language")
+
+	assert.Empty(t, prompts[1].Arguments)
+}
+
+func TestParsePrompt(t *testing.T) {
+	prompt, err := ParsePrompt("greet", "# Say hello\n{{if .Formal}}Good day{{else}}Hi{{end}} {{.Name}}, {{.Name}}!")
+	require.NoError(t, err)
+	assert.Equal(t, "Say hello", prompt.Description)
+	assert.Equal(t, []PromptArgument{{Name: "Formal"}, {Name: "Name"}}, prompt.Arguments)
+
+	_, err = ParsePrompt("bad", "---\narguments:\n  Unused: never rendered\n---\nhello")
+	assert.ErrorContains(t, err, "never used")
+}
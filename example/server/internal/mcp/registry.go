@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// pingTimeout bounds the liveness check of a cached adapter
+const pingTimeout = 5 * time.Second
+
+// Registry keeps one long-lived Adapter per endpoint so tool list changes
+// announced by a server carry over to later agent runs
+type Registry struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpointAdapter
+}
+
+// endpointAdapter holds the adapter of one endpoint. Its lock is held while
+// connecting and checking the connection, so runs only wait for their own
+// servers.
+type endpointAdapter struct {
+	mu      sync.Mutex
+	adapter *Adapter
+	closed  bool
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{endpoints: map[string]*endpointAdapter{}}
+}
+
+// Adapter returns the adapter for endpoint, connecting on first use and
+// reconnecting if the cached connection no longer answers pings. A server
+// that went away after the first connection keeps its last known tools, their
+// calls fail with ToolErrors until it is back. While its circuit is open the
+// cached adapter is returned without contacting the server.
+func (r *Registry) Adapter(ctx context.Context, endpoint string) (*Adapter, error) {
+	r.mu.Lock()
+	entry, ok := r.endpoints[endpoint]
+	if !ok {
+		entry = &endpointAdapter{}
+		r.endpoints[endpoint] = entry
+	}
+	r.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.closed {
+		return nil, errAdapterClosed
+	}
+
+	if adapter := entry.adapter; adapter != nil {
+		if _, open := adapter.breaker.opened(); open {
+			return adapter, nil
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		err := adapter.Ping(pingCtx)
+		cancel()
+		if err != nil {
+			if err := adapter.Reconnect(); err != nil {
+				slog.Warn("MCP server unreachable, using its last known tools", "endpoint", endpoint, "error", err)
+			}
+		}
+		return adapter, nil
+	}
+
+	adapter, err := NewAdapter(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	entry.adapter = adapter
+	return adapter, nil
+}
+
+// Check reports whether the MCP server at endpoint lists its tools on the
+// shared connection, connecting on first use
+func (r *Registry) Check(ctx context.Context, endpoint string) error {
+	adapter, err := r.Adapter(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+	mcpClient, _ := adapter.client()
+	if _, err := mcpClient.ListTools(ctx, mcp.ListToolsRequest{}); err != nil {
+		return fmt.Errorf("list tools from %s: %w", endpoint, err)
+	}
+	return nil
+}
+
+// Close disconnects every adapter, waiting for connections in progress
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	endpoints := r.endpoints
+	r.endpoints = map[string]*endpointAdapter{}
+	r.mu.Unlock()
+
+	closeEndpoints(endpoints)
+	return nil
+}
+
+// closeEndpoints closes the adapters of endpoints, and any still connecting
+// once they are done
+func closeEndpoints(endpoints map[string]*endpointAdapter) {
+	for _, entry := range endpoints {
+		entry.mu.Lock()
+		entry.closed = true
+		if entry.adapter != nil {
+			entry.adapter.Close()
+		}
+		entry.mu.Unlock()
+	}
+}
@@ -0,0 +1,226 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// maxAttempts bounds the calls made for one tool call, including retries
+	maxAttempts = 3
+	// retryBackoff is the wait before the first retry, doubled for each one after
+	retryBackoff = 250 * time.Millisecond
+	// breakerThreshold consecutive failed calls open a server's circuit
+	breakerThreshold = 5
+	// breakerCooldown is how long an open circuit fails calls before letting one through
+	breakerCooldown = 30 * time.Second
+)
+
+// ToolErrorCode classifies a tool call that failed without a result from the tool
+type ToolErrorCode string
+
+const (
+	// ToolErrorInvalidInput means the arguments were not valid JSON
+	ToolErrorInvalidInput ToolErrorCode = "invalid_input"
+	// ToolErrorRejected means the server refused the call, e.g. for an unknown tool
+	ToolErrorRejected ToolErrorCode = "rejected"
+	// ToolErrorTimeout means the tool did not answer in time
+	ToolErrorTimeout ToolErrorCode = "timeout"
+	// ToolErrorUnavailable means the server could not be reached
+	ToolErrorUnavailable ToolErrorCode = "unavailable"
+	// ToolErrorCircuitOpen means calls to the server are paused after repeated failures
+	ToolErrorCircuitOpen ToolErrorCode = "circuit_open"
+	// ToolErrorCanceled means the run was canceled during the call
+	ToolErrorCanceled ToolErrorCode = "canceled"
+	// ToolErrorDenied means the call was not run because of the tool's approval policy
+	ToolErrorDenied ToolErrorCode = "denied"
+)
+
+// ToolError describes a failed tool call to the agent. It is returned as an
+// error result, with the error as structured content, so the agent can decide
+// whether to retry, use another tool or give up.
+type ToolError struct {
+	Code    ToolErrorCode `json:"code"`
+	Message string        `json:"message"`
+	// Retryable is set when the same call may succeed later
+	Retryable bool `json:"retryable"`
+	Attempts  int  `json:"attempts,omitempty"`
+}
+
+func (e *ToolError) Error() string {
+	if e.Retryable {
+		return fmt.Sprintf("%s: %s (retryable)", e.Code, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Result is the error as a tool result
+func (e *ToolError) Result() *mcp.CallToolResult {
+	result := mcp.NewToolResultError(e.Error())
+	result.StructuredContent = map[string]any{"error": e}
+	return result
+}
+
+// callTool calls a tool on the server. Connection failures replace the
+// connection and are retried with backoff when the tool is idempotent or the
+// request never reached the server. Every failure is returned as a ToolError result.
+func (a *Adapter) callTool(ctx context.Context, request mcp.CallToolRequest, idempotent bool, timeout time.Duration) *mcp.CallToolResult {
+	if wait, ok := a.breaker.allow(); !ok {
+		return a.circuitOpen(wait)
+	}
+
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		mcpClient, generation := a.client()
+		callCtx, cancel := context.WithTimeout(ctx, timeout)
+		result, err := mcpClient.CallTool(callCtx, request)
+		cancel()
+
+		switch {
+		case err == nil:
+			a.breaker.record(true)
+			return result
+		case ctx.Err() != nil:
+			a.breaker.release()
+			return (&ToolError{Code: ToolErrorCanceled, Message: ctx.Err().Error(), Attempts: attempt}).Result()
+		case !isTransportError(err):
+			// The server answered, so the connection is healthy
+			a.breaker.record(true)
+			return (&ToolError{Code: ToolErrorRejected, Message: err.Error(), Attempts: attempt}).Result()
+		case errors.Is(err, context.DeadlineExceeded):
+			a.breaker.record(false)
+			return (&ToolError{
+				Code:      ToolErrorTimeout,
+				Message:   fmt.Sprintf("no result after %s", timeout),
+				Retryable: idempotent,
+				Attempts:  attempt,
+			}).Result()
+		}
+
+		// The connection failed, later attempts and calls use a new one
+		if err := a.reconnect(generation); err != nil {
+			slog.Warn("MCP reconnect failed", "endpoint", a.endpoint, "error", err)
+		}
+
+		if attempt == maxAttempts || !(idempotent || notSent(err)) {
+			a.breaker.record(false)
+			return (&ToolError{
+				Code:      ToolErrorUnavailable,
+				Message:   err.Error(),
+				Retryable: idempotent,
+				Attempts:  attempt,
+			}).Result()
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			a.breaker.release()
+			return (&ToolError{Code: ToolErrorCanceled, Message: ctx.Err().Error(), Attempts: attempt}).Result()
+		}
+	}
+}
+
+// circuitOpen is the result of calls refused by the breaker for wait
+func (a *Adapter) circuitOpen(wait time.Duration) *mcp.CallToolResult {
+	return (&ToolError{
+		Code:      ToolErrorCircuitOpen,
+		Message:   fmt.Sprintf("%s failed repeatedly, calls are paused for %s", a.endpoint, wait.Round(time.Second)),
+		Retryable: true,
+	}).Result()
+}
+
+// isTransportError reports whether err came from the connection rather than
+// an error response from the server
+func isTransportError(err error) bool {
+	var transportErr *transport.Error
+	return errors.As(err, &transportErr)
+}
+
+// notSent reports whether the failed request cannot have reached the tool, so
+// even tools with side effects can be retried
+func notSent(err error) bool {
+	if errors.Is(err, transport.ErrSessionTerminated) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// breaker is a per-server circuit breaker. After breakerThreshold consecutive
+// failures it fails calls for breakerCooldown, then lets a single call through
+// to probe the server and closes again once one succeeds.
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports whether a call may go ahead, otherwise how long the circuit stays open
+func (b *breaker) allow() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < breakerThreshold {
+		return 0, true
+	}
+	if wait := time.Until(b.openUntil); wait > 0 {
+		return wait, false
+	}
+	if b.probing {
+		return breakerCooldown, false
+	}
+	b.probing = true
+	return 0, true
+}
+
+// opened reports whether the circuit is open and for how long, without
+// taking the call that probes the server once it may close
+func (b *breaker) opened() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < breakerThreshold {
+		return 0, false
+	}
+	if wait := time.Until(b.openUntil); wait > 0 {
+		return wait, true
+	}
+	if b.probing {
+		return breakerCooldown, true
+	}
+	return 0, false
+}
+
+// record counts the outcome of an allowed call
+func (b *breaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if ok {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= breakerThreshold {
+		b.openUntil = time.Now().Add(breakerCooldown)
+	}
+}
+
+// release ends an allowed call without an outcome, e.g. when the run was canceled
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
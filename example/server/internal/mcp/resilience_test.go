@@ -0,0 +1,209 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdapterReconnect(t *testing.T) {
+	port := freePort(t)
+	server := startServer(t, port)
+
+	adapter, err := NewAdapter(server.Endpoint())
+	require.NoError(t, err)
+	defer adapter.Close()
+
+	tools, err := adapter.Tools()
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	tool := tools[0].(*Tool)
+	assert.True(t, tool.idempotent)
+
+	var got *mcp.CallToolResult
+	ctx := WithToolResultHandler(context.Background(), func(ctx context.Context, tool string, result *mcp.CallToolResult) {
+		got = result
+	})
+	args := `{"option1":"Go","option2":"Rust","option3":"Zig","option4":"C"}`
+
+	// A stopped server is reported as a retryable tool error
+	stopServer(server)
+	text, err := tool.Call(ctx, args)
+	require.NoError(t, err)
+	assert.Contains(t, text, "unavailable")
+	require.True(t, got.IsError)
+	toolErr := got.StructuredContent.(map[string]any)["error"].(*ToolError)
+	assert.Equal(t, ToolErrorUnavailable, toolErr.Code)
+	assert.True(t, toolErr.Retryable)
+	assert.Equal(t, maxAttempts, toolErr.Attempts)
+
+	// Once it is back the stale session is replaced
+	server = startServer(t, port)
+	defer stopServer(server)
+	text, err = tool.Call(ctx, args)
+	require.NoError(t, err)
+	assert.JSONEq(t, args, text)
+	assert.False(t, got.IsError)
+}
+
+func TestBreaker(t *testing.T) {
+	var b breaker
+	for i := 0; i < breakerThreshold; i++ {
+		_, ok := b.allow()
+		require.True(t, ok)
+		b.record(false)
+	}
+
+	wait, ok := b.allow()
+	assert.False(t, ok)
+	assert.Greater(t, wait, breakerCooldown-time.Second)
+
+	// After the cooldown a single call probes the server
+	b.openUntil = time.Now()
+	_, ok = b.allow()
+	assert.True(t, ok)
+	_, ok = b.allow()
+	assert.False(t, ok)
+
+	_, open := b.opened()
+	assert.True(t, open)
+
+	b.record(true)
+	_, ok = b.allow()
+	assert.True(t, ok)
+	_, open = b.opened()
+	assert.False(t, open)
+}
+
+func TestRegistrySkipsOpenCircuit(t *testing.T) {
+	port := freePort(t)
+	server := startServer(t, port)
+	registry := NewRegistry()
+	defer registry.Close()
+	adapter, err := registry.Adapter(context.Background(), server.Endpoint())
+	require.NoError(t, err)
+	stopServer(server)
+
+	// A server that never answers would hold up every run until the ping
+	// and reconnect time out
+	release := make(chan struct{})
+	hanging := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	hanging.Listener = listener
+	hanging.Start()
+	defer hanging.Close()
+	defer close(release)
+
+	for i := 0; i < breakerThreshold; i++ {
+		_, ok := adapter.breaker.allow()
+		require.True(t, ok)
+		adapter.breaker.record(false)
+	}
+	start := time.Now()
+	again, err := registry.Adapter(context.Background(), server.Endpoint())
+	require.NoError(t, err)
+	assert.Same(t, adapter, again)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Runs with call handlers do not dial it either
+	ctx, closeCalls := WithCallHandlers(context.Background(), CallHandlers{})
+	defer closeCalls()
+	tools, err := adapter.Tools()
+	require.NoError(t, err)
+	text, err := tools[0].Call(ctx, `{"option1":"Go","option2":"Rust","option3":"Zig","option4":"C"}`)
+	require.NoError(t, err)
+	assert.Contains(t, text, string(ToolErrorCircuitOpen))
+}
+
+func TestRegistryCheck(t *testing.T) {
+	port := freePort(t)
+	server := startServer(t, port)
+	registry := NewRegistry()
+	defer registry.Close()
+
+	// Probes share the connection of the runs
+	require.NoError(t, registry.Check(context.Background(), server.Endpoint()))
+	adapter, err := registry.Adapter(context.Background(), server.Endpoint())
+	require.NoError(t, err)
+	require.NoError(t, registry.Check(context.Background(), server.Endpoint()))
+	again, err := registry.Adapter(context.Background(), server.Endpoint())
+	require.NoError(t, err)
+	assert.Same(t, adapter, again)
+
+	stopServer(server)
+	assert.Error(t, registry.Check(context.Background(), server.Endpoint()))
+}
+
+func TestRegistryCheckListsTools(t *testing.T) {
+	server := startServer(t, freePort(t))
+	defer stopServer(server)
+	target, err := url.Parse(server.Endpoint())
+	require.NoError(t, err)
+
+	// A server that answers pings but cannot list its tools is not ready
+	var failList atomic.Bool
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: target.Scheme, Host: target.Host})
+	front := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if failList.Load() && strings.Contains(string(body), `"tools/list"`) {
+			http.Error(w, "tools unavailable", http.StatusInternalServerError)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		proxy.ServeHTTP(w, r)
+	}))
+	defer front.Close()
+	endpoint := front.URL + target.Path
+
+	registry := NewRegistry()
+	defer registry.Close()
+	require.NoError(t, registry.Check(context.Background(), endpoint))
+	failList.Store(true)
+	assert.ErrorContains(t, registry.Check(context.Background(), endpoint), "list tools")
+}
+
+func TestRegistryConnectsConcurrently(t *testing.T) {
+	// A server that never answers holds up only the runs using it
+	release := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hanging.Close()
+	server := startServer(t, freePort(t))
+	defer stopServer(server)
+	registry := NewRegistry()
+	defer registry.Close()
+	defer close(release)
+
+	go registry.Adapter(context.Background(), hanging.URL+"/mcp")
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan error)
+	go func() {
+		_, err := registry.Adapter(context.Background(), server.Endpoint())
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("connecting to one endpoint blocked the others")
+	}
+}
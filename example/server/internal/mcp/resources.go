@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DocsURIScheme prefixes the resource URIs of files in the resources dir
+const DocsURIScheme = "docs://"
+
+// maxResourceSize caps the files exposed as resources
+const maxResourceSize = 1 << 20
+
+// LoadResources exposes every text file under dir, recursively, as a
+// docs://<relative path> resource. Files are re-read on every request so
+// edits are picked up without a restart.
+func LoadResources(dir string) ([]server.ServerResource, error) {
+	var resources []server.ServerResource
+	err := filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if p != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() > maxResourceSize {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		resources = append(resources, fileResource(p, filepath.ToSlash(rel)))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load resources dir: %w", err)
+	}
+	return resources, nil
+}
+
+func fileResource(file, rel string) server.ServerResource {
+	uri := DocsURIScheme + rel
+	mimeType := mime.TypeByExtension(path.Ext(rel))
+	switch {
+	case strings.HasSuffix(rel, ".md"):
+		mimeType = "text/markdown"
+	case mimeType == "":
+		mimeType = "text/plain"
+	}
+
+	resource := mcp.NewResource(uri, rel, mcp.WithMIMEType(mimeType))
+	handler := func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", uri, err)
+		}
+		if !utf8.Valid(data) {
+			return nil, fmt.Errorf("%s is not a text file", uri)
+		}
+		return []mcp.ResourceContents{mcp.TextResourceContents{URI: uri, MIMEType: mimeType, Text: string(data)}}, nil
+	}
+	return server.ServerResource{Resource: resource, Handler: handler}
+}
@@ -0,0 +1,445 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// Languages run_code always understands, CodeOptions.Runtimes adds others
+const (
+	LanguageGo   = "go"
+	LanguageWasm = "wasm"
+)
+
+const (
+	// DefaultCodeMemoryLimit is the memory a sandboxed program may use
+	DefaultCodeMemoryLimit = 128 << 20
+	// DefaultCodeConcurrency is the number of programs built or run at the same time
+	DefaultCodeConcurrency = 4
+)
+
+const (
+	// maxCodeSize caps the source or module run_code accepts
+	maxCodeSize = 4 << 20
+	// goBuildTimeout bounds compiling a Go program, which takes longer the
+	// first time as the standard library is built for WASI
+	goBuildTimeout = 2 * time.Minute
+	// codePlaceholder is replaced with the code in a runtime's arguments
+	codePlaceholder = "{code}"
+	// maxCachedModules bounds the compiled programs kept for reuse
+	maxCachedModules = 32
+)
+
+// CodeOptions configures the run_code tool
+type CodeOptions struct {
+	// Enabled serves run_code
+	Enabled bool
+	// MemoryLimit is the memory in bytes a program may use, rounded up to
+	// 64 KiB WebAssembly pages
+	MemoryLimit int
+	// Concurrency bounds the programs built or run at the same time, further
+	// calls wait for a free slot
+	Concurrency int
+	// GoToolchain is the go command that compiles Go programs, it is looked
+	// up on PATH when empty. Go is not offered when it cannot be found or
+	// GoToolchain is "off".
+	GoToolchain string
+	// Runtimes adds languages run by an interpreter compiled to WASI
+	Runtimes map[string]CodeRuntime
+}
+
+// CodeRuntime is an interpreter compiled to a WASI module
+type CodeRuntime struct {
+	// Module is the path of the .wasm file
+	Module string
+	// Args follow the program name, "{code}" is replaced with the code
+	Args []string
+}
+
+// sandbox runs WASI modules without filesystem or network access. Runs share
+// a runtime, which enforces the memory limit and closes modules whose context
+// is done, so a timeout stops programs that never yield. The timeout is
+// wall-clock time, CPU time is not metered: a busy loop keeps a core until it
+// is stopped, which is why only slots programs are built or run at once.
+type sandbox struct {
+	runtime  wazero.Runtime
+	limits   ToolLimits
+	memory   int
+	goBinary string
+	runtimes map[string]loadedRuntime
+	slots    chan struct{}
+
+	mu      sync.Mutex
+	modules map[[sha256.Size]byte]*cachedModule
+}
+
+type loadedRuntime struct {
+	module wazero.CompiledModule
+	args   []string
+}
+
+// cachedModule is a compiled module and the runs using it. wazero shares the
+// compiled code of identical modules, closing one closes them all, so there
+// is a single module per hash that is only closed once unused.
+type cachedModule struct {
+	module wazero.CompiledModule
+	users  int
+	pinned bool
+	used   time.Time
+}
+
+func newSandbox(opts CodeOptions, limits ToolLimits) (*sandbox, error) {
+	if limits.Timeout <= 0 {
+		limits.Timeout = DefaultPackTimeout
+	}
+	if limits.MaxOutput <= 0 {
+		limits.MaxOutput = DefaultPackMaxOutput
+	}
+	memory := opts.MemoryLimit
+	if memory <= 0 {
+		memory = DefaultCodeMemoryLimit
+	}
+	pages := (memory + 1<<16 - 1) >> 16
+	if pages > 1<<16 {
+		return nil, fmt.Errorf("code memory limit %d exceeds 4 GiB", memory)
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultCodeConcurrency
+	}
+
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(pages)).
+		WithCloseOnContextDone(true).
+		WithCompilationCache(wazero.NewCompilationCache()))
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+
+	s := &sandbox{
+		runtime:  runtime,
+		limits:   limits,
+		memory:   pages << 16,
+		runtimes: map[string]loadedRuntime{},
+		slots:    make(chan struct{}, concurrency),
+		modules:  map[[sha256.Size]byte]*cachedModule{},
+	}
+	for _, language := range slices.Sorted(maps.Keys(opts.Runtimes)) {
+		if language == LanguageGo || language == LanguageWasm {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("code runtime %s: the language is built in", language)
+		}
+		config := opts.Runtimes[language]
+		wasm, err := os.ReadFile(config.Module)
+		if err != nil {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("code runtime %s: %w", language, err)
+		}
+		module, _, err := s.compile(ctx, wasm, true)
+		if err != nil {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("code runtime %s: %w", language, err)
+		}
+		s.runtimes[language] = loadedRuntime{module: module, args: config.Args}
+	}
+
+	switch opts.GoToolchain {
+	case "off":
+	case "":
+		s.goBinary, _ = exec.LookPath("go")
+	default:
+		s.goBinary = opts.GoToolchain
+	}
+	if s.goBinary != "" {
+		// The first build compiles the standard library for WASI, do it
+		// before an agent is waiting on it
+		go s.buildGo(ctx, "package main\n\nfunc main() {}\n")
+	}
+	return s, nil
+}
+
+// languages lists what the sandbox can run
+func (s *sandbox) languages() []string {
+	languages := []string{LanguageWasm}
+	if s.goBinary != "" {
+		languages = append(languages, LanguageGo)
+	}
+	return append(languages, slices.Sorted(maps.Keys(s.runtimes))...)
+}
+
+// tool declares run_code, its language argument is limited to what the
+// sandbox can run
+func (s *sandbox) tool() ToolDef {
+	languages := s.languages()
+	def := MustTypedTool(ToolRunCode,
+		fmt.Sprintf("Run a program in an isolated sandbox without filesystem or network access and get its output. "+
+			"Languages: %s. Go programs are a main package that may import the standard library only, "+
+			"wasm is a base64 encoded WASI module. Runs are limited to %s of wall-clock time and %d MiB of memory.",
+			strings.Join(languages, ", "), s.limits.Timeout, s.memory>>20),
+		s.run)
+	def.InputSchema["properties"].(map[string]any)["language"].(map[string]any)["enum"] = languages
+	return def
+}
+
+type RunCodeInput struct {
+	Language string   `json:"language" mcp:"required" description:"The language of the code"`
+	Code     string   `json:"code" mcp:"required" description:"The program's source, or the base64 encoded module for wasm"`
+	Stdin    string   `json:"stdin" description:"Standard input for the program"`
+	Args     []string `json:"args" description:"Command line arguments, after the program name"`
+}
+
+type RunCodeResult struct {
+	Stage      string `json:"stage" description:"build when a Go program did not compile, run otherwise"`
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr" description:"Standard error, or the compiler's errors for the build stage"`
+	ExitCode   int    `json:"exit_code" description:"The exit status, 0 on success"`
+	TimedOut   bool   `json:"timed_out" description:"Whether the program was stopped for running too long"`
+	Error      string `json:"error,omitempty" description:"Why the program stopped abnormally, such as a trap or running out of memory"`
+	DurationMS int64  `json:"duration_ms" description:"How long the program ran"`
+}
+
+func (s *sandbox) run(ctx context.Context, in RunCodeInput) (RunCodeResult, error) {
+	if len(in.Code) > maxCodeSize {
+		return RunCodeResult{}, fmt.Errorf("code is %d bytes, the limit is %d", len(in.Code), maxCodeSize)
+	}
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return RunCodeResult{}, err
+	}
+	defer release()
+
+	args := append([]string{in.Language}, in.Args...)
+	switch in.Language {
+	case LanguageGo:
+		if s.goBinary == "" {
+			return RunCodeResult{}, fmt.Errorf("go is not available, languages: %s", strings.Join(s.languages(), ", "))
+		}
+		ReportProgress(ctx, Progress{Progress: 0, Total: 2, Message: "Building the program"})
+		wasm, output, err := s.buildGo(ctx, in.Code)
+		if err != nil {
+			return RunCodeResult{}, err
+		}
+		if wasm == nil {
+			return RunCodeResult{Stage: "build", Stderr: truncate(output, len(output), s.limits.MaxOutput), ExitCode: 1}, nil
+		}
+		ReportProgress(ctx, Progress{Progress: 1, Total: 2, Message: "Running the program"})
+		module, release, err := s.compile(ctx, wasm, false)
+		if err != nil {
+			return RunCodeResult{}, fmt.Errorf("compile module: %w", err)
+		}
+		defer release()
+		return s.exec(ctx, module, args, in.Stdin), nil
+	case LanguageWasm:
+		wasm, err := base64.StdEncoding.DecodeString(in.Code)
+		if err != nil {
+			return RunCodeResult{}, fmt.Errorf("wasm code must be base64 encoded: %w", err)
+		}
+		module, release, err := s.compile(ctx, wasm, false)
+		if err != nil {
+			return RunCodeResult{}, fmt.Errorf("invalid module: %w", err)
+		}
+		defer release()
+		return s.exec(ctx, module, args, in.Stdin), nil
+	default:
+		runtime, ok := s.runtimes[in.Language]
+		if !ok {
+			return RunCodeResult{}, fmt.Errorf("unknown language '%s', must be one of: %s", in.Language, strings.Join(s.languages(), ", "))
+		}
+		args = []string{in.Language}
+		for _, arg := range runtime.args {
+			args = append(args, strings.ReplaceAll(arg, codePlaceholder, in.Code))
+		}
+		args = append(args, in.Args...)
+		return s.exec(ctx, runtime.module, args, in.Stdin), nil
+	}
+}
+
+// acquire waits for a slot to build and run a program in, and returns the
+// function that frees it
+func (s *sandbox) acquire(ctx context.Context) (func(), error) {
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		ReportProgress(ctx, Progress{Message: "Waiting for other programs to finish"})
+		select {
+		case s.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no free sandbox: %w", context.Cause(ctx))
+		}
+	}
+	return func() { <-s.slots }, nil
+}
+
+// compile returns the compiled module for wasm and a function to call once
+// the run is done. Pinned modules are never closed, the least recently used
+// unpinned ones are beyond maxCachedModules.
+func (s *sandbox) compile(ctx context.Context, wasm []byte, pinned bool) (wazero.CompiledModule, func(), error) {
+	key := sha256.Sum256(wasm)
+	s.mu.Lock()
+	cached, ok := s.modules[key]
+	s.mu.Unlock()
+	if !ok {
+		module, err := s.runtime.CompileModule(ctx, wasm)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.mu.Lock()
+		// A concurrent run may have compiled it too, both share the code
+		if cached, ok = s.modules[key]; !ok {
+			cached = &cachedModule{module: module}
+			s.modules[key] = cached
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cached.users++
+	cached.pinned = cached.pinned || pinned
+	cached.used = time.Now()
+	s.evict()
+	return cached.module, func() {
+		s.mu.Lock()
+		cached.users--
+		s.mu.Unlock()
+	}, nil
+}
+
+// evict closes unused modules beyond the cache size, s.mu must be held
+func (s *sandbox) evict() {
+	for len(s.modules) > maxCachedModules {
+		var oldest [sha256.Size]byte
+		var found *cachedModule
+		for key, cached := range s.modules {
+			if cached.users == 0 && !cached.pinned && (found == nil || cached.used.Before(found.used)) {
+				oldest, found = key, cached
+			}
+		}
+		if found == nil {
+			return
+		}
+		found.module.Close(context.Background())
+		delete(s.modules, oldest)
+	}
+}
+
+// exec instantiates module, which runs its _start function to completion
+func (s *sandbox) exec(ctx context.Context, module wazero.CompiledModule, args []string, stdin string) RunCodeResult {
+	ctx, cancel := context.WithTimeout(ctx, s.limits.Timeout)
+	defer cancel()
+
+	stdout := &cappedBuffer{limit: s.limits.MaxOutput}
+	stderr := &cappedBuffer{limit: s.limits.MaxOutput}
+	config := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(args...).
+		WithStdin(strings.NewReader(stdin)).
+		WithStdout(stdout).
+		WithStderr(stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep().
+		WithRandSource(rand.Reader)
+
+	started := time.Now()
+	instance, err := s.runtime.InstantiateModule(ctx, module, config)
+	result := RunCodeResult{Stage: "run", DurationMS: time.Since(started).Milliseconds()}
+	if err == nil {
+		instance.Close(context.Background())
+	}
+
+	var exitErr *sys.ExitError
+	switch {
+	case err == nil:
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.TimedOut = true
+		result.ExitCode = -1
+		result.Error = fmt.Sprintf("stopped after %s", s.limits.Timeout)
+	case errors.As(err, &exitErr):
+		result.ExitCode = int(exitErr.ExitCode())
+	default:
+		result.ExitCode = -1
+		result.Error = err.Error()
+	}
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	return result
+}
+
+// buildGo compiles a main package for WASI. Compiler errors are returned as
+// output with a nil module.
+func (s *sandbox) buildGo(ctx context.Context, code string) ([]byte, string, error) {
+	dir, err := os.MkdirTemp("", "run-code-")
+	if err != nil {
+		return nil, "", fmt.Errorf("build: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{"go.mod": "module program\n\ngo 1.21\n", "main.go": code}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			return nil, "", fmt.Errorf("build: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, goBuildTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, s.goBinary, "build", "-trimpath", "-o", "program.wasm", ".")
+	cmd.Dir = dir
+	// Only the standard library can be imported, nothing is downloaded
+	cmd.Env = append(os.Environ(),
+		"GOOS=wasip1", "GOARCH=wasm", "CGO_ENABLED=0",
+		"GOFLAGS=-mod=mod", "GOPROXY=off", "GOTOOLCHAIN=local", "GOWORK=off")
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return nil, "", fmt.Errorf("build timed out after %s", goBuildTimeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil, strings.TrimPrefix(string(output), "# program\n"), nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("build: %w", err)
+	}
+
+	wasm, err := os.ReadFile(filepath.Join(dir, "program.wasm"))
+	if err != nil {
+		return nil, "", fmt.Errorf("build: %w", err)
+	}
+	return wasm, string(output), nil
+}
+
+// cappedBuffer keeps the start of what is written to it, one byte past the
+// limit so truncate can find a rune boundary
+type cappedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+	size  int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.size += len(p)
+	if room := b.limit + 1 - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+func (b *cappedBuffer) String() string {
+	return truncate(b.buf.String(), b.size, b.limit)
+}
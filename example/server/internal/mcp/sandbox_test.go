@@ -0,0 +1,130 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSandbox(t *testing.T) {
+	module := func(name string) string {
+		wasm, err := os.ReadFile("testdata/sandbox/" + name + ".wasm")
+		require.NoError(t, err)
+		return base64.StdEncoding.EncodeToString(wasm)
+	}
+	s, err := newSandbox(CodeOptions{
+		MemoryLimit: 1 << 20,
+		GoToolchain: "off",
+		Runtimes:    map[string]CodeRuntime{"echo": {Module: "testdata/sandbox/echo.wasm", Args: []string{"{code}"}}},
+	}, ToolLimits{Timeout: 100 * time.Millisecond, MaxOutput: 10})
+	require.NoError(t, err)
+	run := func(in RunCodeInput) RunCodeResult {
+		result, err := s.run(context.Background(), in)
+		require.NoError(t, err)
+		return result
+	}
+
+	result := run(RunCodeInput{Language: LanguageWasm, Code: module("echo"), Stdin: "hi\n"})
+	assert.Equal(t, RunCodeResult{Stage: "run", Stdout: "hi\n", Stderr: "done\n", ExitCode: 3, DurationMS: result.DurationMS}, result)
+
+	result = run(RunCodeInput{Language: "echo", Code: "ignored", Stdin: strings.Repeat("x", 200)})
+	assert.Equal(t, "xxxxxxxxxx\n[truncated 190 of 200 bytes]", result.Stdout)
+
+	result = run(RunCodeInput{Language: LanguageWasm, Code: module("spin")})
+	assert.True(t, result.TimedOut)
+	assert.Equal(t, "stopped after 100ms", result.Error)
+
+	// Growing past the memory limit fails, the module traps
+	result = run(RunCodeInput{Language: LanguageWasm, Code: module("grow")})
+	assert.Equal(t, -1, result.ExitCode)
+	assert.Contains(t, result.Error, "unreachable")
+
+	_, err = s.run(context.Background(), RunCodeInput{Language: LanguageWasm, Code: "bm90IHdhc20="})
+	assert.ErrorContains(t, err, "invalid module")
+	_, err = s.run(context.Background(), RunCodeInput{Language: LanguageGo, Code: "package main"})
+	assert.ErrorContains(t, err, "go is not available, languages: wasm, echo")
+
+	def := s.tool()
+	assert.Equal(t, []string{"wasm", "echo"}, def.InputSchema["properties"].(map[string]any)["language"].(map[string]any)["enum"])
+	assert.Contains(t, def.Description, "limited to 100ms of wall-clock time and 1 MiB of memory")
+	assert.True(t, callTool(t, def, map[string]any{"language": "python", "code": "print(1)"}).IsError)
+
+	// Programs beyond the concurrency limit wait for a free slot
+	s, err = newSandbox(CodeOptions{GoToolchain: "off", Concurrency: 1}, ToolLimits{Timeout: 200 * time.Millisecond})
+	require.NoError(t, err)
+	spun := make(chan RunCodeResult)
+	go func() {
+		result, _ := s.run(context.Background(), RunCodeInput{Language: LanguageWasm, Code: module("spin")})
+		spun <- result
+	}()
+	require.Eventually(t, func() bool { return len(s.slots) == 1 }, time.Second, time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = s.run(ctx, RunCodeInput{Language: LanguageWasm, Code: module("echo")})
+	assert.ErrorContains(t, err, "no free sandbox")
+	assert.True(t, (<-spun).TimedOut)
+	result = run(RunCodeInput{Language: LanguageWasm, Code: module("echo"), Stdin: "hi\n"})
+	assert.Equal(t, "hi\n", result.Stdout)
+}
+
+func TestSandboxGo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("building for WASI is slow")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	defs, err := ToolPack(PackOptions{
+		Disabled: []string{ToolCalculate, ToolCurrentTime, ToolJSONQuery},
+		Code:     CodeOptions{Enabled: true},
+		Limits:   map[string]ToolLimits{ToolRunCode: {Timeout: 30 * time.Second}},
+	})
+	require.NoError(t, err)
+	require.Len(t, defs, 1)
+
+	call := func(code string) RunCodeResult {
+		result := callTool(t, defs[0], map[string]any{"language": "go", "code": code, "args": []any{"world"}})
+		require.False(t, result.IsError, resultText(t, result))
+		data, err := json.Marshal(result.StructuredContent)
+		require.NoError(t, err)
+		var out RunCodeResult
+		require.NoError(t, json.Unmarshal(data, &out))
+		return out
+	}
+
+	result := call(`package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+func main() {
+	fmt.Println("hello", os.Args[1])
+	if _, err := os.ReadFile("/etc/passwd"); err != nil {
+		fmt.Fprintln(os.Stderr, "no files")
+	}
+	if _, err := http.Get("http://example.com"); err != nil {
+		fmt.Fprintln(os.Stderr, "no network")
+	}
+	os.Exit(2)
+}
+`)
+	assert.Equal(t, "run", result.Stage)
+	assert.Equal(t, "hello world\n", result.Stdout)
+	assert.Equal(t, "no files\nno network\n", result.Stderr)
+	assert.Equal(t, 2, result.ExitCode)
+
+	result = call("package main\n\nfunc main() { undefined() }\n")
+	assert.Equal(t, "build", result.Stage)
+	assert.Contains(t, result.Stderr, "./main.go:3:15: undefined: undefined")
+}
@@ -2,108 +2,261 @@ package mcp
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
 
-	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
 const DefaultPort = 3217
 
+// Transport selects how the MCP server is exposed to clients
+type Transport string
+
+const (
+	TransportStdio          Transport = "stdio"
+	TransportSSE            Transport = "sse"
+	TransportStreamableHTTP Transport = "http"
+)
+
+// ParseTransport validates a transport name
+func ParseTransport(name string) (Transport, error) {
+	switch t := Transport(strings.ToLower(name)); t {
+	case TransportStdio, TransportSSE, TransportStreamableHTTP:
+		return t, nil
+	default:
+		return "", fmt.Errorf("unknown MCP transport '%s', must be one of: stdio, sse, http", name)
+	}
+}
+
+// httpServer is implemented by both the SSE and streamable HTTP servers
+type httpServer interface {
+	Start(addr string) error
+	Shutdown(ctx context.Context) error
+}
+
 type Server struct {
-	server *server.StreamableHTTPServer
-	port   int
+	mcpServer *server.MCPServer
+	transport Transport
+	port      int
+	toolsDir  string
+
+	// toolsMu guards the tool set, which is the static tools plus those
+	// loaded from toolsDir and can change at runtime
+	toolsMu       sync.Mutex
+	tools         []ToolDef
+	manifestTools []ToolDef
+
+	promptsDir   string
+	resourcesDir string
+
+	httpServer  httpServer
+	stdioCtx    context.Context
+	stdioCancel context.CancelFunc
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithTransport selects the transport, streamable HTTP by default
+func WithTransport(transport Transport) Option {
+	return func(s *Server) {
+		s.transport = transport
+	}
+}
+
+// WithTools registers additional tools
+func WithTools(defs ...ToolDef) Option {
+	return func(s *Server) {
+		s.tools = append(s.tools, defs...)
+	}
+}
+
+// WithToolsDir loads tool manifests from dir, see LoadManifests
+func WithToolsDir(dir string) Option {
+	return func(s *Server) {
+		s.toolsDir = dir
+	}
+}
+
+// WithPromptsDir adds the markdown prompt templates in dir, see PromptDef
+func WithPromptsDir(dir string) Option {
+	return func(s *Server) {
+		s.promptsDir = dir
+	}
+}
+
+// WithResourcesDir exposes the text files under dir as resources, see LoadResources
+func WithResourcesDir(dir string) Option {
+	return func(s *Server) {
+		s.resourcesDir = dir
+	}
+}
+
+func NewServer(port int, opts ...Option) (*Server, error) {
+	s := &Server{
+		transport: TransportStreamableHTTP,
+		port:      port,
+		tools:     BuiltinTools(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.toolsDir != "" {
+		defs, err := LoadManifests(s.toolsDir)
+		if err != nil {
+			return nil, err
+		}
+		s.manifestTools = defs
+	}
+
+	prompts, err := loadPromptSet(s.promptsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []server.ServerResource
+	if s.resourcesDir != "" {
+		resources, err = LoadResources(s.resourcesDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mcpServer, err := newMCPServer(s.toolSet(), prompts, resources)
+	if err != nil {
+		return nil, err
+	}
+	s.mcpServer = mcpServer
+
+	switch s.transport {
+	case TransportStreamableHTTP:
+		s.httpServer = server.NewStreamableHTTPServer(s.mcpServer)
+	case TransportSSE:
+		s.httpServer = server.NewSSEServer(s.mcpServer, server.WithBaseURL(fmt.Sprintf("http://127.0.0.1:%d", port)))
+	case TransportStdio:
+		s.stdioCtx, s.stdioCancel = context.WithCancel(context.Background())
+	default:
+		return nil, fmt.Errorf("unknown MCP transport '%s'", s.transport)
+	}
+
+	return s, nil
 }
 
-func NewServer(port int) (*Server, error) {
+// newMCPServer creates the MCP server with our tools, prompts and resources, shared by every transport
+func newMCPServer(defs []ToolDef, prompts []PromptDef, resources []server.ServerResource) (*server.MCPServer, error) {
 	// Create a new MCP server
 	s := server.NewMCPServer(
 		"Demo 🚀",
 		"1.0.0",
-		server.WithToolCapabilities(false),
+		server.WithToolCapabilities(true),
+		server.WithPromptCapabilities(false),
+		server.WithResourceCapabilities(false, false),
+		server.WithElicitation(),
 	)
+	// Tools may ask the host's model for completions, see CallHandlers
+	s.EnableSampling()
 
-	// Add tool
-	tool := mcp.NewTool("provide_language_options",
-		mcp.WithDescription("Provide a list of programming languages to choose from"),
-		mcp.WithString("option1",
-			mcp.Required(),
-			mcp.Description("Name of the first programming language option"),
-		),
-		mcp.WithString("option2",
-			mcp.Required(),
-			mcp.Description("Name of the second programming language option"),
-		),
-		mcp.WithString("option3",
-			mcp.Required(),
-			mcp.Description("Name of the third programming language option"),
-		),
-		mcp.WithString("option4",
-			mcp.Required(),
-			mcp.Description("Name of the fourth programming language option"),
-		),
-	)
+	tools, err := serverTools(defs)
+	if err != nil {
+		return nil, err
+	}
+	s.AddTools(tools...)
 
-	// Add tool handler
-	s.AddTool(tool, languageChoiceHandler)
+	// Prompt files are also readable as resources so hosts can attach them verbatim
+	for _, prompt := range prompts {
+		s.AddPrompts(prompt.serverPrompt())
+		s.AddResources(prompt.serverResource())
+	}
+	s.AddResources(resources...)
 
-	streamableServer := server.NewStreamableHTTPServer(s)
+	return s, nil
+}
 
-	return &Server{
-		server: streamableServer,
-		port:   port,
-	}, nil
+func serverTools(defs []ToolDef) ([]server.ServerTool, error) {
+	tools := make([]server.ServerTool, 0, len(defs))
+	for _, def := range defs {
+		tool, err := def.serverTool()
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, tool)
+	}
+	return tools, nil
 }
 
+// Start serves until Shutdown is called. The stdio transport reads requests
+// from stdin and writes responses to stdout.
 func (s *Server) Start() error {
-	portString := fmt.Sprintf(":%d", s.port)
-	return s.server.Start(portString)
-}
+	if s.transport == TransportStdio {
+		err := server.NewStdioServer(s.mcpServer).Listen(s.stdioCtx, os.Stdin, os.Stdout)
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	}
 
-func (s *Server) Shutdown(ctx context.Context) error {
-	return s.server.Shutdown(ctx)
+	portString := fmt.Sprintf(":%d", s.port)
+	return s.httpServer.Start(portString)
 }
 
-type LanguageOptions struct {
-	Option1 string
-	Option2 string
-	Option3 string
-	Option4 string
+// Endpoint returns the URL local clients connect to, empty for stdio
+func (s *Server) Endpoint() string {
+	switch s.transport {
+	case TransportStreamableHTTP:
+		return fmt.Sprintf("http://127.0.0.1:%d/mcp", s.port)
+	case TransportSSE:
+		return fmt.Sprintf("sse+http://127.0.0.1:%d/sse", s.port)
+	default:
+		return ""
+	}
 }
 
-func languageChoiceHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	optionOne, err := request.RequireString("option1")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+// CheckListening reports whether the server accepts connections on its port
+func (s *Server) CheckListening(ctx context.Context) error {
+	if s.transport == TransportStdio {
+		return nil
 	}
 
-	optionTwo, err := request.RequireString("option2")
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", fmt.Sprintf("127.0.0.1:%d", s.port))
 	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+		return fmt.Errorf("mcp server not listening: %w", err)
 	}
+	return conn.Close()
+}
 
-	optionThree, err := request.RequireString("option3")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.transport == TransportStdio {
+		s.stdioCancel()
+		return nil
 	}
+	return s.httpServer.Shutdown(ctx)
+}
 
-	optionFour, err := request.RequireString("option4")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
+// BuiltinTools returns the tools every server provides
+func BuiltinTools() []ToolDef {
+	languages := MustTypedTool("provide_language_options",
+		"Provide a list of programming languages to choose from",
+		languageChoiceHandler,
+	)
+	languages.Idempotent = true
+	languages.Choices = true
+	return []ToolDef{languages}
+}
 
-	// Marshal the struct to a JSON byte slice
-	jsonData, err := json.Marshal(LanguageOptions{
-		Option1: optionOne,
-		Option2: optionTwo,
-		Option3: optionThree,
-		Option4: optionFour,
-	})
-	if err != nil {
-		fmt.Println("Error marshaling JSON:", err)
-		return nil, err
-	}
+type LanguageOptions struct {
+	Option1 string `json:"option1" mcp:"required" description:"Name of the first programming language option"`
+	Option2 string `json:"option2" mcp:"required" description:"Name of the second programming language option"`
+	Option3 string `json:"option3" mcp:"required" description:"Name of the third programming language option"`
+	Option4 string `json:"option4" mcp:"required" description:"Name of the fourth programming language option"`
+}
 
-	return mcp.NewToolResultText(string(jsonData)), nil
+func languageChoiceHandler(ctx context.Context, options LanguageOptions) (LanguageOptions, error) {
+	return options, nil
 }
@@ -0,0 +1,17 @@
+;; Copies stdin to stdout, writes "done" to stderr and exits with status 3
+(module
+  (import "wasi_snapshot_preview1" "fd_read" (func $fd_read (param i32 i32 i32 i32) (result i32)))
+  (import "wasi_snapshot_preview1" "fd_write" (func $fd_write (param i32 i32 i32 i32) (result i32)))
+  (import "wasi_snapshot_preview1" "proc_exit" (func $proc_exit (param i32)))
+  (memory (export "memory") 1)
+  (data (i32.const 8) "done\n")
+  (func (export "_start")
+    (i32.store (i32.const 16) (i32.const 1024))
+    (i32.store (i32.const 20) (i32.const 1024))
+    (drop (call $fd_read (i32.const 0) (i32.const 16) (i32.const 1) (i32.const 24)))
+    (i32.store (i32.const 20) (i32.load (i32.const 24)))
+    (drop (call $fd_write (i32.const 1) (i32.const 16) (i32.const 1) (i32.const 28)))
+    (i32.store (i32.const 32) (i32.const 8))
+    (i32.store (i32.const 36) (i32.const 5))
+    (drop (call $fd_write (i32.const 2) (i32.const 32) (i32.const 1) (i32.const 28)))
+    (call $proc_exit (i32.const 3))))
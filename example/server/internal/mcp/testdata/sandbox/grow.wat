@@ -0,0 +1,6 @@
+;; Grows its memory by 2 MiB and traps when that fails
+(module
+  (memory (export "memory") 1)
+  (func (export "_start")
+    (if (i32.eq (memory.grow (i32.const 32)) (i32.const -1))
+      (then unreachable))))
@@ -0,0 +1,229 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/schema"
+)
+
+// ToolFunc handles a tool call. Arguments have already been validated against
+// the tool's input schema. A *mcp.CallToolResult is sent as is, which is how
+// handlers return images or embedded resources. With an output schema the
+// result is sent as structured content, otherwise a string result is returned
+// to the client as text and anything else is marshaled to JSON.
+type ToolFunc func(ctx context.Context, args map[string]any) (any, error)
+
+// ChoicesMeta is the _meta key set on the results of tools declared with
+// Choices, telling clients the structured content holds options for the user
+const ChoicesMeta = "ag-ui/choices"
+
+// InteractiveMeta is the _meta key set on tools declared Interactive, telling
+// clients the tool sends elicitation or sampling requests while it runs
+const InteractiveMeta = "ag-ui/interactive"
+
+// ToolDef declares a tool independently of how it is implemented
+type ToolDef struct {
+	Name        string
+	Description string
+	InputSchema map[string]any
+	// OutputSchema describes structured results, it must be an object schema
+	OutputSchema map[string]any
+	// Idempotent tools can safely be called again with the same arguments,
+	// clients may retry them after connection failures
+	Idempotent bool
+	// Choices marks the structured results as options for the user to pick
+	// from, see ChoicesMeta
+	Choices bool
+	// Interactive tools ask the user or the host's model for input while they
+	// run, see InteractiveMeta
+	Interactive bool
+	Handler     ToolFunc
+}
+
+// TypedTool declares a tool whose arguments are bound to In. The input schema
+// is derived from In's struct tags, see schema.For. When Out is a struct its
+// schema becomes the output schema and results are sent as structured content.
+func TypedTool[In, Out any](name, description string, fn func(ctx context.Context, in In) (Out, error)) (ToolDef, error) {
+	inputSchema, err := schema.Of[In]()
+	if err != nil {
+		return ToolDef{}, fmt.Errorf("tool %s: %w", name, err)
+	}
+
+	var outputSchema map[string]any
+	outType := reflect.TypeOf((*Out)(nil)).Elem()
+	for outType.Kind() == reflect.Pointer {
+		outType = outType.Elem()
+	}
+	if outType.Kind() == reflect.Struct {
+		if outputSchema, err = schema.For(outType); err != nil {
+			return ToolDef{}, fmt.Errorf("tool %s output: %w", name, err)
+		}
+	}
+
+	return ToolDef{
+		Name:         name,
+		Description:  description,
+		InputSchema:  inputSchema,
+		OutputSchema: outputSchema,
+		Handler: func(ctx context.Context, args map[string]any) (any, error) {
+			var in In
+			if err := bind(args, &in); err != nil {
+				return nil, err
+			}
+			return fn(ctx, in)
+		},
+	}, nil
+}
+
+// MustTypedTool is TypedTool for tool sets known at compile time
+func MustTypedTool[In, Out any](name, description string, fn func(ctx context.Context, in In) (Out, error)) ToolDef {
+	def, err := TypedTool(name, description, fn)
+	if err != nil {
+		panic(err)
+	}
+	return def
+}
+
+// bind copies validated arguments into a typed struct
+func bind(args map[string]any, target any) error {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("encode arguments: %w", err)
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// serverTool compiles the schema and wraps the handler with validation and
+// result marshaling
+func (d ToolDef) serverTool() (server.ServerTool, error) {
+	if d.Name == "" {
+		return server.ServerTool{}, fmt.Errorf("tool name is required")
+	}
+	if d.Handler == nil {
+		return server.ServerTool{}, fmt.Errorf("tool %s has no handler", d.Name)
+	}
+
+	inputSchema := d.InputSchema
+	if inputSchema == nil {
+		inputSchema = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	validator, err := schema.Compile(d.Name, inputSchema)
+	if err != nil {
+		return server.ServerTool{}, err
+	}
+	raw, err := json.Marshal(inputSchema)
+	if err != nil {
+		return server.ServerTool{}, fmt.Errorf("tool %s: %w", d.Name, err)
+	}
+
+	tool := mcp.NewToolWithRawSchema(d.Name, d.Description, raw)
+	tool.Annotations.IdempotentHint = mcp.ToBoolPtr(d.Idempotent)
+	if d.Interactive {
+		tool.Meta = &mcp.Meta{AdditionalFields: map[string]any{InteractiveMeta: true}}
+	}
+	var outputValidator *schema.Validator
+	if d.OutputSchema != nil {
+		if d.OutputSchema["type"] != "object" {
+			return server.ServerTool{}, fmt.Errorf("tool %s: output schema must be of type object", d.Name)
+		}
+		if outputValidator, err = schema.Compile(d.Name+"-output", d.OutputSchema); err != nil {
+			return server.ServerTool{}, err
+		}
+		if tool.RawOutputSchema, err = json.Marshal(d.OutputSchema); err != nil {
+			return server.ServerTool{}, fmt.Errorf("tool %s: %w", d.Name, err)
+		}
+	}
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := request.GetArguments()
+		if args == nil {
+			args = map[string]any{}
+		}
+		if err := validator.Validate(args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid arguments: %v", err)), nil
+		}
+
+		result, err := d.Handler(withProgressToken(ctx, request), args)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		r, ok := result.(*mcp.CallToolResult)
+		switch {
+		case ok:
+		case outputValidator != nil:
+			r = structuredResult(result, outputValidator)
+		default:
+			if r, err = toolResult(result); err != nil {
+				return nil, err
+			}
+		}
+		if d.Choices && !r.IsError {
+			markChoices(r)
+		}
+		return r, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}, nil
+}
+
+// structuredResult validates the result against the output schema and sends
+// it as structured content, with its JSON as the text fallback for older clients.
+// String results are expected to hold JSON, as shell and webhook handlers return.
+func structuredResult(result any, validator *schema.Validator) *mcp.CallToolResult {
+	var data []byte
+	switch r := result.(type) {
+	case string:
+		data = []byte(r)
+	case []byte:
+		data = r
+	default:
+		var err error
+		if data, err = json.Marshal(result); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal tool result: %v", err))
+		}
+	}
+
+	var structured any
+	if err := json.Unmarshal(data, &structured); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("tool result is not JSON: %v", err))
+	}
+	if err := validator.Validate(structured); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("tool result does not match its output schema: %v", err))
+	}
+	return mcp.NewToolResultStructured(structured, string(data))
+}
+
+// markChoices sets ChoicesMeta on result, keeping its other metadata
+func markChoices(result *mcp.CallToolResult) {
+	if result.Meta == nil {
+		result.Meta = &mcp.Meta{}
+	}
+	if result.Meta.AdditionalFields == nil {
+		result.Meta.AdditionalFields = map[string]any{}
+	}
+	result.Meta.AdditionalFields[ChoicesMeta] = true
+}
+
+func toolResult(result any) (*mcp.CallToolResult, error) {
+	switch r := result.(type) {
+	case string:
+		return mcp.NewToolResultText(r), nil
+	case []byte:
+		return mcp.NewToolResultText(string(r)), nil
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("marshal tool result: %w", err)
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
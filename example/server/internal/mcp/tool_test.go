@@ -0,0 +1,113 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func callTool(t *testing.T, def ToolDef, args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+
+	tool, err := def.serverTool()
+	require.NoError(t, err)
+
+	var request mcp.CallToolRequest
+	request.Params.Name = def.Name
+	request.Params.Arguments = args
+	result, err := tool.Handler(context.Background(), request)
+	require.NoError(t, err)
+	return result
+}
+
+func resultText(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+
+	require.Len(t, result.Content, 1)
+	text, ok := result.Content[0].(mcp.TextContent)
+	require.True(t, ok)
+	return text.Text
+}
+
+func TestTypedTool(t *testing.T) {
+	def := BuiltinTools()[0]
+	assert.Equal(t, []string{"option1", "option2", "option3", "option4"}, def.InputSchema["required"])
+
+	result := callTool(t, def, map[string]any{"option1": "Go", "option2": "Rust", "option3": "Zig", "option4": "C"})
+	assert.False(t, result.IsError)
+	assert.JSONEq(t, `{"option1":"Go","option2":"Rust","option3":"Zig","option4":"C"}`, resultText(t, result))
+
+	result = callTool(t, def, map[string]any{"option1": "Go", "option2": 3})
+	assert.True(t, result.IsError)
+	assert.Contains(t, resultText(t, result), "option2")
+	assert.Contains(t, resultText(t, result), "option3")
+}
+
+func TestLoadManifests(t *testing.T) {
+	dir := t.TempDir()
+	manifest := `
+name: greet
+description: Greet someone
+input_schema:
+  type: object
+  properties:
+    name: {type: string}
+  required: [name]
+handler:
+  type: shell
+  command: ["sh", "-c", "echo hello $MCP_ARG_NAME"]
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "greet.yaml"), []byte(manifest), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("ignored"), 0o644))
+
+	defs, err := LoadManifests(dir)
+	require.NoError(t, err)
+	require.Len(t, defs, 1)
+
+	result := callTool(t, defs[0], map[string]any{"name": "gopher"})
+	assert.False(t, result.IsError)
+	assert.Equal(t, "hello gopher", resultText(t, result))
+
+	result = callTool(t, defs[0], map[string]any{})
+	assert.True(t, result.IsError)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"name": "bad", "handler": {"type": "ftp"}}`), 0o644))
+	_, err = LoadManifests(dir)
+	assert.ErrorContains(t, err, "unknown handler type")
+}
+
+func TestAdapterStructuredResult(t *testing.T) {
+	server := startServer(t, freePort(t))
+	defer stopServer(server)
+
+	adapter, err := NewAdapter(server.Endpoint())
+	require.NoError(t, err)
+	defer adapter.Close()
+
+	tools, err := adapter.Tools()
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+
+	var got *mcp.CallToolResult
+	ctx := WithToolResultHandler(context.Background(), func(ctx context.Context, tool string, result *mcp.CallToolResult) {
+		got = result
+	})
+	text, err := tools[0].Call(ctx, `{"option1":"Go","option2":"Rust","option3":"Zig","option4":"C"}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"option1":"Go","option2":"Rust","option3":"Zig","option4":"C"}`, text)
+
+	require.NotNil(t, got)
+	assert.Equal(t, map[string]any{"option1": "Go", "option2": "Rust", "option3": "Zig", "option4": "C"}, got.StructuredContent)
+	require.NotNil(t, got.Meta)
+	assert.Equal(t, true, got.Meta.AdditionalFields[ChoicesMeta])
+
+	text, err = tools[0].Call(ctx, `not json`)
+	require.NoError(t, err)
+	assert.Contains(t, text, "input must be valid json")
+	assert.True(t, got.IsError)
+}
@@ -0,0 +1,570 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Names of the tools in the built-in pack
+const (
+	ToolReadFile    = "read_file"
+	ToolListFiles   = "list_files"
+	ToolFetchURL    = "fetch_url"
+	ToolCalculate   = "calculate"
+	ToolCurrentTime = "current_time"
+	ToolJSONQuery   = "json_query"
+	ToolRunCode     = "run_code"
+)
+
+// PackTools lists the tools of the built-in pack
+var PackTools = []string{ToolReadFile, ToolListFiles, ToolFetchURL, ToolCalculate, ToolCurrentTime, ToolJSONQuery, ToolRunCode}
+
+// Limits applied to pack tools that do not set their own
+const (
+	DefaultPackTimeout   = 10 * time.Second
+	DefaultPackMaxOutput = 64 << 10
+)
+
+// maxListEntries caps the entries list_files returns
+const maxListEntries = 1000
+
+// PackOptions configures the built-in tool pack
+type PackOptions struct {
+	// FilesRoot confines read_file and list_files, which are only served when it is set
+	FilesRoot string
+	// AllowedHosts are the hosts fetch_url may contact, "*.example.com"
+	// matches the subdomains of example.com
+	AllowedHosts []string
+	// Mirrors maps URL prefixes to local directories fetch_url serves instead,
+	// without contacting the host. Directory URLs serve index.html.
+	Mirrors map[string]string
+	// Disabled names pack tools that are not served
+	Disabled []string
+	// Limits overrides the timeout and output size of tools by name
+	Limits map[string]ToolLimits
+	// Code configures run_code, which is only served when enabled
+	Code CodeOptions
+}
+
+// ToolLimits bounds a tool call, zero values use the pack defaults
+type ToolLimits struct {
+	Timeout time.Duration
+	// MaxOutput is the size in bytes text results are truncated to
+	MaxOutput int
+}
+
+// ToolPack returns the enabled tools of the built-in pack. fetch_url is only
+// served with allowed hosts or mirrors, the file tools only with a root and
+// run_code when code execution is enabled.
+func ToolPack(opts PackOptions) ([]ToolDef, error) {
+	for _, name := range append(slices.Clone(opts.Disabled), slices.Collect(maps.Keys(opts.Limits))...) {
+		if !slices.Contains(PackTools, name) {
+			return nil, fmt.Errorf("unknown built-in tool '%s', must be one of: %s", name, strings.Join(PackTools, ", "))
+		}
+	}
+
+	var defs []ToolDef
+	enabled := func(name string) bool { return !slices.Contains(opts.Disabled, name) }
+
+	if opts.FilesRoot != "" && (enabled(ToolReadFile) || enabled(ToolListFiles)) {
+		info, err := os.Stat(opts.FilesRoot)
+		if err != nil {
+			return nil, fmt.Errorf("files root: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("files root %s is not a directory", opts.FilesRoot)
+		}
+		files := fileTools{root: opts.FilesRoot}
+		if enabled(ToolReadFile) {
+			defs = append(defs, MustTypedTool(ToolReadFile,
+				"Read a text file from the server's files directory. Paths are relative to that directory.",
+				files.read))
+		}
+		if enabled(ToolListFiles) {
+			defs = append(defs, MustTypedTool(ToolListFiles,
+				"List the files in a directory of the server's files directory, directories end with /",
+				files.list))
+		}
+	}
+
+	if (len(opts.AllowedHosts) > 0 || len(opts.Mirrors) > 0) && enabled(ToolFetchURL) {
+		fetcher, err := newFetcher(opts.AllowedHosts, opts.Mirrors)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, MustTypedTool(ToolFetchURL,
+			"Fetch a web page or text document by URL. Only some hosts are reachable, the tool says which when a URL is refused.",
+			fetcher.fetch))
+	}
+
+	if enabled(ToolCalculate) {
+		defs = append(defs, MustTypedTool(ToolCalculate,
+			"Evaluate an arithmetic expression with + - * / % ^, parentheses, the constants pi and e, "+
+				"and the functions abs, ceil, floor, round, sqrt, exp, ln, log, sin, cos, tan, min, max and pow",
+			calculate))
+	}
+	if enabled(ToolCurrentTime) {
+		defs = append(defs, MustTypedTool(ToolCurrentTime,
+			"Get the current date and time, in UTC or an IANA time zone such as Europe/Paris",
+			currentTime))
+	}
+	if enabled(ToolJSONQuery) {
+		defs = append(defs, MustTypedTool(ToolJSONQuery,
+			"Query JSON data with a jq filter such as .items[] | select(.price < 10) | .name",
+			jsonQuery))
+	}
+
+	for i := range defs {
+		defs[i].Idempotent = true
+		defs[i].Handler = limitTool(defs[i].Handler, opts.Limits[defs[i].Name])
+	}
+
+	// The sandbox applies run_code's limits to the program itself, so
+	// building it is not counted against the timeout
+	if opts.Code.Enabled && enabled(ToolRunCode) {
+		sandbox, err := newSandbox(opts.Code, opts.Limits[ToolRunCode])
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, sandbox.tool())
+	}
+	return defs, nil
+}
+
+// limitTool enforces limits on handler. The call is abandoned when it times
+// out, text results longer than the limit are truncated.
+func limitTool(handler ToolFunc, limits ToolLimits) ToolFunc {
+	if limits.Timeout <= 0 {
+		limits.Timeout = DefaultPackTimeout
+	}
+	if limits.MaxOutput <= 0 {
+		limits.MaxOutput = DefaultPackMaxOutput
+	}
+
+	return func(ctx context.Context, args map[string]any) (any, error) {
+		ctx, cancel := context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+		output := &outputSize{limit: limits.MaxOutput}
+		ctx = context.WithValue(ctx, outputSizeKey{}, output)
+
+		type outcome struct {
+			result any
+			err    error
+		}
+		done := make(chan outcome, 1)
+		go func() {
+			result, err := handler(ctx, args)
+			done <- outcome{result, err}
+		}()
+
+		var out outcome
+		select {
+		case out = <-done:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out after %s", limits.Timeout)
+		}
+		if out.err != nil {
+			return nil, out.err
+		}
+		if text, ok := out.result.(string); ok {
+			return truncate(text, max(len(text), output.size), limits.MaxOutput), nil
+		}
+		return out.result, nil
+	}
+}
+
+// outputSize lets a pack tool read only what its output limit keeps, and
+// report the full size of what it read from
+type outputSize struct {
+	limit int
+	size  int
+}
+
+type outputSizeKey struct{}
+
+// readOutput reads r up to one byte past the call's output limit, so the
+// result is truncated with a note when r is longer. size is the full size of
+// r, or -1 when unknown.
+func readOutput(ctx context.Context, r io.Reader, size int64) ([]byte, error) {
+	limit := maxHandlerOutput
+	if output, ok := ctx.Value(outputSizeKey{}).(*outputSize); ok {
+		limit = output.limit
+		output.size = int(size)
+	}
+	return io.ReadAll(io.LimitReader(r, int64(limit)+1))
+}
+
+// isText reports whether data is UTF-8 text without NUL bytes. Its last rune
+// may be incomplete, as reading stops at the output limit.
+func isText(data []byte) bool {
+	end := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				end = i
+			}
+			break
+		}
+	}
+	return utf8.Valid(data[:end]) && !slices.Contains(data, 0)
+}
+
+// truncate cuts text, the start of an output of size bytes, to limit bytes on
+// a rune boundary and notes how much was left out. text must extend past the
+// limit when size does.
+func truncate(text string, size, limit int) string {
+	if size <= limit {
+		return text
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s\n[truncated %d of %d bytes]", text[:cut], size-cut, size)
+}
+
+// fileTools reads files under root. Paths are opened through os.Root, so
+// neither .. nor symlinks reach outside it.
+type fileTools struct {
+	root string
+}
+
+type ReadFileInput struct {
+	Path string `json:"path" mcp:"required" description:"Path of the file, relative to the files directory"`
+}
+
+func (f fileTools) read(ctx context.Context, in ReadFileInput) (string, error) {
+	root, err := os.OpenRoot(f.root)
+	if err != nil {
+		return "", err
+	}
+	defer root.Close()
+
+	file, err := root.Open(relativePath(in.Path))
+	if err != nil {
+		return "", fileError(in.Path, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", fileError(in.Path, err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory, use %s", in.Path, ToolListFiles)
+	}
+
+	// The output limit truncates long files, reading more than that is wasted
+	data, err := readOutput(ctx, file, info.Size())
+	if err != nil {
+		return "", fileError(in.Path, err)
+	}
+	if !isText(data) {
+		return "", fmt.Errorf("%s is not a text file", in.Path)
+	}
+	return string(data), nil
+}
+
+type ListFilesInput struct {
+	Path      string `json:"path" description:"Directory to list, relative to the files directory, the directory itself when empty"`
+	Recursive bool   `json:"recursive" description:"Also list the contents of subdirectories"`
+}
+
+func (f fileTools) list(ctx context.Context, in ListFilesInput) (string, error) {
+	root, err := os.OpenRoot(f.root)
+	if err != nil {
+		return "", err
+	}
+	defer root.Close()
+
+	dir := relativePath(in.Path)
+	var entries []string
+	err = fs.WalkDir(root.FS(), dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(entries) == maxListEntries {
+			entries = append(entries, fmt.Sprintf("[stopped after %d entries]", maxListEntries))
+			return fs.SkipAll
+		}
+
+		name := strings.TrimPrefix(p, dir+"/")
+		if dir == "." {
+			name = p
+		}
+		if entry.IsDir() {
+			entries = append(entries, name+"/")
+			if !in.Recursive {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		entries = append(entries, name)
+		return nil
+	})
+	if err != nil {
+		return "", fileError(in.Path, err)
+	}
+	if len(entries) == 0 {
+		return "The directory is empty.", nil
+	}
+	return strings.Join(entries, "\n"), nil
+}
+
+// relativePath turns a path from the model into one os.Root accepts,
+// leading slashes are dropped so "/docs" is the docs directory of the root
+func relativePath(p string) string {
+	p = path.Clean("/" + filepath.ToSlash(p))
+	if p == "/" {
+		return "."
+	}
+	return strings.TrimPrefix(p, "/")
+}
+
+// fileError rewords errors without revealing where the root is
+func fileError(name string, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("%s does not exist", name)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("%s cannot be read", name)
+	case strings.Contains(err.Error(), "escapes from parent"):
+		return fmt.Errorf("%s is outside the files directory", name)
+	default:
+		return err
+	}
+}
+
+// fetcher gets URLs from mirrors or the allowed hosts
+type fetcher struct {
+	allowed []string
+	// mirrors are sorted by descending prefix length so the longest match wins
+	mirrors []mirror
+	client  *http.Client
+}
+
+type mirror struct {
+	prefix string
+	dir    string
+}
+
+func newFetcher(allowed []string, mirrors map[string]string) (*fetcher, error) {
+	f := &fetcher{}
+	for _, host := range allowed {
+		f.allowed = append(f.allowed, strings.ToLower(host))
+	}
+	for prefix, dir := range mirrors {
+		if _, err := url.Parse(prefix); err != nil || !strings.HasPrefix(prefix, "http://") && !strings.HasPrefix(prefix, "https://") {
+			return nil, fmt.Errorf("fetch mirror prefix '%s' is not an http(s) URL", prefix)
+		}
+		f.mirrors = append(f.mirrors, mirror{prefix: prefix, dir: dir})
+	}
+	slices.SortFunc(f.mirrors, func(a, b mirror) int { return len(b.prefix) - len(a.prefix) })
+
+	f.client = &http.Client{
+		// Redirects must stay on allowed hosts
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			if !f.hostAllowed(req.URL.Hostname()) {
+				return fmt.Errorf("redirected to %s, which is not an allowed host", req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+	return f, nil
+}
+
+type FetchURLInput struct {
+	URL string `json:"url" mcp:"required" description:"The http or https URL to fetch"`
+}
+
+func (f *fetcher) fetch(ctx context.Context, in FetchURLInput) (string, error) {
+	u, err := url.Parse(in.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("'%s' is not an http or https URL", in.URL)
+	}
+
+	for _, m := range f.mirrors {
+		if rest, ok := strings.CutPrefix(in.URL, m.prefix); ok {
+			return f.fromMirror(ctx, m, rest)
+		}
+	}
+
+	if !f.hostAllowed(u.Hostname()) {
+		return "", fmt.Errorf("%s is not an allowed host, allowed hosts are: %s", u.Hostname(), strings.Join(f.allowed, ", "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/*, application/json, application/xml")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch %s: %w", in.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("fetch %s: %s", in.URL, resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !textType(contentType) {
+		return "", fmt.Errorf("%s is %s, only text can be fetched", in.URL, contentType)
+	}
+	data, err := readOutput(ctx, resp.Body, resp.ContentLength)
+	if err != nil {
+		return "", fmt.Errorf("fetch %s: %w", in.URL, err)
+	}
+	return string(data), nil
+}
+
+// fromMirror reads the file rest names in the mirror's directory
+func (f *fetcher) fromMirror(ctx context.Context, m mirror, rest string) (string, error) {
+	rest, _, _ = strings.Cut(rest, "?")
+	rest, _, _ = strings.Cut(rest, "#")
+	if rest == "" || strings.HasSuffix(rest, "/") {
+		rest += "index.html"
+	}
+
+	root, err := os.OpenRoot(m.dir)
+	if err != nil {
+		return "", fmt.Errorf("mirror of %s: %w", m.prefix, err)
+	}
+	defer root.Close()
+	file, err := root.Open(relativePath(rest))
+	if err != nil {
+		return "", fileError(m.prefix+rest, err)
+	}
+	defer file.Close()
+
+	if contentType := mime.TypeByExtension(path.Ext(rest)); contentType != "" && !textType(contentType) {
+		return "", fmt.Errorf("%s%s is %s, only text can be fetched", m.prefix, rest, contentType)
+	}
+	var size int64 = -1
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+	data, err := readOutput(ctx, file, size)
+	if err != nil {
+		return "", fileError(m.prefix+rest, err)
+	}
+	return string(data), nil
+}
+
+// hostAllowed reports whether host matches an allowed host
+func (f *fetcher) hostAllowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range f.allowed {
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// textType reports whether a Content-Type is text that can be returned to the model
+func textType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "xml") ||
+		mediaType == "application/javascript"
+}
+
+type CurrentTimeInput struct {
+	TimeZone string `json:"timezone" description:"IANA time zone such as America/New_York, UTC when empty"`
+}
+
+type CurrentTime struct {
+	Time     string `json:"time" description:"The time in RFC 3339 format"`
+	Date     string `json:"date" description:"The date as YYYY-MM-DD"`
+	Weekday  string `json:"weekday"`
+	TimeZone string `json:"timezone"`
+	Unix     int64  `json:"unix" description:"Seconds since the Unix epoch"`
+}
+
+func currentTime(ctx context.Context, in CurrentTimeInput) (CurrentTime, error) {
+	name := in.TimeZone
+	if name == "" {
+		name = "UTC"
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return CurrentTime{}, fmt.Errorf("unknown time zone '%s'", in.TimeZone)
+	}
+	now := time.Now().In(location)
+	return CurrentTime{
+		Time:     now.Format(time.RFC3339),
+		Date:     now.Format(time.DateOnly),
+		Weekday:  now.Weekday().String(),
+		TimeZone: location.String(),
+		Unix:     now.Unix(),
+	}, nil
+}
+
+type CalculateInput struct {
+	Expression string `json:"expression" mcp:"required" description:"The expression, for example (2 + 3) * sqrt(16)"`
+}
+
+func calculate(ctx context.Context, in CalculateInput) (string, error) {
+	value, err := Evaluate(in.Expression)
+	if err != nil {
+		return "", err
+	}
+	return formatNumber(value), nil
+}
+
+type JSONQueryInput struct {
+	Data  any    `json:"data" mcp:"required" description:"The JSON value to query, or a string holding JSON"`
+	Query string `json:"query" mcp:"required" description:"jq filter, see the tool description"`
+}
+
+func jsonQuery(ctx context.Context, in JSONQueryInput) (string, error) {
+	data := in.Data
+	if text, ok := data.(string); ok {
+		if err := json.Unmarshal([]byte(text), &data); err != nil {
+			return "", fmt.Errorf("data is a string that does not hold JSON: %w", err)
+		}
+	}
+	results, err := Query(ctx, data, in.Query)
+	if err != nil {
+		return "", err
+	}
+
+	lines := make([]string, 0, len(results))
+	for _, result := range results {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return "", fmt.Errorf("encode result: %w", err)
+		}
+		lines = append(lines, string(encoded))
+	}
+	return strings.Join(lines, "\n"), nil
+}
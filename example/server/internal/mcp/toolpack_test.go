@@ -0,0 +1,174 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolPack(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "docs", "guides"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", "intro.md"), []byte("# Intro"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", "guides", "setup.md"), []byte("Setup"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "image.bin"), []byte{0x89, 0, 1}, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notes.txt"), []byte(strings.Repeat("0123456789", 4)), 0o600))
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	require.NoError(t, os.WriteFile(outside, []byte("secret"), 0o600))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "link.txt")))
+
+	mirror := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(mirror, "llms-full.txt"), []byte("AG-UI docs"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(mirror, "index.html"), []byte("<h1>AG-UI</h1>"), 0o600))
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "http://127.0.0.2/", http.StatusFound)
+		case "/long":
+			w.Write([]byte(strings.Repeat("é", 100)))
+		default:
+			w.Write([]byte("hello from " + r.URL.Path))
+		}
+	}))
+	defer site.Close()
+	siteURL, err := url.Parse(site.URL)
+	require.NoError(t, err)
+
+	defs, err := ToolPack(PackOptions{
+		FilesRoot:    root,
+		AllowedHosts: []string{siteURL.Hostname()},
+		Mirrors:      map[string]string{"https://docs.ag-ui.com/": mirror},
+		Disabled:     []string{ToolCurrentTime},
+		Limits:       map[string]ToolLimits{ToolFetchURL: {MaxOutput: 15}, ToolReadFile: {MaxOutput: 16}},
+	})
+	require.NoError(t, err)
+	tools := map[string]ToolDef{}
+	for _, def := range defs {
+		tools[def.Name] = def
+	}
+	require.NotContains(t, tools, ToolCurrentTime)
+
+	call := func(name string, args map[string]any) (string, bool) {
+		result := callTool(t, tools[name], args)
+		return resultText(t, result), result.IsError
+	}
+	ok := func(name string, args map[string]any) string {
+		text, failed := call(name, args)
+		require.False(t, failed, text)
+		return text
+	}
+	fails := func(name string, args map[string]any, message string) {
+		text, failed := call(name, args)
+		require.True(t, failed, text)
+		assert.Contains(t, text, message)
+	}
+
+	t.Run("files", func(t *testing.T) {
+		assert.Equal(t, "# Intro", ok(ToolReadFile, map[string]any{"path": "/docs/intro.md"}))
+		assert.Equal(t, "0123456789012345\n[truncated 24 of 40 bytes]", ok(ToolReadFile, map[string]any{"path": "notes.txt"}))
+		fails(ToolReadFile, map[string]any{"path": "../secret.txt"}, "does not exist")
+		fails(ToolReadFile, map[string]any{"path": "link.txt"}, "outside the files directory")
+		fails(ToolReadFile, map[string]any{"path": "image.bin"}, "not a text file")
+		fails(ToolReadFile, map[string]any{"path": "docs"}, "is a directory")
+
+		assert.Equal(t, "guides/\nintro.md", ok(ToolListFiles, map[string]any{"path": "docs"}))
+		assert.Equal(t, "guides/\nguides/setup.md\nintro.md", ok(ToolListFiles, map[string]any{"path": "docs", "recursive": true}))
+		assert.Equal(t, "docs/\nimage.bin\nlink.txt\nnotes.txt", ok(ToolListFiles, map[string]any{}))
+	})
+
+	t.Run("fetch", func(t *testing.T) {
+		assert.Equal(t, "AG-UI docs", ok(ToolFetchURL, map[string]any{"url": "https://docs.ag-ui.com/llms-full.txt"}))
+		assert.Equal(t, "<h1>AG-UI</h1>", ok(ToolFetchURL, map[string]any{"url": "https://docs.ag-ui.com/"}))
+		assert.Equal(t, "hello from /", ok(ToolFetchURL, map[string]any{"url": site.URL + "/"}))
+		// Truncated on a rune boundary
+		assert.Equal(t, "ééééééé\n[truncated 186 of 200 bytes]", ok(ToolFetchURL, map[string]any{"url": site.URL + "/long"}))
+
+		fails(ToolFetchURL, map[string]any{"url": "https://example.com/"}, "example.com is not an allowed host")
+		fails(ToolFetchURL, map[string]any{"url": site.URL + "/redirect"}, "not an allowed host")
+		fails(ToolFetchURL, map[string]any{"url": "file:///etc/passwd"}, "not an http or https URL")
+	})
+
+	t.Run("calculate", func(t *testing.T) {
+		assert.Equal(t, "20", ok(ToolCalculate, map[string]any{"expression": "(2 + 3) * sqrt(16)"}))
+		assert.Equal(t, "-4", ok(ToolCalculate, map[string]any{"expression": "-2^2"}))
+		assert.Equal(t, "0.5", ok(ToolCalculate, map[string]any{"expression": "max(1, 2) / 4"}))
+		fails(ToolCalculate, map[string]any{"expression": "1 / 0"}, "division by zero")
+		fails(ToolCalculate, map[string]any{"expression": "2 +"}, "unexpected end")
+		fails(ToolCalculate, map[string]any{"expression": "system(1)"}, "unknown name 'system'")
+	})
+
+	t.Run("json query", func(t *testing.T) {
+		data := map[string]any{"items": []any{
+			map[string]any{"name": "pen", "price": 2},
+			map[string]any{"name": "book", "price": 12},
+			map[string]any{"name": "cup", "price": 5},
+		}}
+		query := func(q string) string { return ok(ToolJSONQuery, map[string]any{"data": data, "query": q}) }
+
+		assert.Equal(t, "\"pen\"\n\"cup\"", query(`.items[] | select(.price < 10) | .name`))
+		assert.Equal(t, `["book","cup"]`, query(`[.items[1:] | .[].name]`))
+		assert.Equal(t, `3`, query(`.items | length`))
+		assert.Equal(t, `19`, query(`.items | map(.price) | add`))
+		assert.Equal(t, `"cup"`, query(`.items[-1].name`))
+		assert.Equal(t, `null`, query(`.missing.deeper`))
+		assert.Equal(t, `["items"]`, ok(ToolJSONQuery, map[string]any{"data": `{"items": []}`, "query": "keys"}))
+		assert.Equal(t, "{}", query(`env`))
+		fails(ToolJSONQuery, map[string]any{"data": data, "query": ".items.name"}, "expected an object but got: array")
+		fails(ToolJSONQuery, map[string]any{"data": data, "query": ".items[] |"}, "invalid query: unexpected EOF")
+		fails(ToolJSONQuery, map[string]any{"data": data, "query": "range(1e9)"}, "more than 10000 results")
+	})
+
+	_, err = ToolPack(PackOptions{Disabled: []string{"rm_rf"}})
+	require.ErrorContains(t, err, "unknown built-in tool 'rm_rf'")
+}
+
+func TestCurrentTime(t *testing.T) {
+	defs, err := ToolPack(PackOptions{})
+	require.NoError(t, err)
+	var tool ToolDef
+	for _, def := range defs {
+		if def.Name == ToolCurrentTime {
+			tool = def
+		}
+	}
+	require.NotNil(t, tool.Handler)
+
+	result := callTool(t, tool, map[string]any{"timezone": "Asia/Tokyo"})
+	require.False(t, result.IsError, resultText(t, result))
+	got := result.StructuredContent.(map[string]any)
+	assert.Equal(t, "Asia/Tokyo", got["timezone"])
+	now, err := time.Parse(time.RFC3339, got["time"].(string))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), now, time.Minute)
+	_, offset := now.Zone()
+	assert.Equal(t, 9*60*60, offset)
+	assert.Equal(t, now.Format(time.DateOnly), got["date"])
+	assert.Equal(t, now.Weekday().String(), got["weekday"])
+	assert.Equal(t, float64(now.Unix()), got["unix"])
+
+	result = callTool(t, tool, map[string]any{})
+	assert.Equal(t, "UTC", result.StructuredContent.(map[string]any)["timezone"])
+
+	result = callTool(t, tool, map[string]any{"timezone": "Mars/Olympus"})
+	assert.True(t, result.IsError)
+	assert.Contains(t, resultText(t, result), "unknown time zone 'Mars/Olympus'")
+}
+
+func TestLimitTool(t *testing.T) {
+	slow := limitTool(func(ctx context.Context, args map[string]any) (any, error) {
+		time.Sleep(time.Second)
+		return "done", nil
+	}, ToolLimits{Timeout: 10 * time.Millisecond})
+	_, err := slow(context.Background(), nil)
+	require.ErrorContains(t, err, "timed out after 10ms")
+}
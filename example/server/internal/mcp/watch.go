@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/watch"
+	"github.com/sirupsen/logrus"
+)
+
+// toolSet returns the static tools followed by the manifest tools
+func (s *Server) toolSet() []ToolDef {
+	s.toolsMu.Lock()
+	defer s.toolsMu.Unlock()
+	return append(append([]ToolDef{}, s.tools...), s.manifestTools...)
+}
+
+// applyTools replaces the served tools, which notifies connected clients with
+// notifications/tools/list_changed
+func (s *Server) applyTools() error {
+	tools, err := serverTools(s.toolSet())
+	if err != nil {
+		return err
+	}
+	s.mcpServer.SetTools(tools...)
+	return nil
+}
+
+// AddTools serves additional tools, replacing any with the same name
+func (s *Server) AddTools(defs ...ToolDef) error {
+	if _, err := serverTools(defs); err != nil {
+		return err
+	}
+
+	s.toolsMu.Lock()
+	for _, def := range defs {
+		s.tools = removeTool(s.tools, def.Name)
+		s.tools = append(s.tools, def)
+	}
+	s.toolsMu.Unlock()
+	return s.applyTools()
+}
+
+// RemoveTools stops serving the named tools
+func (s *Server) RemoveTools(names ...string) error {
+	s.toolsMu.Lock()
+	for _, name := range names {
+		s.tools = removeTool(s.tools, name)
+		s.manifestTools = removeTool(s.manifestTools, name)
+	}
+	s.toolsMu.Unlock()
+	return s.applyTools()
+}
+
+func removeTool(defs []ToolDef, name string) []ToolDef {
+	kept := defs[:0]
+	for _, def := range defs {
+		if def.Name != name {
+			kept = append(kept, def)
+		}
+	}
+	return kept
+}
+
+// ReloadTools re-reads the tools dir. Invalid manifests are reported and the
+// current tool set is kept.
+func (s *Server) ReloadTools() error {
+	if s.toolsDir == "" {
+		return nil
+	}
+
+	defs, err := LoadManifests(s.toolsDir)
+	if err != nil {
+		return err
+	}
+	if _, err := serverTools(defs); err != nil {
+		return err
+	}
+
+	s.toolsMu.Lock()
+	s.manifestTools = defs
+	s.toolsMu.Unlock()
+	return s.applyTools()
+}
+
+// WatchTools reloads the tools dir whenever a manifest in it changes until ctx is done
+func (s *Server) WatchTools(ctx context.Context, logger *logrus.Logger) error {
+	if s.toolsDir == "" {
+		return nil
+	}
+
+	isManifestPath := func(path string) bool {
+		return isManifest(filepath.Base(path))
+	}
+	reload := func() {
+		if err := s.ReloadTools(); err != nil {
+			logger.WithError(err).Error("Tool reload failed, keeping current tools")
+			return
+		}
+		var names []string
+		for _, def := range s.toolSet() {
+			names = append(names, def.Name)
+		}
+		logger.WithField("tools", names).Info("Tools reloaded")
+	}
+	onError := func(err error) {
+		logger.WithError(err).Warn("Tools dir watcher error")
+	}
+	return watch.Dir(ctx, s.toolsDir, isManifestPath, reload, onError)
+}
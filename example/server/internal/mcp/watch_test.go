@@ -0,0 +1,57 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	langchaingoTools "github.com/tmc/langchaingo/tools"
+)
+
+func toolNames(tools []langchaingoTools.Tool) []string {
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Name())
+	}
+	return names
+}
+
+func TestToolListChanged(t *testing.T) {
+	dir := t.TempDir()
+	server := startServer(t, freePort(t), WithToolsDir(dir))
+	defer stopServer(server)
+
+	adapter, err := NewAdapter(server.Endpoint())
+	require.NoError(t, err)
+	defer adapter.Close()
+
+	tools, err := adapter.Tools()
+	require.NoError(t, err)
+	require.Equal(t, []string{"provide_language_options"}, toolNames(tools))
+
+	changed := make(chan []string, 4)
+	adapter.OnToolsChanged(func(tools []langchaingoTools.Tool) {
+		changed <- toolNames(tools)
+	})
+
+	manifest := "name: echo\nhandler:\n  type: shell\n  command: [cat]\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "echo.yaml"), []byte(manifest), 0o644))
+	require.NoError(t, server.ReloadTools())
+
+	select {
+	case names := <-changed:
+		require.Equal(t, []string{"echo", "provide_language_options"}, names)
+	case <-time.After(5 * time.Second):
+		t.Fatal("adapter did not refresh its tools")
+	}
+
+	require.NoError(t, server.RemoveTools("echo"))
+	select {
+	case names := <-changed:
+		require.Equal(t, []string{"provide_language_options"}, names)
+	case <-time.After(5 * time.Second):
+		t.Fatal("adapter did not refresh its tools")
+	}
+}
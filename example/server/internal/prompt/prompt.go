@@ -0,0 +1,195 @@
+// Package prompt renders the system and user prompts of agent runs with
+// text/template. Templates are read from the .md files of a directory, named
+// after the file, and get the run's AG-UI context entries, shared state and
+// forwardedProps as data. The directory is reloaded when it changes.
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"text/template"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/watch"
+	"github.com/sirupsen/logrus"
+)
+
+// Templates used by agents that do not name their own, when the directory has them
+const (
+	DefaultSystemTemplate = "system"
+	DefaultUserTemplate   = "user"
+)
+
+// Data is what prompt templates are rendered with
+type Data struct {
+	// Input is the content of the last user message
+	Input string
+	// Agent is the name of the agent profile, empty for the default agent
+	Agent string
+	// Context holds the AG-UI context entries sent with the run
+	Context []ContextEntry
+	// State is the run's shared state
+	State any
+	// Props are the run's forwardedProps
+	Props map[string]any
+}
+
+// ContextEntry is an AG-UI context entry
+type ContextEntry struct {
+	Description string `json:"description"`
+	Value       string `json:"value"`
+}
+
+// ContextValue returns the value of the context entry with description, as
+// {{.ContextValue "timezone"}}
+func (d Data) ContextValue(description string) string {
+	for _, entry := range d.Context {
+		if entry.Description == description {
+			return entry.Value
+		}
+	}
+	return ""
+}
+
+// funcs are available to every template
+var funcs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// Render renders text as the template name. Missing map keys are errors, so a
+// template asking for an absent forwardedProp fails instead of rendering "<no value>".
+func Render(name, text string, data Data) (string, error) {
+	tmpl, err := newTemplate(name).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parse prompt template: %w", err)
+	}
+	return execute(tmpl, data)
+}
+
+func newTemplate(name string) *template.Template {
+	return template.New(name).Option("missingkey=error").Funcs(funcs)
+}
+
+func execute(tmpl *template.Template, data Data) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render prompt template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// Engine serves the templates of a directory. Templates can include each other
+// with {{template "name" .}}.
+type Engine struct {
+	dir       string
+	templates atomic.Pointer[template.Template]
+}
+
+// Load reads the templates in dir. An empty dir returns nil, which every
+// method accepts as an engine without templates.
+func Load(dir string) (*Engine, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	e := &Engine{dir: dir}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Reload re-reads the directory. Invalid templates are reported and the
+// current ones are kept.
+func (e *Engine) Reload() error {
+	if e == nil {
+		return nil
+	}
+
+	entries, err := os.ReadDir(e.dir)
+	if err != nil {
+		return fmt.Errorf("read prompt templates dir: %w", err)
+	}
+
+	root := newTemplate("")
+	for _, entry := range entries {
+		if entry.IsDir() || !isTemplate(entry.Name()) {
+			continue
+		}
+		text, err := os.ReadFile(filepath.Join(e.dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("read prompt template: %w", err)
+		}
+		if _, err := root.New(templateName(entry.Name())).Parse(string(text)); err != nil {
+			return fmt.Errorf("parse prompt template %s: %w", entry.Name(), err)
+		}
+	}
+	e.templates.Store(root)
+	return nil
+}
+
+// Names returns the names of the loaded templates
+func (e *Engine) Names() []string {
+	if e == nil {
+		return nil
+	}
+	var names []string
+	for _, tmpl := range e.templates.Load().Templates() {
+		if tmpl.Name() != "" {
+			names = append(names, tmpl.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether the template name is loaded
+func (e *Engine) Has(name string) bool {
+	return e != nil && e.templates.Load().Lookup(name) != nil
+}
+
+// Render renders the template name with data
+func (e *Engine) Render(name string, data Data) (string, error) {
+	if !e.Has(name) {
+		return "", fmt.Errorf("prompt template %s not found", name)
+	}
+	return execute(e.templates.Load().Lookup(name), data)
+}
+
+// Watch reloads the directory whenever a template in it changes until ctx is done
+func (e *Engine) Watch(ctx context.Context, logger *logrus.Logger) error {
+	if e == nil {
+		return nil
+	}
+
+	isTemplatePath := func(path string) bool {
+		return isTemplate(filepath.Base(path))
+	}
+	reload := func() {
+		if err := e.Reload(); err != nil {
+			logger.WithError(err).Error("Prompt template reload failed, keeping current templates")
+			return
+		}
+		logger.WithField("templates", e.Names()).Info("Prompt templates reloaded")
+	}
+	onError := func(err error) {
+		logger.WithError(err).Warn("Prompt templates dir watcher error")
+	}
+	return watch.Dir(ctx, e.dir, isTemplatePath, reload, onError)
+}
+
+func isTemplate(file string) bool {
+	return strings.HasSuffix(file, ".md") && !strings.HasPrefix(file, ".")
+}
+
+func templateName(file string) string {
+	return strings.TrimSuffix(file, ".md")
+}
@@ -0,0 +1,62 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, text string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644))
+	}
+	write("greeting.md", `Answer in {{.ContextValue "language"}}.`)
+	write("system.md", `{{template "greeting" .}} The user has {{len .State.todos}} todos, theme {{.Props.theme}}.`)
+	write("notes.txt", "{{ not a template")
+
+	engine, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"greeting", "system"}, engine.Names())
+
+	data := Data{
+		Context: []ContextEntry{{Description: "language", Value: "French"}},
+		State:   map[string]any{"todos": []any{"a", "b"}},
+		Props:   map[string]any{"theme": "dark"},
+	}
+	text, err := engine.Render(DefaultSystemTemplate, data)
+	require.NoError(t, err)
+	assert.Equal(t, "Answer in French. The user has 2 todos, theme dark.", text)
+
+	// Missing props are errors rather than "<no value>"
+	_, err = engine.Render(DefaultSystemTemplate, Data{State: data.State, Props: map[string]any{}})
+	require.ErrorContains(t, err, `map has no entry for key "theme"`)
+
+	_, err = engine.Render(DefaultUserTemplate, data)
+	require.ErrorContains(t, err, "prompt template user not found")
+
+	// Broken templates keep the current ones
+	write("user.md", "{{.Input")
+	require.ErrorContains(t, engine.Reload(), "parse prompt template user.md")
+	assert.False(t, engine.Has(DefaultUserTemplate))
+
+	write("user.md", "Question: {{.Input}}")
+	require.NoError(t, engine.Reload())
+	text, err = engine.Render(DefaultUserTemplate, Data{Input: "Which language?"})
+	require.NoError(t, err)
+	assert.Equal(t, "Question: Which language?", text)
+}
+
+func TestNilEngine(t *testing.T) {
+	engine, err := Load("")
+	require.NoError(t, err)
+	assert.False(t, engine.Has(DefaultSystemTemplate))
+	require.NoError(t, engine.Reload())
+
+	text, err := Render("inline", `{{json .State}}`, Data{State: map[string]int{"count": 1}})
+	require.NoError(t, err)
+	assert.Equal(t, `{"count":1}`, text)
+}
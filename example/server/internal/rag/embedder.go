@@ -0,0 +1,108 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/config"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms/ollama"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+// DefaultOllamaModel embeds with Ollama when no model is configured
+const DefaultOllamaModel = "nomic-embed-text"
+
+// NewEmbedder returns the embedder of a config.Embedding* provider, model
+// may be empty to use the provider's default
+func NewEmbedder(provider, model string) (embeddings.Embedder, error) {
+	switch provider {
+	case config.EmbeddingHash, "":
+		return HashEmbedder{}, nil
+	case config.EmbeddingOpenAI:
+		var opts []openai.Option
+		if model != "" {
+			opts = append(opts, openai.WithEmbeddingModel(model))
+		}
+		client, err := openai.New(opts...)
+		if err != nil {
+			return nil, fmt.Errorf("create OpenAI client: %w", err)
+		}
+		return embeddings.NewEmbedder(client)
+	case config.EmbeddingOllama:
+		if model == "" {
+			model = DefaultOllamaModel
+		}
+		client, err := ollama.New(ollama.WithModel(model))
+		if err != nil {
+			return nil, fmt.Errorf("create Ollama client: %w", err)
+		}
+		return embeddings.NewEmbedder(client)
+	default:
+		return nil, fmt.Errorf("unknown embedding provider '%s'", provider)
+	}
+}
+
+// hashDimensions is the length of HashEmbedder vectors
+const hashDimensions = 512
+
+// HashEmbedder embeds texts by hashing their words and word pairs into a
+// fixed number of buckets. It runs offline and always returns the same vector
+// for the same text, texts only match when they share words.
+type HashEmbedder struct{}
+
+func (e HashEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e HashEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return e.embed(text), nil
+}
+
+func (HashEmbedder) embed(text string) []float32 {
+	vector := make([]float32, hashDimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	add := func(term string, weight float32) {
+		h := fnv.New64a()
+		h.Write([]byte(term))
+		sum := h.Sum64()
+		// The top bit signs the term so unrelated terms sharing a bucket cancel out
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		vector[sum%hashDimensions] += weight
+	}
+	for i, word := range words {
+		add(word, 1)
+		if i > 0 {
+			add(words[i-1]+" "+word, 0.5)
+		}
+	}
+	return normalize(vector)
+}
+
+// normalize scales vector to unit length, the zero vector is returned as is
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vector
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
@@ -0,0 +1,136 @@
+// Package rag indexes a directory of Markdown and text files into an
+// in-memory vector store that agents search for documents to cite.
+package rag
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/schema"
+)
+
+// Metadata keys of indexed documents
+const (
+	// MetaSource is the file's path relative to the indexed directory, with slashes
+	MetaSource = "source"
+	// MetaTitle is the file's first level 1 heading, or its name
+	MetaTitle = "title"
+	// MetaSection is the heading the chunk is under, empty before the first one
+	MetaSection = "section"
+)
+
+// maxChunkSize bounds the characters of a chunk, longer sections are split
+// between paragraphs
+const maxChunkSize = 1500
+
+// indexedExtensions are the file extensions Index reads
+var indexedExtensions = map[string]bool{".md": true, ".markdown": true, ".txt": true}
+
+// Index reads the Markdown and text files under dir into a new Store, one
+// document per chunk. It returns nil when dir is empty.
+func Index(ctx context.Context, dir string, embedder embeddings.Embedder) (*Store, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	var docs []schema.Document
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), ".") && path != dir {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !indexedExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, chunk(filepath.ToSlash(rel), string(data))...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("index %s: %w", dir, err)
+	}
+
+	store := NewStore(embedder)
+	if len(docs) == 0 {
+		return store, nil
+	}
+	if _, err := store.AddDocuments(ctx, docs); err != nil {
+		return nil, fmt.Errorf("index %s: %w", dir, err)
+	}
+	return store, nil
+}
+
+// chunk splits a file into documents at its headings, then between
+// paragraphs so no chunk exceeds maxChunkSize unless a paragraph does
+func chunk(source, text string) []schema.Document {
+	title := strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	for _, line := range strings.Split(text, "\n") {
+		if heading, ok := strings.CutPrefix(line, "# "); ok {
+			title = strings.TrimSpace(heading)
+			break
+		}
+	}
+
+	var docs []schema.Document
+	var section string
+	var paragraphs []string
+	flush := func() {
+		var current strings.Builder
+		emit := func() {
+			if content := strings.TrimSpace(current.String()); content != "" {
+				docs = append(docs, schema.Document{
+					PageContent: content,
+					Metadata:    map[string]any{MetaSource: source, MetaTitle: title, MetaSection: section},
+				})
+			}
+			current.Reset()
+		}
+		for _, paragraph := range paragraphs {
+			if current.Len() > 0 && current.Len()+len(paragraph) > maxChunkSize {
+				emit()
+			}
+			current.WriteString(paragraph + "\n\n")
+		}
+		emit()
+		paragraphs = nil
+	}
+
+	for _, block := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		block = strings.Trim(block, "\n")
+		if heading, ok := markdownHeading(block); ok {
+			flush()
+			section = heading
+		}
+		paragraphs = append(paragraphs, block)
+	}
+	flush()
+	return docs
+}
+
+// markdownHeading returns the text of the heading block starts with
+func markdownHeading(block string) (string, bool) {
+	line, _, _ := strings.Cut(block, "\n")
+	level := len(line) - len(strings.TrimLeft(line, "#"))
+	if level == 0 || level > 6 || !strings.HasPrefix(line[level:], " ") {
+		return "", false
+	}
+	return strings.TrimSpace(line[level:]), true
+}
@@ -0,0 +1,42 @@
+package rag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+func TestIndex(t *testing.T) {
+	ctx := context.Background()
+	store, err := Index(ctx, "testdata/docs", HashEmbedder{})
+	require.NoError(t, err)
+	// The languages heading and its two sections, and the setup file
+	require.Equal(t, 4, store.Len())
+
+	docs, err := store.SimilaritySearch(ctx, "static binary for command line tools", 2)
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "languages.md", docs[0].Metadata[MetaSource])
+	assert.Equal(t, "Choosing a language", docs[0].Metadata[MetaTitle])
+	assert.Equal(t, "Go", docs[0].Metadata[MetaSection])
+	assert.Contains(t, docs[0].PageContent, "single static binary")
+	assert.Greater(t, docs[0].Score, docs[1].Score)
+
+	docs, err = store.SimilaritySearch(ctx, "install toolchain", 4, vectorstores.WithScoreThreshold(0.2))
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "guides/setup.txt", docs[0].Metadata[MetaSource])
+	assert.Equal(t, "setup", docs[0].Metadata[MetaTitle])
+
+	// The hashing embedder is deterministic
+	a, _ := HashEmbedder{}.EmbedQuery(ctx, "Go servers")
+	b, _ := HashEmbedder{}.EmbedQuery(ctx, "go, servers!")
+	assert.Equal(t, a, b)
+
+	store, err = Index(ctx, "", HashEmbedder{})
+	require.NoError(t, err)
+	assert.Zero(t, store.Len())
+}
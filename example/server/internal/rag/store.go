@@ -0,0 +1,122 @@
+package rag
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// Store is an in-memory vector store searched by cosine similarity. A nil
+// Store holds no documents.
+type Store struct {
+	embedder embeddings.Embedder
+
+	mu      sync.RWMutex
+	docs    []schema.Document
+	vectors [][]float32
+}
+
+var _ vectorstores.VectorStore = (*Store)(nil)
+
+func NewStore(embedder embeddings.Embedder) *Store {
+	return &Store{embedder: embedder}
+}
+
+// Len returns the number of documents in the store
+func (s *Store) Len() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.docs)
+}
+
+// AddDocuments embeds docs and adds them to the store. The IDs returned are
+// the documents' positions in the store.
+func (s *Store) AddDocuments(ctx context.Context, docs []schema.Document, options ...vectorstores.Option) ([]string, error) {
+	embedder := s.options(options).Embedder
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.PageContent
+	}
+	vectors, err := embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("embed documents: %w", err)
+	}
+	if len(vectors) != len(docs) {
+		return nil, fmt.Errorf("embed documents: got %d vectors for %d documents", len(vectors), len(docs))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = fmt.Sprint(len(s.docs))
+		s.docs = append(s.docs, doc)
+		s.vectors = append(s.vectors, vectors[i])
+	}
+	return ids, nil
+}
+
+// SimilaritySearch returns the numDocuments documents most similar to query,
+// with their cosine similarity as Score. Documents scoring below the
+// vectorstores.WithScoreThreshold option are left out.
+func (s *Store) SimilaritySearch(ctx context.Context, query string, numDocuments int, options ...vectorstores.Option) ([]schema.Document, error) {
+	if s.Len() == 0 || numDocuments <= 0 {
+		return nil, nil
+	}
+	opts := s.options(options)
+	vector, err := opts.Embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+
+	s.mu.RLock()
+	results := make([]schema.Document, 0, len(s.docs))
+	for i, doc := range s.docs {
+		doc.Score = cosine(vector, s.vectors[i])
+		if doc.Score >= opts.ScoreThreshold {
+			results = append(results, doc)
+		}
+	}
+	s.mu.RUnlock()
+
+	// Stable, so equally similar documents keep their index order
+	slices.SortStableFunc(results, func(a, b schema.Document) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	return results[:min(numDocuments, len(results))], nil
+}
+
+func (s *Store) options(options []vectorstores.Option) vectorstores.Options {
+	opts := vectorstores.Options{Embedder: s.embedder}
+	for _, option := range options {
+		option(&opts)
+	}
+	return opts
+}
+
+// cosine returns the cosine similarity of a and b, 0 when either is zero
+func cosine(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(normA*normB))
+}
@@ -0,0 +1,239 @@
+package routes
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/agentic"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/config"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/mcp"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/prompt"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/runs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postAgentic sends body to path and returns the events of the response
+func postAgentic(t *testing.T, cfg *config.Config, templates *prompt.Engine, path, body string) []map[string]any {
+	t.Helper()
+
+	app := fiber.New()
+	registry := mcp.NewRegistry()
+	defer registry.Close()
+	handler := AgenticHandler(cfg, Deps{
+		Tracker:      runs.NewTracker(),
+		Adapters:     registry,
+		Interactions: agentic.NewInteractions(),
+		Templates:    templates,
+		History:      agentic.NewHistoryCache(),
+		Files:        agentic.NewFiles(),
+	})
+	app.Post("/agentic", handler)
+	app.Post("/agents/:name", handler)
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var events []map[string]any
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event map[string]any
+		require.NoError(t, json.Unmarshal([]byte(data), &event))
+		events = append(events, event)
+	}
+	require.NoError(t, scanner.Err())
+	return events
+}
+
+func TestAgenticReplay(t *testing.T) {
+	cfg := config.New()
+	cfg.EmbeddedMCP = false
+	cfg.Cassette = "testdata/languages.json"
+	cfg.CassetteMode = "replay"
+
+	events := postAgentic(t, cfg, nil, "/agentic", `{"messages": [{"role": "user", "content": "Which language should I learn?"}]}`)
+
+	var types []string
+	for _, event := range events {
+		types = append(types, event["type"].(string))
+	}
+	assert.Equal(t, []string{
+		"RUN_STARTED",
+		"STEP_STARTED",
+		"TOOL_CALL_START",
+		"TOOL_CALL_ARGS",
+		"TOOL_CALL_END",
+		"TOOL_CALL_RESULT",
+		"TEXT_MESSAGE_START",
+		"TEXT_MESSAGE_CONTENT",
+		"TEXT_MESSAGE_END",
+		"STEP_FINISHED",
+		"RUN_FINISHED",
+	}, types)
+
+	assert.Equal(t, "provide_language_options", events[2]["toolCallName"])
+	assert.JSONEq(t, `{"option1":"Go","option2":"Rust","option3":"Zig","option4":"Python"}`, events[3]["delta"].(string))
+	assert.Contains(t, events[5]["content"], `"structuredContent":{"option1":"Go"`)
+	assert.Equal(t, " Pick one of Go, Rust, Zig or Python.", events[7]["delta"])
+}
+
+func TestAgentProfiles(t *testing.T) {
+	cfg := config.New()
+	cfg.EmbeddedMCP = false
+	cfg.Cassette = "testdata/languages.json"
+	cfg.CassetteMode = "replay"
+	cfg.Agents = map[string]config.AgentProfile{
+		"languages": {SystemPrompt: "You help people pick a programming language.", Tools: []string{"provide_language_options"}},
+	}
+
+	events := postAgentic(t, cfg, nil, "/agents/languages", `{"messages": [{"role": "user", "content": "Which language should I learn?"}]}`)
+	require.Len(t, events, 11)
+	assert.Equal(t, "provide_language_options", events[2]["toolCallName"])
+	assert.Equal(t, "RUN_FINISHED", events[10]["type"])
+
+	app := fiber.New()
+	app.Post("/agents/:name", AgenticHandler(cfg, Deps{Tracker: runs.NewTracker(), Interactions: agentic.NewInteractions()}))
+	req := httptest.NewRequest(http.MethodPost, "/agents/unknown", strings.NewReader(`{"messages": []}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestSubAgents(t *testing.T) {
+	cfg := config.New()
+	cfg.EmbeddedMCP = false
+	cfg.Cassette = "testdata/supervisor.json"
+	cfg.CassetteMode = "replay"
+	cfg.Agents = map[string]config.AgentProfile{
+		"tutor":     {SystemPrompt: "You delegate to the agents you know.", Tools: []string{"none"}, SubAgents: []string{"languages"}},
+		"languages": {Description: "Offers programming languages.", Tools: []string{"provide_language_options"}},
+	}
+
+	events := postAgentic(t, cfg, nil, "/agents/tutor", `{"messages": [{"role": "user", "content": "Which language should I learn?"}]}`)
+	var types []string
+	for _, event := range events {
+		types = append(types, event["type"].(string))
+	}
+	require.Equal(t, []string{
+		"RUN_STARTED",
+		"STEP_STARTED",
+		"TOOL_CALL_START",
+		"TOOL_CALL_ARGS",
+		"CUSTOM",
+		"STEP_STARTED",
+		"CUSTOM",
+		"TOOL_CALL_START",
+		"TOOL_CALL_ARGS",
+		"TOOL_CALL_END",
+		"TOOL_CALL_RESULT",
+		"STEP_FINISHED",
+		"TOOL_CALL_END",
+		"TOOL_CALL_RESULT",
+		"TEXT_MESSAGE_START",
+		"TEXT_MESSAGE_CONTENT",
+		"TEXT_MESSAGE_END",
+		"STEP_FINISHED",
+		"RUN_FINISHED",
+	}, types)
+
+	// The sub-agent's step and tool calls belong to the call delegating to it
+	delegation := events[2]["toolCallId"]
+	assert.Equal(t, "languages", events[2]["toolCallName"])
+	assert.Equal(t, agentic.SubAgentEvent, events[4]["name"])
+	assert.Equal(t, map[string]any{"agent": "languages", "parentToolCallId": delegation, "stepName": events[5]["stepName"]}, events[4]["value"])
+	assert.Equal(t, agentic.SubAgentEvent, events[6]["name"])
+	assert.Equal(t, map[string]any{
+		"agent":            "languages",
+		"parentToolCallId": delegation,
+		"toolCallId":       events[7]["toolCallId"],
+		"toolName":         "provide_language_options",
+	}, events[6]["value"])
+	assert.Equal(t, "provide_language_options", events[7]["toolCallName"])
+	assert.Nil(t, events[7]["parentMessageId"])
+	assert.Equal(t, events[5]["stepName"], events[11]["stepName"])
+	assert.Equal(t, delegation, events[13]["toolCallId"])
+	assert.Equal(t, "I offered Go, Rust, Zig and Python.", events[13]["content"])
+	assert.Equal(t, " Pick one of Go, Rust, Zig or Python.", events[15]["delta"])
+}
+
+func TestStructuredOutput(t *testing.T) {
+	cfg := config.New()
+	cfg.EmbeddedMCP = false
+	cfg.Cassette = "testdata/structured.json"
+	cfg.CassetteMode = "replay"
+	cfg.Agents = map[string]config.AgentProfile{
+		"advisor": {Tools: []string{"none"}, OutputSchema: map[string]any{
+			"type":     "object",
+			"required": []any{"language", "reason"},
+			"properties": map[string]any{
+				"language": map[string]any{"type": "string"},
+				"reason":   map[string]any{"type": "string"},
+			},
+		}},
+	}
+
+	// The first structured answer misses the reason and is asked for again
+	events := postAgentic(t, cfg, nil, "/agents/advisor", `{"messages": [{"role": "user", "content": "Which language should I learn?"}]}`)
+	require.Len(t, events, 8)
+	assert.Equal(t, " Learn Go, it compiles fast.", events[3]["delta"])
+	assert.Equal(t, "CUSTOM", events[6]["type"])
+	assert.Equal(t, agentic.StructuredOutputEvent, events[6]["name"])
+	assert.Equal(t, map[string]any{"language": "Go", "reason": "It compiles fast."}, events[6]["value"])
+	assert.Equal(t, "RUN_FINISHED", events[7]["type"])
+
+	// An answer that still misses the reason after the retries ends the run
+	// with an error, after the text answer
+	cfg.Cassette = "testdata/structured_invalid.json"
+	advisor := cfg.Agents["advisor"]
+	advisor.OutputRetries = 1
+	cfg.Agents["advisor"] = advisor
+	events = postAgentic(t, cfg, nil, "/agents/advisor", `{"messages": [{"role": "user", "content": "Which language should I learn?"}]}`)
+	require.Len(t, events, 7)
+	assert.Equal(t, " Learn Go, it compiles fast.", events[3]["delta"])
+	assert.Equal(t, "RUN_ERROR", events[6]["type"])
+	assert.Equal(t, "output_schema", events[6]["code"])
+	assert.Contains(t, events[6]["message"], "after 2 attempts")
+	assert.Contains(t, events[6]["message"], "reason")
+}
+
+func TestPromptTemplates(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "user.md"), []byte(`{{.Input}} I know {{.Props.known}}.`), 0o644))
+	templates, err := prompt.Load(dir)
+	require.NoError(t, err)
+
+	cfg := config.New()
+	cfg.EmbeddedMCP = false
+	cfg.Cassette = "testdata/languages.json"
+	cfg.CassetteMode = "replay"
+
+	events := postAgentic(t, cfg, templates, "/agentic",
+		`{"messages": [{"role": "user", "content": "Which language should I learn?"}], "forwarded_props": {"known": "Go"}}`)
+	require.Len(t, events, 11)
+
+	// A template asking for a missing prop ends the run with a RUN_ERROR
+	events = postAgentic(t, cfg, templates, "/agentic", `{"messages": [{"role": "user", "content": "Which language should I learn?"}]}`)
+	require.Len(t, events, 2)
+	assert.Equal(t, "RUN_STARTED", events[0]["type"])
+	assert.Equal(t, "RUN_ERROR", events[1]["type"])
+	assert.Equal(t, "prompt_template", events[1]["code"])
+	assert.Contains(t, events[1]["message"], `map has no entry for key "known"`)
+}
@@ -0,0 +1,155 @@
+package routes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/agentic"
+)
+
+// Content is the content of a message. It is sent as a string, or for user
+// messages as a list of parts:
+//
+//	[{"type": "text", "text": "What is in this picture?"},
+//	 {"type": "binary", "mimeType": "image/png", "data": "<base64>", "filename": "cat.png", "id": "cat"}]
+//
+// A binary part with only an id refers to a file sent with that id earlier in
+// the thread, see agentic.Files.
+type Content []ContentPart
+
+// ContentPart is a text part, or a binary part holding a base64 encoded file,
+// the URL of one or the id of one sent before
+type ContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	Data     string `json:"data,omitempty"`
+	URL      string `json:"url,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// Content part types
+const (
+	PartText   = "text"
+	PartBinary = "binary"
+)
+
+func (c *Content) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = nil
+		if text != "" {
+			*c = Content{{Type: PartText, Text: text}}
+		}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]ContentPart)(c))
+}
+
+func (c Content) MarshalJSON() ([]byte, error) {
+	if len(c) == 0 {
+		return json.Marshal("")
+	}
+	if len(c) == 1 && c[0].Type == PartText {
+		return json.Marshal(c[0].Text)
+	}
+	return json.Marshal([]ContentPart(c))
+}
+
+// JSONSchema describes both encodings of Content
+func (Content) JSONSchema() map[string]any {
+	str := map[string]any{"type": "string"}
+	return map[string]any{"anyOf": []any{str, map[string]any{
+		"type": "array",
+		"items": map[string]any{
+			"type":     "object",
+			"required": []string{"type"},
+			"properties": map[string]any{
+				"type":     map[string]any{"enum": []string{PartText, PartBinary}},
+				"text":     str,
+				"mimeType": str,
+				"data":     str,
+				"url":      str,
+				"id":       str,
+				"filename": str,
+			},
+		},
+	}}}
+}
+
+// Text returns the text parts, one per line
+func (c Content) Text() string {
+	var texts []string
+	for _, part := range c {
+		if part.Type == PartText {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Attachments returns the binary parts carrying a file, which must have been
+// validated. Parts that only refer to a file by id are left out.
+func (c Content) Attachments() []agentic.Attachment {
+	var attachments []agentic.Attachment
+	for _, part := range c {
+		if part.Type != PartBinary || part.isReference() {
+			continue
+		}
+		data, _ := base64.StdEncoding.DecodeString(part.Data)
+		attachments = append(attachments, agentic.Attachment{
+			ID:       part.ID,
+			Name:     part.Filename,
+			MimeType: part.MimeType,
+			Data:     data,
+			URL:      part.URL,
+		})
+	}
+	return attachments
+}
+
+// hasFiles reports whether c has binary parts
+func (c Content) hasFiles() bool {
+	for _, part := range c {
+		if part.Type == PartBinary {
+			return true
+		}
+	}
+	return false
+}
+
+// isReference reports whether the binary part names a file sent before instead of carrying one
+func (p ContentPart) isReference() bool {
+	return p.Data == "" && p.URL == "" && p.ID != ""
+}
+
+// validate checks the binary parts of a message with role, returning a message
+// for each offending part by its index
+func (c Content) validate(role string) map[int]string {
+	problems := map[int]string{}
+	for i, part := range c {
+		if part.Type != PartBinary {
+			continue
+		}
+		switch {
+		case role != "user":
+			problems[i] = "only user messages can carry attachments"
+		case part.isReference():
+			// Resolved against the thread's files, see AgenticInput.Attachments
+		case part.Data == "" && part.URL == "":
+			problems[i] = "binary parts need data, a url or the id of a file sent before"
+		case part.MimeType == "":
+			problems[i] = "binary parts need a mimeType"
+		default:
+			if err := agentic.CheckAttachment(part.MimeType, part.Data == ""); err != nil {
+				problems[i] = err.Error()
+			} else if _, err := base64.StdEncoding.DecodeString(part.Data); err != nil {
+				problems[i] = fmt.Sprintf("data is not valid base64: %s", err)
+			}
+		}
+	}
+	return problems
+}
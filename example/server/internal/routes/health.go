@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/buildinfo"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/health"
+)
+
+// HealthzHandler reports that the process is alive
+func HealthzHandler() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"status": "ok",
+		})
+	}
+}
+
+// ReadyzHandler reports whether the server and its dependencies can serve agent runs
+func ReadyzHandler(checker *health.Checker) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		report := checker.Ready(c.RequestCtx())
+		status := fiber.StatusOK
+		if !report.Ready {
+			status = fiber.StatusServiceUnavailable
+		}
+		return c.Status(status).JSON(report)
+	}
+}
+
+// VersionHandler reports the build information of the running binary
+func VersionHandler() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		return c.JSON(buildinfo.Get())
+	}
+}
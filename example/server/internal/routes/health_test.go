@@ -0,0 +1,61 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthRoutes(t *testing.T) {
+	checker := health.NewChecker()
+	var failing error
+	checker.Register("mcp_tools", func(ctx context.Context) error { return failing })
+	checker.Register("llm_provider", func(ctx context.Context) error { return nil })
+
+	app := fiber.New()
+	app.Get("/healthz", HealthzHandler())
+	app.Get("/readyz", ReadyzHandler(checker))
+	get := func(path string) (int, map[string]any) {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp.StatusCode, body
+	}
+
+	status, body := get("/healthz")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ok", body["status"])
+
+	status, body = get("/readyz")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ready", body["status"])
+
+	// The failure body lists the failed checks with their errors
+	failing = errors.New("list tools from http://127.0.0.1:3217/mcp: connection refused")
+	status, body = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "not_ready", body["status"])
+	checks := body["checks"].(map[string]any)
+	assert.Equal(t, "failing", checks["mcp_tools"].(map[string]any)["status"])
+	assert.Equal(t, failing.Error(), checks["mcp_tools"].(map[string]any)["error"])
+	assert.Equal(t, "ok", checks["llm_provider"].(map[string]any)["status"])
+
+	// Readiness fails once shutdown starts, liveness does not
+	failing = nil
+	checker.SetShuttingDown()
+	status, body = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "shutting_down", body["status"])
+	status, _ = get("/healthz")
+	assert.Equal(t, http.StatusOK, status)
+}
@@ -0,0 +1,273 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/agentic"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/schema"
+)
+
+// AgenticInput is the AG-UI RunAgentInput. Keys may be camelCase, as in the
+// spec, or snake_case, see inputAliases.
+type AgenticInput struct {
+	ThreadID       string         `json:"threadId"`
+	RunID          string         `json:"runId"`
+	ParentRunID    string         `json:"parentRunId,omitempty"`
+	State          any            `json:"state"`
+	Messages       []Message      `json:"messages" mcp:"required"`
+	Tools          []Tool         `json:"tools"`
+	Context        []ContextEntry `json:"context"`
+	ForwardedProps map[string]any `json:"forwardedProps"`
+}
+
+// Message is a message of the conversation
+type Message struct {
+	ID      string  `json:"id"`
+	Role    string  `json:"role" mcp:"required" enum:"developer,system,user,assistant,tool"`
+	Content Content `json:"content"`
+	Name    string  `json:"name,omitempty"`
+	// ToolCalls are the calls requested by an assistant message
+	ToolCalls []ToolCall `json:"toolCalls,omitempty"`
+	// ToolCallID is the call a tool message answers
+	ToolCallID string `json:"toolCallId,omitempty"`
+}
+
+// ToolCall is a tool call requested by the assistant
+type ToolCall struct {
+	ID       string       `json:"id" mcp:"required"`
+	Type     string       `json:"type" enum:"function"`
+	Function FunctionCall `json:"function" mcp:"required"`
+}
+
+// FunctionCall names the called tool and holds its JSON encoded arguments
+type FunctionCall struct {
+	Name      string `json:"name" mcp:"required"`
+	Arguments string `json:"arguments"`
+}
+
+// Tool is a frontend tool the agent may call
+type Tool struct {
+	Name        string `json:"name" mcp:"required"`
+	Description string `json:"description"`
+	// Parameters is the JSON Schema of the tool's arguments
+	Parameters map[string]any `json:"parameters"`
+}
+
+// ContextEntry is context the frontend supplies, such as the current page
+type ContextEntry struct {
+	Description string `json:"description" mcp:"required"`
+	Value       string `json:"value" mcp:"required"`
+}
+
+// LastMessage returns the last message of the conversation, or the zero Message
+func (in *AgenticInput) LastMessage() Message {
+	if len(in.Messages) == 0 {
+		return Message{}
+	}
+	return in.Messages[len(in.Messages)-1]
+}
+
+// History returns the conversation before the last message as text. Tool
+// calls and attachments are written out, system and developer messages are
+// left to the system prompt. The attachments themselves are sent with the
+// run, see Attachments.
+func (in *AgenticInput) History() []agentic.HistoryMessage {
+	if len(in.Messages) < 2 {
+		return nil
+	}
+	var history []agentic.HistoryMessage
+	for _, message := range in.Messages[:len(in.Messages)-1] {
+		if message.Role == "system" || message.Role == "developer" {
+			continue
+		}
+		parts := []string{message.Content.Text()}
+		for _, part := range message.Content {
+			if part.Type == PartBinary {
+				parts = append(parts, fmt.Sprintf("(attached %s)", part.label()))
+			}
+		}
+		for _, call := range message.ToolCalls {
+			parts = append(parts, fmt.Sprintf("(called %s with %s)", call.Function.Name, call.Function.Arguments))
+		}
+		if text := strings.TrimSpace(strings.Join(parts, "\n")); text != "" {
+			history = append(history, agentic.HistoryMessage{Role: message.Role, Content: text})
+		}
+	}
+	return history
+}
+
+// Attachments returns the files of the thread: those kept in files by earlier
+// runs and those of the input's messages, which are added to files. Parts
+// that refer to a file by id are resolved against the input and files, ids
+// that match neither are returned as a *schema.ValidationError.
+func (in *AgenticInput) Attachments(files *agentic.Files) ([]agentic.Attachment, error) {
+	sent := map[string]agentic.Attachment{}
+	var attachments []agentic.Attachment
+	verr := &schema.ValidationError{}
+	for i, message := range in.Messages {
+		for j, part := range message.Content {
+			if part.Type != PartBinary || !part.isReference() {
+				continue
+			}
+			if _, ok := sent[part.ID]; ok {
+				continue
+			}
+			if _, ok := files.Get(in.ThreadID, part.ID); !ok {
+				verr.Fields = append(verr.Fields, schema.FieldError{
+					Field:   fmt.Sprintf("messages/%d/content/%d", i, j),
+					Message: fmt.Sprintf("no file with id %s was sent in this thread", part.ID),
+				})
+			}
+		}
+		for _, a := range message.Content.Attachments() {
+			if a.ID != "" {
+				sent[a.ID] = a
+			}
+			attachments = append(attachments, a)
+		}
+	}
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+	return files.Keep(in.ThreadID, attachments), nil
+}
+
+// label names the file of a binary part
+func (p ContentPart) label() string {
+	for _, label := range []string{p.Filename, p.ID, p.URL} {
+		if label != "" {
+			return label
+		}
+	}
+	return p.MimeType
+}
+
+// inputAliases maps the snake_case keys clients may send to their camelCase
+// names. Only the keys of the input, its messages, content parts, tool calls
+// and tools are renamed, state and forwardedProps are passed through as sent.
+var inputAliases = map[string]string{
+	"thread_id":       "threadId",
+	"run_id":          "runId",
+	"parent_run_id":   "parentRunId",
+	"forwarded_props": "forwardedProps",
+	"tool_calls":      "toolCalls",
+	"tool_call_id":    "toolCallId",
+	"mime_type":       "mimeType",
+}
+
+var inputValidator = sync.OnceValues(func() (*schema.Validator, error) {
+	s, err := schema.Of[AgenticInput]()
+	if err != nil {
+		return nil, err
+	}
+	return schema.Compile("run-agent-input", s)
+})
+
+// ParseInput decodes and validates a RunAgentInput. Malformed JSON is returned
+// as is, invalid input as a *schema.ValidationError.
+func ParseInput(data []byte) (*AgenticInput, error) {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if obj, ok := raw.(map[string]any); ok {
+		normalize(obj)
+		for _, message := range objects(obj["messages"]) {
+			normalize(message)
+			for _, call := range objects(message["toolCalls"]) {
+				normalize(call)
+			}
+			for _, part := range objects(message["content"]) {
+				normalize(part)
+			}
+		}
+		for _, tool := range objects(obj["tools"]) {
+			normalize(tool)
+		}
+	}
+
+	validator, err := inputValidator()
+	if err != nil {
+		return nil, fmt.Errorf("input schema: %w", err)
+	}
+	if err := validator.Validate(raw); err != nil {
+		return nil, err
+	}
+
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var input AgenticInput
+	if err := json.Unmarshal(normalized, &input); err != nil {
+		return nil, err
+	}
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+	return &input, nil
+}
+
+// validate checks what the schema cannot express
+func (in *AgenticInput) validate() error {
+	verr := &schema.ValidationError{}
+	for i, message := range in.Messages {
+		if message.Role == "tool" && message.ToolCallID == "" {
+			verr.Fields = append(verr.Fields, schema.FieldError{
+				Field:   fmt.Sprintf("messages/%d/toolCallId", i),
+				Message: "tool messages must name the tool call they answer",
+			})
+		}
+		problems := message.Content.validate(message.Role)
+		for _, j := range slices.Sorted(maps.Keys(problems)) {
+			verr.Fields = append(verr.Fields, schema.FieldError{
+				Field:   fmt.Sprintf("messages/%d/content/%d", i, j),
+				Message: problems[j],
+			})
+		}
+		if len(message.ToolCalls) > 0 && message.Role != "assistant" {
+			verr.Fields = append(verr.Fields, schema.FieldError{
+				Field:   fmt.Sprintf("messages/%d/toolCalls", i),
+				Message: "only assistant messages can make tool calls",
+			})
+		}
+	}
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// normalize renames the snake_case keys of obj and drops null values, which
+// clients send for absent fields
+func normalize(obj map[string]any) {
+	for key, value := range obj {
+		if value == nil {
+			delete(obj, key)
+			continue
+		}
+		if alias, ok := inputAliases[key]; ok {
+			delete(obj, key)
+			if _, exists := obj[alias]; !exists {
+				obj[alias] = value
+			}
+		}
+	}
+}
+
+// objects returns the JSON objects in v, when it is an array
+func objects(v any) []map[string]any {
+	items, _ := v.([]any)
+	var objs []map[string]any
+	for _, item := range items {
+		if obj, ok := item.(map[string]any); ok {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
@@ -0,0 +1,207 @@
+package routes
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/agentic"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/config"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/runs"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInput(t *testing.T) {
+	camel := `{
+		"threadId": "t1", "runId": "r1", "state": {"count": 1},
+		"messages": [
+			{"id": "m1", "role": "assistant", "content": "", "toolCalls": [{"id": "c1", "type": "function", "function": {"name": "search", "arguments": "{}"}}]},
+			{"id": "m2", "role": "tool", "content": "found", "toolCallId": "c1"}
+		],
+		"tools": [{"name": "search", "parameters": {"type": "object"}}],
+		"context": [{"description": "page", "value": "/home"}],
+		"forwardedProps": {"snake_key": true}
+	}`
+	snake := `{
+		"thread_id": "t1", "run_id": "r1", "state": {"count": 1},
+		"messages": [
+			{"id": "m1", "role": "assistant", "content": null, "tool_calls": [{"id": "c1", "type": "function", "function": {"name": "search", "arguments": "{}"}}]},
+			{"id": "m2", "role": "tool", "content": "found", "tool_call_id": "c1"}
+		],
+		"tools": [{"name": "search", "parameters": {"type": "object"}}],
+		"context": [{"description": "page", "value": "/home"}],
+		"forwarded_props": {"snake_key": true}
+	}`
+
+	want, err := ParseInput([]byte(camel))
+	require.NoError(t, err)
+	assert.Equal(t, "t1", want.ThreadID)
+	assert.Equal(t, "search", want.Messages[0].ToolCalls[0].Function.Name)
+	assert.Equal(t, Message{ID: "m2", Role: "tool", Content: Content{{Type: PartText, Text: "found"}}, ToolCallID: "c1"}, want.LastMessage())
+	assert.Equal(t, []ContextEntry{{Description: "page", Value: "/home"}}, want.Context)
+	// Props are passed through without renaming
+	assert.Equal(t, map[string]any{"snake_key": true}, want.ForwardedProps)
+
+	got, err := ParseInput([]byte(snake))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	_, err = ParseInput([]byte(`{"messages": [{"role": "robot"}, {"role": "tool", "content": "x"}], "context": [{"value": 1}]}`))
+	var verr *schema.ValidationError
+	require.ErrorAs(t, err, &verr)
+	var fields []string
+	for _, f := range verr.Fields {
+		fields = append(fields, f.Field)
+	}
+	assert.ElementsMatch(t, []string{"messages/0/role", "context/0", "context/0/value"}, fields)
+
+	_, err = ParseInput([]byte(`{"messages": [{"role": "tool", "content": "x"}]}`))
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []schema.FieldError{{Field: "messages/0/toolCallId", Message: "tool messages must name the tool call they answer"}}, verr.Fields)
+}
+
+func TestInvalidInputStatus(t *testing.T) {
+	app := fiber.New()
+	app.Post("/agentic", AgenticHandler(config.New(), Deps{Tracker: runs.NewTracker(), Interactions: agentic.NewInteractions()}))
+
+	post := func(body string) (int, map[string]any) {
+		req := httptest.NewRequest(http.MethodPost, "/agentic", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		return resp.StatusCode, decoded
+	}
+
+	status, _ := post(`{"messages": [`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, body := post(`{"threadId": "t1"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, "Invalid run input", body["error"])
+	require.Len(t, body["fields"], 1)
+	assert.Contains(t, body["fields"].([]any)[0].(map[string]any)["message"], "messages")
+}
+
+func TestParseMultimodalInput(t *testing.T) {
+	input, err := ParseInput([]byte(`{"messages": [{"role": "user", "content": [
+		{"type": "text", "text": "What is in these files?"},
+		{"type": "binary", "mime_type": "image/png", "data": "iVBORw0K", "filename": "cat.png"},
+		{"type": "binary", "mimeType": "image/jpeg", "url": "https://example.com/dog.jpg"},
+		{"type": "binary", "mimeType": "text/plain", "data": "aGVsbG8=", "filename": "notes.txt"}
+	]}]}`))
+	require.NoError(t, err)
+
+	content := input.LastMessage().Content
+	assert.Equal(t, "What is in these files?", content.Text())
+	attachments := content.Attachments()
+	require.Len(t, attachments, 3)
+	assert.Equal(t, "cat.png", attachments[0].Name)
+	assert.Equal(t, "https://example.com/dog.jpg", attachments[1].URL)
+	assert.Equal(t, []byte("hello"), attachments[2].Data)
+
+	_, err = ParseInput([]byte(`{"messages": [
+		{"role": "assistant", "content": [{"type": "binary", "mimeType": "image/png", "data": "iVBORw0K"}]},
+		{"role": "user", "content": [
+			{"type": "binary", "mimeType": "application/zip", "data": "UEsDBA=="},
+			{"type": "binary", "mimeType": "text/plain", "url": "https://example.com/notes.txt"},
+			{"type": "binary", "mimeType": "image/png"},
+			{"type": "binary", "mimeType": "image/png", "data": "not base64!"},
+			{"type": "video"}
+		]}
+	]}`))
+	var verr *schema.ValidationError
+	require.ErrorAs(t, err, &verr)
+	fields := map[string]string{}
+	for _, f := range verr.Fields {
+		fields[f.Field] = f.Message
+	}
+	assert.Contains(t, fields, "messages/1/content")
+	assert.NotContains(t, fields, "messages/0/content/0")
+
+	// Without the invalid part type every other problem is reported
+	_, err = ParseInput([]byte(`{"messages": [
+		{"role": "assistant", "content": [{"type": "binary", "mimeType": "image/png", "data": "iVBORw0K"}]},
+		{"role": "user", "content": [
+			{"type": "binary", "mimeType": "application/zip", "data": "UEsDBA=="},
+			{"type": "binary", "mimeType": "text/plain", "url": "https://example.com/notes.txt"},
+			{"type": "binary", "mimeType": "image/png"},
+			{"type": "binary", "mimeType": "image/png", "data": "not base64!"}
+		]}
+	]}`))
+	require.ErrorAs(t, err, &verr)
+	fields = map[string]string{}
+	for _, f := range verr.Fields {
+		fields[f.Field] = f.Message
+	}
+	assert.Equal(t, map[string]string{
+		"messages/0/content/0": "only user messages can carry attachments",
+		"messages/1/content/0": "unsupported attachment type application/zip, send images, PDFs or text",
+		"messages/1/content/1": "text/plain files must be sent as data, only images can be sent by url",
+		"messages/1/content/2": "binary parts need data, a url or the id of a file sent before",
+		"messages/1/content/3": "data is not valid base64: illegal base64 data at input byte 3",
+	}, fields)
+}
+
+func TestThreadAttachments(t *testing.T) {
+	files := agentic.NewFiles()
+	parse := func(data string) *AgenticInput {
+		input, err := ParseInput([]byte(data))
+		require.NoError(t, err)
+		return input
+	}
+
+	// The first run sends a picture
+	input := parse(`{"threadId": "t1", "messages": [{"role": "user", "content": [
+		{"type": "text", "text": "What is this?"},
+		{"type": "binary", "mimeType": "image/png", "data": "iVBORw0K", "filename": "cat.png", "id": "cat"}
+	]}]}`)
+	attachments, err := input.Attachments(files)
+	require.NoError(t, err)
+	require.Len(t, attachments, 1)
+	assert.Equal(t, "cat", attachments[0].ID)
+
+	// A later run sending only its new message still has the picture, and can
+	// refer to it by id
+	input = parse(`{"threadId": "t1", "messages": [{"role": "user", "content": [
+		{"type": "text", "text": "Compare the cat with this"},
+		{"type": "binary", "id": "cat"},
+		{"type": "binary", "mimeType": "image/jpeg", "url": "https://example.com/dog.jpg"}
+	]}]}`)
+	attachments, err = input.Attachments(files)
+	require.NoError(t, err)
+	require.Len(t, attachments, 2)
+	assert.Equal(t, "cat.png", attachments[0].Name)
+	assert.Equal(t, "https://example.com/dog.jpg", attachments[1].URL)
+
+	// A client resending the whole thread does not duplicate its files
+	input = parse(`{"threadId": "t1", "messages": [
+		{"role": "user", "content": [{"type": "binary", "mimeType": "image/jpeg", "url": "https://example.com/dog.jpg"}]},
+		{"role": "assistant", "content": "A dog"},
+		{"role": "user", "content": "Which one is bigger?"}
+	]}`)
+	attachments, err = input.Attachments(files)
+	require.NoError(t, err)
+	assert.Len(t, attachments, 2)
+	assert.Equal(t, []agentic.HistoryMessage{
+		{Role: "user", Content: "(attached https://example.com/dog.jpg)"},
+		{Role: "assistant", Content: "A dog"},
+	}, input.History())
+
+	// Ids of files the thread does not have are rejected
+	input = parse(`{"threadId": "t2", "messages": [{"role": "user", "content": [{"type": "binary", "id": "cat"}]}]}`)
+	_, err = input.Attachments(files)
+	var verr *schema.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []schema.FieldError{{Field: "messages/0/content/0", Message: "no file with id cat was sent in this thread"}}, verr.Fields)
+}
@@ -0,0 +1,87 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/config"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/schema"
+)
+
+// errUnknownAgent is returned for routes naming an agent that is not configured
+var errUnknownAgent = errors.New("unknown agent")
+
+// overrideProps maps the forwardedProps keys that override run parameters to
+// the config.Override* names that allow them
+var overrideProps = []struct {
+	prop, override string
+}{
+	{"agent", config.OverrideAgent},
+	{"model", config.OverrideModel},
+	{"temperature", config.OverrideTemperature},
+	{"maxIterations", config.OverrideMaxIterations},
+}
+
+// resolveAgent returns the agent profile for a run of the named agent with the
+// overrides in props applied:
+//
+//	{"agent": "reviewer", "model": "claude-3-5-sonnet-20241022", "temperature": 0.2, "maxIterations": 10}
+//
+// Overrides the config does not allow, or with invalid values, are returned as
+// a *schema.ValidationError, an unknown agent in the route as errUnknownAgent.
+func resolveAgent(cfg *config.Config, name string, props map[string]any) (string, config.AgentProfile, error) {
+	verr := &schema.ValidationError{}
+	fail := func(prop, format string, args ...any) {
+		verr.Fields = append(verr.Fields, schema.FieldError{Field: "forwardedProps/" + prop, Message: fmt.Sprintf(format, args...)})
+	}
+	for _, o := range overrideProps {
+		if _, ok := props[o.prop]; ok && !cfg.OverrideAllowed(o.override) {
+			fail(o.prop, "the server does not allow overriding %s", o.override)
+		}
+	}
+	if len(verr.Fields) > 0 {
+		return "", config.AgentProfile{}, verr
+	}
+
+	if value, ok := props["agent"]; ok {
+		agent, _ := value.(string)
+		if _, exists := cfg.Agent(agent); agent == "" || !exists {
+			fail("agent", "unknown agent %v", value)
+			return "", config.AgentProfile{}, verr
+		}
+		name = agent
+	}
+	profile, ok := cfg.Agent(name)
+	if !ok {
+		return "", config.AgentProfile{}, errUnknownAgent
+	}
+
+	if value, ok := props["model"]; ok {
+		if model, ok := value.(string); ok && model != "" {
+			profile.Model = model
+		} else {
+			fail("model", "must be a model name")
+		}
+	}
+	if value, ok := props["temperature"]; ok {
+		if t, ok := value.(float64); ok && t >= 0 && t <= 1 {
+			profile.Temperature = &t
+		} else {
+			fail("temperature", "must be a number between 0 and 1")
+		}
+	}
+	if value, ok := props["maxIterations"]; ok {
+		// Clients may lower the agent's limit, never raise it
+		if n, ok := value.(float64); ok && n == math.Trunc(n) && n >= 1 && int(n) <= profile.MaxIterations {
+			profile.MaxIterations = int(n)
+		} else {
+			fail("maxIterations", "must be a whole number between 1 and %d", profile.MaxIterations)
+		}
+	}
+
+	if len(verr.Fields) > 0 {
+		return "", config.AgentProfile{}, verr
+	}
+	return name, profile, nil
+}
@@ -0,0 +1,64 @@
+package routes
+
+import (
+	"testing"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/config"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveAgent(t *testing.T) {
+	cfg := config.New()
+	cfg.Model = "default-model"
+	cfg.Agents = map[string]config.AgentProfile{"reviewer": {SystemPrompt: "Review code.", MaxIterations: 10}}
+
+	// Nothing may be overridden by default
+	_, _, err := resolveAgent(cfg, "", map[string]any{"model": "other", "agent": "reviewer"})
+	var verr *schema.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []schema.FieldError{
+		{Field: "forwardedProps/agent", Message: "the server does not allow overriding agent"},
+		{Field: "forwardedProps/model", Message: "the server does not allow overriding model"},
+	}, verr.Fields)
+
+	_, _, err = resolveAgent(cfg, "unknown", nil)
+	require.ErrorIs(t, err, errUnknownAgent)
+
+	cfg.AllowedOverrides = []string{config.OverrideAgent, config.OverrideModel, config.OverrideTemperature, config.OverrideMaxIterations}
+	name, profile, err := resolveAgent(cfg, "", map[string]any{"agent": "reviewer", "model": "other", "temperature": 0.3, "maxIterations": 4.0, "theme": "dark"})
+	require.NoError(t, err)
+	assert.Equal(t, "reviewer", name)
+	assert.Equal(t, "Review code.", profile.SystemPrompt)
+	assert.Equal(t, "other", profile.Model)
+	assert.InDelta(t, 0.3, *profile.Temperature, 1e-9)
+	assert.Equal(t, 4, profile.MaxIterations)
+
+	// The agent's iteration limit cannot be raised
+	_, _, err = resolveAgent(cfg, "reviewer", map[string]any{"maxIterations": 11.0, "temperature": "hot", "agent": "missing"})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []schema.FieldError{{Field: "forwardedProps/agent", Message: "unknown agent missing"}}, verr.Fields)
+
+	_, _, err = resolveAgent(cfg, "reviewer", map[string]any{"maxIterations": 11.0, "temperature": "hot"})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []schema.FieldError{
+		{Field: "forwardedProps/temperature", Message: "must be a number between 0 and 1"},
+		{Field: "forwardedProps/maxIterations", Message: "must be a whole number between 1 and 10"},
+	}, verr.Fields)
+}
+
+func TestRenderPromptsContext(t *testing.T) {
+	input := &AgenticInput{Context: []ContextEntry{
+		{Description: "Current page", Value: "/orders/42"},
+		{Description: "Selected record", Value: "order 42"},
+	}}
+
+	system, content, err := renderPrompts(nil, "", config.AgentProfile{SystemPrompt: "You manage orders."}, input, "Cancel it")
+	require.NoError(t, err)
+	assert.Equal(t, "You manage orders.\n\n"+
+		"The user's application provided this context:\n"+
+		"- Current page: /orders/42\n"+
+		"- Selected record: order 42", system)
+	assert.Equal(t, "Cancel it", content)
+}
@@ -0,0 +1,111 @@
+package routes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/agentic"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/config"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/prompt"
+)
+
+// renderPrompts renders the system prompt of the agent and the user's input.
+// The profile's own system prompt comes first, followed by its system
+// template and the run's context entries, and the user template wraps content.
+func renderPrompts(templates *prompt.Engine, name string, profile config.AgentProfile, input *AgenticInput, content string) (string, string, error) {
+	data := prompt.Data{
+		Input:   content,
+		Agent:   name,
+		Context: contextEntries(input.Context),
+		State:   input.State,
+		Props:   input.ForwardedProps,
+	}
+
+	var system []string
+	if profile.SystemPrompt != "" {
+		text, err := prompt.Render("system_prompt", profile.SystemPrompt, data)
+		if err != nil {
+			return "", "", fmt.Errorf("agent system prompt: %w", err)
+		}
+		system = append(system, text)
+	}
+	if tmpl := templateName(templates, profile.SystemTemplate, prompt.DefaultSystemTemplate); tmpl != "" {
+		text, err := templates.Render(tmpl, data)
+		if err != nil {
+			return "", "", err
+		}
+		system = append(system, text)
+	}
+
+	if section := contextSection(input.Context); section != "" {
+		system = append(system, section)
+	}
+
+	if tmpl := templateName(templates, profile.UserTemplate, prompt.DefaultUserTemplate); tmpl != "" {
+		var err error
+		if content, err = templates.Render(tmpl, data); err != nil {
+			return "", "", err
+		}
+	}
+	return strings.Join(system, "\n\n"), content, nil
+}
+
+// subAgents returns the sub-agents of profile with their system prompts
+// rendered for input
+func subAgents(cfg *config.Config, templates *prompt.Engine, profile config.AgentProfile, input *AgenticInput, content string) ([]agentic.SubAgent, error) {
+	var agents []agentic.SubAgent
+	for _, name := range profile.SubAgents {
+		sub, ok := cfg.Agent(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown sub-agent %s", name)
+		}
+		systemPrompt, _, err := renderPrompts(templates, name, sub, input, content)
+		if err != nil {
+			return nil, fmt.Errorf("sub-agent %s: %w", name, err)
+		}
+		agents = append(agents, agentic.SubAgent{
+			Name:          name,
+			Description:   sub.Description,
+			Model:         sub.Model,
+			SystemPrompt:  systemPrompt,
+			Tools:         sub.Tools,
+			MaxIterations: sub.MaxIterations,
+			Temperature:   sub.Temperature,
+		})
+	}
+	return agents, nil
+}
+
+// templateName returns the template the profile names, or the default one when
+// it names none and the default exists
+func templateName(templates *prompt.Engine, name, fallback string) string {
+	if name != "" {
+		return name
+	}
+	if templates.Has(fallback) {
+		return fallback
+	}
+	return ""
+}
+
+// contextSection lists the context entries the frontend supplied
+func contextSection(entries []ContextEntry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("The user's application provided this context:")
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "\n- %s: %s", entry.Description, entry.Value)
+	}
+	return b.String()
+}
+
+// contextEntries converts the run's context entries for templates
+func contextEntries(entries []ContextEntry) []prompt.ContextEntry {
+	converted := make([]prompt.ContextEntry, 0, len(entries))
+	for _, entry := range entries {
+		converted = append(converted, prompt.ContextEntry{Description: entry.Description, Value: entry.Value})
+	}
+	return converted
+}
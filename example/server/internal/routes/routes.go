@@ -3,6 +3,7 @@ package routes
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 
@@ -10,22 +11,40 @@ import (
 	"github.com/ag-ui-protocol/ag-ui/sdks/community/go/pkg/encoding/sse"
 	"github.com/gofiber/fiber/v3"
 	"github.com/mattsp1290/october-talks-2025/example/server/internal/agentic"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/cassette"
 	"github.com/mattsp1290/october-talks-2025/example/server/internal/config"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/mcp"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/prompt"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/rag"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/runs"
+	"github.com/mattsp1290/october-talks-2025/example/server/internal/schema"
 )
 
-// AgenticInput represents the input structure for the tool-based generative UI endpoint
-type AgenticInput struct {
-	ThreadID       string                   `json:"thread_id"`
-	RunID          string                   `json:"run_id"`
-	State          interface{}              `json:"state"`
-	Messages       []map[string]interface{} `json:"messages"`
-	Tools          []interface{}            `json:"tools"`
-	Context        []interface{}            `json:"context"`
-	ForwardedProps interface{}              `json:"forwarded_props"`
+// Deps are the services shared by every run. Tracker and Interactions are
+// required, the others may be nil.
+type Deps struct {
+	// Tracker registers every run so shutdown can drain it
+	Tracker *runs.Tracker
+	// Adapters shares MCP connections between runs
+	Adapters *mcp.Registry
+	// Interactions delivers the answers to the elicitations and tool call
+	// approvals forwarded to the client
+	Interactions *agentic.Interactions
+	// Templates renders prompts
+	Templates *prompt.Engine
+	// History caches the summaries of long threads
+	History *agentic.HistoryCache
+	// Files keeps attachments per thread
+	Files *agentic.Files
+	// Documents is searched by the agent's retrieval tool
+	Documents *rag.Store
 }
 
-// AgenticHandler creates a Fiber handler for the tool-based generative UI route
-func AgenticHandler(cfg *config.Config) fiber.Handler {
+// AgenticHandler creates a Fiber handler for the tool-based generative UI route.
+// The configuration is read from cfgs on every request so reloads apply to new
+// runs. Routes with a :name parameter serve the named agent profile, /agentic
+// serves the default agent.
+func AgenticHandler(cfgs config.Provider, deps Deps) fiber.Handler {
 	logger := slog.Default()
 	sseWriter := sse.NewSSEWriter().WithLogger(logger)
 
@@ -43,14 +62,79 @@ func AgenticHandler(cfg *config.Config) fiber.Handler {
 		}
 
 		// Parse request body first before setting headers
-		var input AgenticInput
-		if err := c.Bind().JSON(&input); err != nil {
+		var verr *schema.ValidationError
+		input, err := ParseInput(c.Body())
+		if errors.As(err, &verr) {
+			logger.Warn("Invalid run input", append(logCtx, "error", err)...)
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error":  "Invalid run input",
+				"fields": verr.Fields,
+			})
+		} else if err != nil {
 			logger.Error("Failed to parse request body", append(logCtx, "error", err)...)
 			return c.Status(400).JSON(fiber.Map{
 				"error": "Invalid request body",
 			})
 		}
 
+		// The run's agent comes from the route, adjusted by the overrides the
+		// client forwarded
+		cfg := cfgs.Current()
+		name, profile, err := resolveAgent(cfg, c.Params("name"), input.ForwardedProps)
+		if errors.Is(err, errUnknownAgent) {
+			logger.Warn("Unknown agent", append(logCtx, "agent", c.Params("name"))...)
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": fmt.Sprintf("Unknown agent '%s'", c.Params("name")),
+			})
+		} else if errors.As(err, &verr) {
+			logger.Warn("Invalid run overrides", append(logCtx, "error", err)...)
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error":  "Invalid run input",
+				"fields": verr.Fields,
+			})
+		}
+		if name != "" {
+			logCtx = append(logCtx, "agent", name)
+		}
+
+		// Answers to elicitations and approval requests go to the run waiting
+		// for them instead of starting one
+		answered := false
+		if last := input.LastMessage(); last.Role == "tool" && deps.Interactions.Resolve(last.ToolCallID, last.Content.Text()) {
+			logger.Info("Tool call answered", append(logCtx, "tool_call_id", last.ToolCallID)...)
+			answered = true
+		} else if approval, ok := approvalAnswer(input.ForwardedProps); ok && deps.Interactions.Approve(approval) {
+			logger.Info("Tool call approval answered", append(logCtx, "tool_call_id", approval.ToolCallID, "approved", approval.Approved)...)
+			answered = true
+		}
+		if answered {
+			c.Set("Content-Type", "text/event-stream")
+			c.Set("Cache-Control", "no-cache")
+			return c.SendStreamWriter(func(w *bufio.Writer) {
+				if err := writeAnswered(c.RequestCtx(), w, sseWriter, input); err != nil {
+					logger.Error("Error acknowledging answer", append(logCtx, "error", err)...)
+				}
+			})
+		}
+
+		attachments, err := input.Attachments(deps.Files)
+		if errors.As(err, &verr) {
+			logger.Warn("Invalid run input", append(logCtx, "error", err)...)
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error":  "Invalid run input",
+				"fields": verr.Fields,
+			})
+		}
+
+		// Register the run before committing to a stream so draining servers can refuse it
+		runCtx, done, err := deps.Tracker.Start(context.Background())
+		if err != nil {
+			logger.Warn("Rejecting run", append(logCtx, "error", err)...)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Server is shutting down",
+			})
+		}
+
 		// Set SSE headers after validation
 		c.Set("Content-Type", "text/event-stream")
 		c.Set("Cache-Control", "no-cache")
@@ -65,15 +149,38 @@ func AgenticHandler(cfg *config.Config) fiber.Handler {
 
 		// Start streaming
 		return c.SendStreamWriter(func(w *bufio.Writer) {
-			if err := streamAgenticEvents(ctx, w, sseWriter, &input, cfg, logger, logCtx); err != nil {
+			defer done()
+			run := agentRun{
+				input:       input,
+				attachments: attachments,
+				cfg:         cfg,
+				name:        name,
+				profile:     profile,
+				logger:      logger,
+				logCtx:      logCtx,
+			}
+			if err := streamAgenticEvents(ctx, runCtx, w, sseWriter, deps, run); err != nil {
 				logger.Error("Error streaming tool-based generative UI events", append(logCtx, "error", err)...)
 			}
 		})
 	}
 }
 
-// streamAgenticEvents implements the tool-based generative UI event sequence
-func streamAgenticEvents(reqCtx context.Context, w *bufio.Writer, sseWriter *sse.SSEWriter, input *AgenticInput, _ *config.Config, logger *slog.Logger, logCtx []any) error {
+// agentRun is one run of an agent, as resolved from the request
+type agentRun struct {
+	input       *AgenticInput
+	attachments []agentic.Attachment
+	cfg         *config.Config
+	name        string
+	profile     config.AgentProfile
+	logger      *slog.Logger
+	logCtx      []any
+}
+
+// streamAgenticEvents implements the tool-based generative UI event sequence.
+// reqCtx tracks the client connection, ctx is canceled if the run is aborted by shutdown.
+func streamAgenticEvents(reqCtx, ctx context.Context, w *bufio.Writer, sseWriter *sse.SSEWriter, deps Deps, run agentRun) error {
+	input, cfg, profile, logger, logCtx := run.input, run.cfg, run.profile, run.logger, run.logCtx
 	// Use IDs from input or generate new ones if not provided
 	threadID := input.ThreadID
 	if threadID == "" {
@@ -84,9 +191,6 @@ func streamAgenticEvents(reqCtx context.Context, w *bufio.Writer, sseWriter *sse
 		runID = events.GenerateRunID()
 	}
 
-	// Create a wrapped context for our operations
-	ctx := context.Background()
-
 	// Send RUN_STARTED event
 	runStarted := events.NewRunStartedEvent(threadID, runID)
 	if err := sseWriter.WriteEvent(ctx, w, runStarted); err != nil {
@@ -99,21 +203,74 @@ func streamAgenticEvents(reqCtx context.Context, w *bufio.Writer, sseWriter *sse
 		return nil
 	}
 
-	// Grab last message from input, will be a user message
-	var lastMessage map[string]interface{}
-	if len(input.Messages) > 0 {
-		lastMessage = input.Messages[len(input.Messages)-1]
+	opts := agentic.Options{
+		Model:           profile.Model,
+		MCPServers:      cfg.MCPEndpoints(),
+		Adapters:        deps.Adapters,
+		Interactions:    deps.Interactions,
+		ToolPolicy:      cfg.ToolPolicy,
+		ToolTimeout:     cfg.ToolTimeout,
+		ToolParallelism: cfg.ToolParallelism,
+		Tools:           profile.Tools,
+		MaxIterations:   profile.MaxIterations,
+		Temperature:     profile.Temperature,
+		History:         input.History(),
+		HistoryPolicy: agentic.HistoryPolicy{
+			Mode:      profile.History,
+			MaxTokens: profile.HistoryTokens,
+			Turns:     profile.HistoryTurns,
+		},
+		HistoryCache:  deps.History,
+		ThreadID:      threadID,
+		Documents:     deps.Documents,
+		OutputSchema:  profile.OutputSchema,
+		OutputRetries: profile.OutputRetries,
 	}
-	// grab "content" field if it exists
-	content, ok := lastMessage["content"].(string)
-	if !ok {
-		return fmt.Errorf("last message does not have content")
+	applyMCPProps(&opts, input.ForwardedProps)
+
+	// The last message is the user's input, a prompt can stand in for it
+	last := input.LastMessage()
+	content := last.Content.Text()
+	opts.Attachments = run.attachments
+	if content == "" && !last.Content.hasFiles() && opts.Prompt == "" {
+		logger.Warn("Run input has no content", logCtx...)
+		return writeRunError(ctx, w, sseWriter, runID, "invalid_input", "The last message has no content")
 	}
 
-	err := agentic.ProcessInput(ctx, w, sseWriter, content)
+	systemPrompt, content, err := renderPrompts(deps.Templates, run.name, profile, input, content)
 	if err != nil {
-		return fmt.Errorf("failed to process input: %w", err)
+		logger.Warn("Failed to render prompts", append(logCtx, "error", err)...)
+		return writeRunError(ctx, w, sseWriter, runID, "prompt_template", err.Error())
+	}
+	opts.SystemPrompt = systemPrompt
+	if opts.SubAgents, err = subAgents(cfg, deps.Templates, profile, input, content); err != nil {
+		logger.Warn("Failed to render prompts", append(logCtx, "error", err)...)
+		return writeRunError(ctx, w, sseWriter, runID, "prompt_template", err.Error())
+	}
 
+	rec, err := cassette.Open(cfg.Cassette, cassette.Mode(cfg.CassetteMode))
+	if err != nil {
+		logger.Error("Failed to open cassette", append(logCtx, "error", err)...)
+		return writeRunError(ctx, w, sseWriter, runID, "cassette", err.Error())
+	}
+	defer func() {
+		if err := rec.Close(); err != nil {
+			logger.Error("Failed to save cassette", append(logCtx, "error", err)...)
+		}
+	}()
+	opts.Cassette = rec
+
+	err = agentic.ProcessInput(ctx, w, sseWriter, content, opts)
+	if err != nil {
+		if errors.Is(context.Cause(ctx), runs.ErrServerShutdown) {
+			return writeShutdownError(ctx, w, sseWriter, runID)
+		}
+		if errors.Is(err, agentic.ErrOutputSchema) {
+			logger.Warn("Answer does not match the output schema", append(logCtx, "error", err)...)
+			return writeRunError(ctx, w, sseWriter, runID, "output_schema", err.Error())
+		}
+		logger.Error("Failed to process input", append(logCtx, "error", err)...)
+		return writeRunError(ctx, w, sseWriter, runID, "agent_error", err.Error())
 	}
 
 	// Check for cancellation before final event
@@ -129,3 +286,83 @@ func streamAgenticEvents(reqCtx context.Context, w *bufio.Writer, sseWriter *sse
 
 	return nil
 }
+
+// applyMCPProps reads the MCP prompt and resources a client asked for:
+//
+//	{"prompt": "client_prompt", "prompt_args": {"Language": "Rust"}, "resources": ["docs://README.md"]}
+func applyMCPProps(opts *agentic.Options, props map[string]any) {
+	if prompt, ok := props["prompt"].(string); ok {
+		opts.Prompt = prompt
+	}
+	if args, ok := props["prompt_args"].(map[string]interface{}); ok {
+		opts.PromptArgs = make(map[string]string, len(args))
+		for name, value := range args {
+			opts.PromptArgs[name] = fmt.Sprint(value)
+		}
+	}
+	if resources, ok := props["resources"].([]interface{}); ok {
+		for _, resource := range resources {
+			if uri, ok := resource.(string); ok {
+				opts.Resources = append(opts.Resources, uri)
+			}
+		}
+	}
+}
+
+// approvalAnswer reads the answer to an agentic.ApprovalRequestEvent from forwardedProps:
+//
+//	{"approval": {"toolCallId": "...", "approved": true, "reason": "..."}}
+func approvalAnswer(forwardedProps map[string]any) (agentic.Approval, bool) {
+	fields, ok := forwardedProps["approval"].(map[string]any)
+	if !ok {
+		return agentic.Approval{}, false
+	}
+
+	var approval agentic.Approval
+	approval.ToolCallID, ok = fields["toolCallId"].(string)
+	if !ok {
+		return agentic.Approval{}, false
+	}
+	approval.Approved, _ = fields["approved"].(bool)
+	approval.Reason, _ = fields["reason"].(string)
+	return approval, true
+}
+
+// writeAnswered completes the request that carried an elicitation or approval answer, the
+// waiting run streams the rest of the conversation
+func writeAnswered(ctx context.Context, w *bufio.Writer, sseWriter *sse.SSEWriter, input *AgenticInput) error {
+	threadID := input.ThreadID
+	if threadID == "" {
+		threadID = events.GenerateThreadID()
+	}
+	runID := input.RunID
+	if runID == "" {
+		runID = events.GenerateRunID()
+	}
+
+	if err := sseWriter.WriteEvent(ctx, w, events.NewRunStartedEvent(threadID, runID)); err != nil {
+		return fmt.Errorf("failed to write RUN_STARTED event: %w", err)
+	}
+	if err := sseWriter.WriteEvent(ctx, w, events.NewRunFinishedEvent(threadID, runID)); err != nil {
+		return fmt.Errorf("failed to write RUN_FINISHED event: %w", err)
+	}
+	return nil
+}
+
+// writeShutdownError tells the client its run was aborted because the server is going away
+func writeShutdownError(ctx context.Context, w *bufio.Writer, sseWriter *sse.SSEWriter, runID string) error {
+	// The run context is already canceled, write with one that isn't
+	return writeRunError(context.WithoutCancel(ctx), w, sseWriter, runID, "server_shutdown", "Run aborted: server is shutting down")
+}
+
+// writeRunError ends the run with a RUN_ERROR
+func writeRunError(ctx context.Context, w *bufio.Writer, sseWriter *sse.SSEWriter, runID, code, message string) error {
+	runError := events.NewRunErrorEvent(message,
+		events.WithErrorCode(code),
+		events.WithRunID(runID),
+	)
+	if err := sseWriter.WriteEvent(ctx, w, runError); err != nil {
+		return fmt.Errorf("failed to write RUN_ERROR event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package runs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrDraining is returned by Start once the server has begun shutting down
+var ErrDraining = errors.New("server is shutting down, not accepting new runs")
+
+// ErrServerShutdown is the cancellation cause of runs aborted at the end of the grace period
+var ErrServerShutdown = errors.New("server shutdown")
+
+// Tracker keeps track of in-flight agent runs so shutdown can wait for them
+type Tracker struct {
+	mu       sync.Mutex
+	draining bool
+	nextID   uint64
+	active   map[uint64]context.CancelCauseFunc
+	idle     chan struct{}
+}
+
+// NewTracker creates an empty Tracker
+func NewTracker() *Tracker {
+	return &Tracker{
+		active: make(map[uint64]context.CancelCauseFunc),
+	}
+}
+
+// Start registers a new run. The returned context is canceled with
+// ErrServerShutdown if the run is aborted during shutdown, and done must be
+// called once the run has finished writing its events.
+func (t *Tracker) Start(parent context.Context) (ctx context.Context, done func(), err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.draining {
+		return nil, nil, ErrDraining
+	}
+
+	ctx, cancel := context.WithCancelCause(parent)
+	id := t.nextID
+	t.nextID++
+	t.active[id] = cancel
+
+	var once sync.Once
+	done = func() {
+		once.Do(func() {
+			cancel(nil)
+			t.finish(id)
+		})
+	}
+	return ctx, done, nil
+}
+
+// finish removes a run and wakes up Drain when the last one completes
+func (t *Tracker) finish(id uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.active, id)
+	if len(t.active) == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// Active returns the number of in-flight runs
+func (t *Tracker) Active() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.active)
+}
+
+// StopAccepting makes subsequent calls to Start fail with ErrDraining
+func (t *Tracker) StopAccepting() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.draining = true
+}
+
+// waitIdle returns a channel closed once no runs are active
+func (t *Tracker) waitIdle() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.active) == 0 {
+		idle := make(chan struct{})
+		close(idle)
+		return idle
+	}
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	return t.idle
+}
+
+// Drain stops accepting runs and waits up to grace for active runs to finish.
+// Runs still active after that are canceled with ErrServerShutdown, and Drain
+// waits until ctx is done for them to report the error to their clients.
+// It returns how many runs finished on their own and how many were aborted.
+func (t *Tracker) Drain(ctx context.Context, grace time.Duration) (drained, aborted int) {
+	t.StopAccepting()
+	total := t.Active()
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	select {
+	case <-t.waitIdle():
+		return total, 0
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	t.mu.Lock()
+	aborted = len(t.active)
+	for _, cancel := range t.active {
+		cancel(ErrServerShutdown)
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-t.waitIdle():
+	case <-ctx.Done():
+	}
+	return total - aborted, aborted
+}
@@ -0,0 +1,46 @@
+package runs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainWaitsForActiveRuns(t *testing.T) {
+	tracker := NewTracker()
+	_, done, err := tracker.Start(context.Background())
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		done()
+	}()
+
+	drained, aborted := tracker.Drain(context.Background(), time.Second)
+	require.Equal(t, 1, drained)
+	require.Equal(t, 0, aborted)
+
+	_, _, err = tracker.Start(context.Background())
+	require.ErrorIs(t, err, ErrDraining)
+}
+
+func TestDrainAbortsStragglers(t *testing.T) {
+	tracker := NewTracker()
+	ctx, done, err := tracker.Start(context.Background())
+	require.NoError(t, err)
+
+	// The run reports the abort and finishes once its context is canceled
+	go func() {
+		<-ctx.Done()
+		assert.ErrorIs(t, context.Cause(ctx), ErrServerShutdown)
+		done()
+	}()
+
+	drained, aborted := tracker.Drain(context.Background(), 10*time.Millisecond)
+	require.Equal(t, 0, drained)
+	require.Equal(t, 1, aborted)
+	require.Equal(t, 0, tracker.Active())
+}
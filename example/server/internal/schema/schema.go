@@ -0,0 +1,141 @@
+// Package schema derives JSON Schemas from Go types and validates values against them.
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Struct tags understood by For, alongside the usual json tag:
+//
+//	description:"Human readable description"
+//	mcp:"required"
+//	enum:"a,b,c"
+const (
+	tagDescription = "description"
+	tagMCP         = "mcp"
+	tagEnum        = "enum"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Schemer is implemented by types that describe their own JSON Schema, such as
+// types with a custom JSON encoding
+type Schemer interface {
+	JSONSchema() map[string]any
+}
+
+var schemerType = reflect.TypeOf((*Schemer)(nil)).Elem()
+
+// For returns the JSON Schema describing values of type t
+func For(t reflect.Type) (map[string]any, error) {
+	return forType(t, map[reflect.Type]bool{})
+}
+
+// Of returns the JSON Schema describing values of type T
+func Of[T any]() (map[string]any, error) {
+	return For(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+func forType(t reflect.Type, seen map[reflect.Type]bool) (map[string]any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	case t.Implements(schemerType):
+		return reflect.Zero(t).Interface().(Schemer).JSONSchema(), nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Interface:
+		return map[string]any{}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := forType(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key of %s must be a string", t)
+		}
+		values, err := forType(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		return forStruct(t, seen)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+func forStruct(t reflect.Type, seen map[reflect.Type]bool) (map[string]any, error) {
+	if seen[t] {
+		return nil, fmt.Errorf("recursive type %s is not supported", t)
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	properties := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		prop, err := forType(field.Type, seen)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if desc := field.Tag.Get(tagDescription); desc != "" {
+			prop["description"] = desc
+		}
+		if enum := field.Tag.Get(tagEnum); enum != "" {
+			values := strings.Split(enum, ",")
+			prop["enum"] = values
+		}
+		for _, opt := range strings.Split(field.Tag.Get(tagMCP), ",") {
+			if opt == "required" {
+				required = append(required, name)
+			}
+		}
+		properties[name] = prop
+	}
+
+	s := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s, nil
+}
@@ -0,0 +1,97 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// Validator checks values against a compiled JSON Schema
+type Validator struct {
+	schema *jsonschema.Schema
+}
+
+// FieldError is a single validation failure
+type FieldError struct {
+	// Field is the JSON pointer of the offending value, empty for the root
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every field that failed validation
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		if f.Field == "" {
+			msgs = append(msgs, f.Message)
+			continue
+		}
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Compile compiles a JSON Schema given as a decoded JSON document
+func Compile(name string, s map[string]any) (*Validator, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("marshal schema %s: %w", name, err)
+	}
+
+	url := "mem://" + name + ".json"
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("load schema %s: %w", name, err)
+	}
+	compiled, err := compiler.Compile(url)
+	if err != nil {
+		return nil, fmt.Errorf("compile schema %s: %w", name, err)
+	}
+	return &Validator{schema: compiled}, nil
+}
+
+// Validate checks v, a value decoded from JSON, and returns a *ValidationError on failure
+func (v *Validator) Validate(value any) error {
+	err := v.schema.Validate(value)
+	if err == nil {
+		return nil
+	}
+
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+
+	out := &ValidationError{}
+	for _, unit := range verr.BasicOutput().Errors {
+		// Skip the summary entries that only point at their causes
+		if unit.Error == "" || strings.HasPrefix(unit.Error, "doesn't validate with") {
+			continue
+		}
+		out.Fields = append(out.Fields, FieldError{
+			Field:   strings.TrimPrefix(unit.InstanceLocation, "/"),
+			Message: unit.Error,
+		})
+	}
+	if len(out.Fields) == 0 {
+		out.Fields = append(out.Fields, FieldError{Message: verr.Message})
+	}
+	return out
+}
+
+// ValidateJSON decodes data and validates it
+func (v *Validator) ValidateJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return &ValidationError{Fields: []FieldError{{Message: "invalid JSON: " + err.Error()}}}
+	}
+	return v.Validate(value)
+}
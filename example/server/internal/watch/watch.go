@@ -0,0 +1,45 @@
+// Package watch reloads files when they change on disk.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Debounce collapses the burst of events editors emit when saving a file
+const Debounce = 250 * time.Millisecond
+
+// Dir calls reload once changes to the files in dir accepted by match have
+// settled, until ctx is done. The directory is watched rather than the files
+// so atomic saves (write + rename) are seen. Errors of the watcher go to onError.
+func Dir(ctx context.Context, dir string, match func(path string) bool, reload func(), onError func(error)) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fsw.Close()
+	if err := fsw.Add(dir); err != nil {
+		return fmt.Errorf("watch %s: %w", dir, err)
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-fsw.Events:
+			if event.Has(fsnotify.Chmod) || !match(event.Name) {
+				continue
+			}
+			debounce = time.After(Debounce)
+		case <-debounce:
+			debounce = nil
+			reload()
+		case err := <-fsw.Errors:
+			onError(err)
+		}
+	}
+}
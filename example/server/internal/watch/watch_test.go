@@ -0,0 +1,45 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var reloads atomic.Int32
+	done := make(chan error, 1)
+	isYAML := func(path string) bool { return strings.HasSuffix(path, ".yaml") }
+	go func() {
+		done <- Dir(ctx, dir, isYAML, func() { reloads.Add(1) }, func(err error) { t.Error(err) })
+	}()
+	// Give the watcher time to start
+	time.Sleep(50 * time.Millisecond)
+
+	// A burst of writes is one reload
+	for i := 0; i < 3; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(strings.Repeat("x", i)), 0o644))
+	}
+	assert.Eventually(t, func() bool { return reloads.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	// Files that do not match are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("x"), 0o644))
+	time.Sleep(2 * Debounce)
+	assert.Equal(t, int32(1), reloads.Load())
+
+	cancel()
+	assert.NoError(t, <-done)
+
+	assert.ErrorContains(t, Dir(context.Background(), filepath.Join(dir, "missing"), isYAML, func() {}, func(error) {}), "watch ")
+}